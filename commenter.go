@@ -0,0 +1,330 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	backendClaude = "claude"
+	backendOllama = "ollama"
+)
+
+const defaultOllamaURL = "http://localhost:11434"
+
+// Commenter adds comments to a file using an LLM backend.
+type Commenter interface {
+	// Comment rewrites file in place following prompt and reports the tokens used.
+	// Failures worth retrying are returned as *transientError.
+	Comment(ctx context.Context, file, prompt string) (Usage, error)
+}
+
+// Usage is the token usage of one or more backend requests.
+type Usage struct {
+	InputTokens  int
+	OutputTokens int
+	// CostUSD is only known when the backend reports it
+	CostUSD float64
+	// Estimated is set when the token counts were estimated from text sizes because
+	// the backend didn't report them
+	Estimated bool
+}
+
+func (u *Usage) add(other Usage) {
+	u.InputTokens += other.InputTokens
+	u.OutputTokens += other.OutputTokens
+	u.CostUSD += other.CostUSD
+	u.Estimated = u.Estimated || other.Estimated
+}
+
+// estimateTokens approximates the token count of text using the common rule of thumb
+// of about four bytes per token.
+func estimateTokens(text string) int {
+	return (len(text) + 3) / 4
+}
+
+// commenterFactory creates the Commenter for a single file, with any output the
+// backend produces going to stdout and stderr.
+type commenterFactory func(stdout, stderr io.Writer) Commenter
+
+// newCommenter returns the Commenter for config.Backend, defaulting to Claude.
+func newCommenter(config Config, stdout, stderr io.Writer) Commenter {
+	switch config.Backend {
+	case backendOllama:
+		return &OllamaCommenter{URL: config.OllamaURL, Model: config.Model}
+	default:
+		return &ClaudeCommenter{
+			Bin:    config.ClaudeBin,
+			Args:   config.ClaudeArgs,
+			Model:  config.Model,
+			Stdout: stdout,
+			Stderr: stderr,
+		}
+	}
+}
+
+// transientError wraps a backend failure that may succeed when retried, such as a
+// rate limit or a dropped connection.
+type transientError struct {
+	err error
+}
+
+func (e *transientError) Error() string {
+	return e.err.Error()
+}
+
+func (e *transientError) Unwrap() error {
+	return e.err
+}
+
+// ClaudeCommenter runs the Claude CLI, which edits the file itself.
+type ClaudeCommenter struct {
+	Bin   string
+	Args  []string
+	Model string
+	// Stdout and Stderr receive Claude's output as it runs
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+func (c *ClaudeCommenter) Comment(ctx context.Context, file, prompt string) (Usage, error) {
+	// The original contents are only needed to estimate usage Claude doesn't report
+	before, _ := os.ReadFile(file)
+
+	// Output is still shown live but also kept to tell transient failures apart
+	// and to read the reported usage. Stdout and stderr are copied by separate
+	// goroutines, and c.Stdout and c.Stderr may be the same writer
+	var mu sync.Mutex
+	var stdout, stderr bytes.Buffer
+	cmd := c.command(ctx, prompt)
+	cmd.Stdout = &lockedWriter{mu: &mu, w: io.MultiWriter(c.Stdout, &stdout)}
+	cmd.Stderr = &lockedWriter{mu: &mu, w: io.MultiWriter(c.Stderr, &stderr)}
+
+	runErr := cmd.Run()
+	output := stdout.String() + stderr.String()
+
+	usage, ok := parseClaudeUsage(stdout.String())
+	if !ok {
+		after, _ := os.ReadFile(file)
+		usage = Usage{
+			InputTokens:  estimateTokens(prompt) + estimateTokens(string(before)),
+			OutputTokens: estimateTokens(output) + estimateTokens(string(after)),
+			Estimated:    true,
+		}
+	}
+
+	if runErr != nil {
+		err := fmt.Errorf("claude command failed: %w", runErr)
+		if isTransientClaudeError(stderr.String(), stdout.String()) {
+			return usage, &transientError{err}
+		}
+		return usage, err
+	}
+	return usage, nil
+}
+
+// lockedWriter serializes writes to w on mu, which is shared by the writers whose
+// underlying writers may be the same.
+type lockedWriter struct {
+	mu *sync.Mutex
+	w  io.Writer
+}
+
+func (l *lockedWriter) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.w.Write(p)
+}
+
+// claudeResult is the result message Claude prints with --output-format json or
+// stream-json.
+type claudeResult struct {
+	Type         string  `json:"type"`
+	IsError      bool    `json:"is_error"`
+	Result       string  `json:"result"`
+	TotalCostUSD float64 `json:"total_cost_usd"`
+	Usage        *struct {
+		InputTokens              int `json:"input_tokens"`
+		CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+		CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+		OutputTokens             int `json:"output_tokens"`
+	} `json:"usage"`
+}
+
+// parseClaudeResult finds the result message in Claude's output. Plain text output, the
+// default, has none and returns false.
+func parseClaudeResult(output string) (claudeResult, bool) {
+	lines := strings.Split(output, "\n")
+	for i := len(lines) - 1; i >= 0; i-- {
+		line := strings.TrimSpace(lines[i])
+		if !strings.HasPrefix(line, "{") {
+			continue
+		}
+
+		var result claudeResult
+		if err := json.Unmarshal([]byte(line), &result); err == nil && result.Type == "result" {
+			return result, true
+		}
+	}
+	return claudeResult{}, false
+}
+
+// parseClaudeUsage returns the usage the result message in Claude's output reports.
+func parseClaudeUsage(output string) (Usage, bool) {
+	result, ok := parseClaudeResult(output)
+	if !ok || result.Usage == nil {
+		return Usage{}, false
+	}
+	// Cached input tokens are reported separately but are still input
+	return Usage{
+		InputTokens:  result.Usage.InputTokens + result.Usage.CacheCreationInputTokens + result.Usage.CacheReadInputTokens,
+		OutputTokens: result.Usage.OutputTokens,
+		CostUSD:      result.TotalCostUSD,
+	}, true
+}
+
+// command builds the Claude invocation. The extra arguments from -claude-args go
+// between the model and the prompt. When ctx is done the whole process group is
+// killed, so tools Claude spawned don't outlive it.
+func (c *ClaudeCommenter) command(ctx context.Context, prompt string) *exec.Cmd {
+	args := []string{"--model", c.Model}
+	args = append(args, c.Args...)
+	args = append(args, "-p", prompt)
+	cmd := exec.CommandContext(ctx, c.Bin, args...)
+	killProcessGroupOnCancel(cmd)
+	// Orphaned children may still hold the output pipes open after a kill
+	cmd.WaitDelay = 5 * time.Second
+	return cmd
+}
+
+// transientClaudeErrors match the lowercased error lines of Claude failures that are
+// worth retrying: API errors for rate limits, overload, and server failures, and network
+// errors. They are anchored to the start of the line, where the CLI prints them, so that
+// the same words elsewhere don't count.
+var transientClaudeErrors = []*regexp.Regexp{
+	regexp.MustCompile(`^(?:error: )?api error: (?:429|500|502|503|504|529)\b`),
+	regexp.MustCompile(`^(?:error: )?(?:rate limit|rate_limit_error|overloaded_error|request timed out|network error)`),
+	regexp.MustCompile(`^(?:error: )?(?:(?:connect|read|write) )?(?:econnreset|econnrefused|etimedout|socket hang up)\b`),
+}
+
+// isTransientClaudeError reports whether a failed Claude run indicates a temporary problem
+// rather than one that would fail again on retry. Only its stderr and the error its result
+// message reports are looked at; the rest of stdout is the model's reply, which can
+// mention anything.
+func isTransientClaudeError(stderr, stdout string) bool {
+	text := stderr
+	if result, ok := parseClaudeResult(stdout); ok && result.IsError {
+		text += "\n" + result.Result
+	}
+
+	for line := range strings.Lines(strings.ToLower(text)) {
+		line = strings.TrimSpace(line)
+		for _, pattern := range transientClaudeErrors {
+			if pattern.MatchString(line) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// OllamaCommenter sends the file to a local Ollama server. Unlike Claude, the model
+// cannot edit files, so the file contents go into the prompt and the reply replaces
+// the file.
+type OllamaCommenter struct {
+	URL   string
+	Model string
+}
+
+type ollamaRequest struct {
+	Model  string `json:"model"`
+	Prompt string `json:"prompt"`
+	Stream bool   `json:"stream"`
+}
+
+type ollamaResponse struct {
+	Response        string `json:"response"`
+	PromptEvalCount int    `json:"prompt_eval_count"`
+	EvalCount       int    `json:"eval_count"`
+}
+
+func (o *OllamaCommenter) Comment(ctx context.Context, file, prompt string) (Usage, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return Usage{}, err
+	}
+
+	body, err := json.Marshal(ollamaRequest{
+		Model: o.Model,
+		Prompt: prompt + "\n\nReply with only the complete updated contents of " + file +
+			", without any explanation or Markdown fences. The current contents are:\n\n" + string(content),
+	})
+	if err != nil {
+		return Usage{}, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(o.URL, "/")+"/api/generate", bytes.NewReader(body))
+	if err != nil {
+		return Usage{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		// Connection failures are retried, e.g. while the server is still starting
+		return Usage{}, &transientError{fmt.Errorf("ollama request failed: %w", err)}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		err := fmt.Errorf("ollama returned %s: %s", resp.Status, strings.TrimSpace(string(message)))
+		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+			return Usage{}, &transientError{err}
+		}
+		return Usage{}, err
+	}
+
+	var result ollamaResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return Usage{}, fmt.Errorf("failed to decode ollama response: %w", err)
+	}
+	usage := Usage{InputTokens: result.PromptEvalCount, OutputTokens: result.EvalCount}
+
+	updated := stripMarkdownFence(result.Response)
+	if strings.TrimSpace(updated) == "" {
+		return usage, fmt.Errorf("ollama returned an empty file")
+	}
+	if !strings.HasSuffix(updated, "\n") {
+		updated += "\n"
+	}
+
+	return usage, os.WriteFile(file, []byte(updated), 0o644)
+}
+
+// stripMarkdownFence removes a Markdown code fence wrapped around the whole reply,
+// which local models often add despite being asked not to.
+func stripMarkdownFence(reply string) string {
+	trimmed := strings.TrimSpace(reply)
+	if !strings.HasPrefix(trimmed, "```") || !strings.HasSuffix(trimmed, "```") {
+		return reply
+	}
+
+	// The opening fence line may carry a language tag
+	_, inner, found := strings.Cut(trimmed, "\n")
+	if !found {
+		return reply
+	}
+	inner = strings.TrimSuffix(inner, "```")
+	return inner
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestClaudeCommand(t *testing.T) {
+	commenter := &ClaudeCommenter{
+		Bin:   "/opt/bin/claude",
+		Args:  []string{"--permission-mode", "acceptEdits"},
+		Model: "sonnet",
+	}
+
+	cmd := commenter.command(context.Background(), "Comment /repo/main.go")
+
+	want := []string{"/opt/bin/claude", "--model", "sonnet", "--permission-mode", "acceptEdits", "-p", "Comment /repo/main.go"}
+	if len(cmd.Args) != len(want) {
+		t.Fatalf("command() args = %v, want %v", cmd.Args, want)
+	}
+	for i := range want {
+		if cmd.Args[i] != want[i] {
+			t.Errorf("command() args[%d] = %q, want %q", i, cmd.Args[i], want[i])
+		}
+	}
+}
+
+func TestParseClaudeUsage(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   Usage
+		ok     bool
+	}{
+		{
+			name:   "plain text",
+			output: "Added comments to main.go\n",
+			ok:     false,
+		},
+		{
+			name:   "json result",
+			output: `{"type":"result","result":"ok","total_cost_usd":0.01,"usage":{"input_tokens":10,"cache_creation_input_tokens":5,"cache_read_input_tokens":3,"output_tokens":7}}` + "\n",
+			want:   Usage{InputTokens: 18, OutputTokens: 7, CostUSD: 0.01},
+			ok:     true,
+		},
+		{
+			name: "stream json",
+			output: `{"type":"system","subtype":"init"}
+{"type":"assistant","message":{"usage":{"input_tokens":1,"output_tokens":1}}}
+{"type":"result","total_cost_usd":0.02,"usage":{"input_tokens":40,"output_tokens":9}}
+`,
+			want: Usage{InputTokens: 40, OutputTokens: 9, CostUSD: 0.02},
+			ok:   true,
+		},
+		{
+			name:   "json without usage",
+			output: `{"type":"result","result":"ok"}`,
+			ok:     false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseClaudeUsage(tt.output)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("parseClaudeUsage() = %+v, %v, want %+v, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestIsTransientClaudeError(t *testing.T) {
+	tests := []struct {
+		name   string
+		stderr string
+		stdout string
+		want   bool
+	}{
+		{"rate limit", "Error: rate limit exceeded\n", "", true},
+		{"overloaded api", "API Error: 529 {\"type\":\"error\",\"error\":{\"type\":\"overloaded_error\"}}\n", "", true},
+		{"network", "Error: connect ECONNREFUSED 127.0.0.1:443\n", "", true},
+		{"json result error", "", `{"type":"result","is_error":true,"result":"API Error: 503 Service Unavailable"}` + "\n", true},
+		{"permanent", "Error: invalid model\n", "", false},
+		// The model's reply can mention status codes and timeouts without them being errors
+		{"status codes in reply", "", "Handled the 429 and 503 responses and the timeout in fetch.go\n", false},
+		{"json result success", "", `{"type":"result","is_error":false,"result":"API Error: 429 is now retried"}` + "\n", false},
+		{"words after the start of a line", "Error: invalid model, not a rate limit\n", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isTransientClaudeError(tt.stderr, tt.stdout); got != tt.want {
+				t.Errorf("isTransientClaudeError(%q, %q) = %v, want %v", tt.stderr, tt.stdout, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOllamaCommenter(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "main.go")
+	if err := os.WriteFile(file, []byte("package main\n"), 0o644); err != nil {
+		t.Fatalf("os.WriteFile() error = %v", err)
+	}
+
+	var got ollamaRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/generate" {
+			t.Errorf("request path = %q, want /api/generate", r.URL.Path)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("failed to decode request: %v", err)
+		}
+		json.NewEncoder(w).Encode(ollamaResponse{
+			Response:        "```go\n// Package main is the entry point.\npackage main\n```",
+			PromptEvalCount: 50,
+			EvalCount:       12,
+		})
+	}))
+	defer server.Close()
+
+	commenter := &OllamaCommenter{URL: server.URL, Model: "llama3.1"}
+	usage, err := commenter.Comment(context.Background(), file, "Comment "+file)
+	if err != nil {
+		t.Fatalf("Comment() error = %v", err)
+	}
+	if want := (Usage{InputTokens: 50, OutputTokens: 12}); usage != want {
+		t.Errorf("Comment() usage = %+v, want %+v", usage, want)
+	}
+
+	if got.Model != "llama3.1" || got.Stream {
+		t.Errorf("request = %+v, want model llama3.1 without streaming", got)
+	}
+	if !strings.HasPrefix(got.Prompt, "Comment "+file) || !strings.HasSuffix(got.Prompt, "package main\n") {
+		t.Errorf("request prompt = %q, want prompt followed by file contents", got.Prompt)
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("os.ReadFile() error = %v", err)
+	}
+	if want := "// Package main is the entry point.\npackage main\n"; string(data) != want {
+		t.Errorf("file content = %q, want %q", string(data), want)
+	}
+}
+
+func TestOllamaCommenterErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		transient bool
+	}{
+		{"rate limited", http.StatusTooManyRequests, true},
+		{"server error", http.StatusServiceUnavailable, true},
+		{"unknown model", http.StatusNotFound, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "main.go")
+			if err := os.WriteFile(file, []byte("package main\n"), 0o644); err != nil {
+				t.Fatalf("os.WriteFile() error = %v", err)
+			}
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, "failed", tt.status)
+			}))
+			defer server.Close()
+
+			commenter := &OllamaCommenter{URL: server.URL, Model: "llama3.1"}
+			_, err := commenter.Comment(context.Background(), file, "Comment")
+			if err == nil {
+				t.Fatalf("Comment() error = nil, want error")
+			}
+			var transient *transientError
+			if errors.As(err, &transient) != tt.transient {
+				t.Errorf("Comment() error = %v, transient = %v, want %v", err, !tt.transient, tt.transient)
+			}
+
+			// The file is left alone on failure
+			if data, _ := os.ReadFile(file); string(data) != "package main\n" {
+				t.Errorf("file content = %q, want unchanged", string(data))
+			}
+		})
+	}
+}
+
+func TestStripMarkdownFence(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"no fence", "package main\n", "package main\n"},
+		{"fence", "```\npackage main\n```", "package main\n"},
+		{"fence with language", "```go\npackage main\n```\n", "package main\n"},
+		{"inner fence kept", "x := \"```\"\n", "x := \"```\"\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := stripMarkdownFence(tt.input); got != tt.want {
+				t.Errorf("stripMarkdownFence() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// configFileName is the config file read from the git repository root.
+const configFileName = ".nocomms.yaml"
+
+// fileConfig is the content of the config file. Options it leaves out keep their flag
+// defaults, so scalars are pointers to tell them apart from zero values.
+type fileConfig struct {
+	BatchSize *int    `yaml:"batch_size"`
+	Prompt    *string `yaml:"prompt"`
+	Model     *string `yaml:"model"`
+	// Formatters maps languages to formatter commands, like -formatter
+	Formatters     map[string]string `yaml:"formatters"`
+	Exclude        []string          `yaml:"exclude"`
+	Include        []string          `yaml:"include"`
+	KeepDirectives []string          `yaml:"keep_directives"`
+}
+
+// configFilePath returns the path of the config file in the git repository root, or an
+// empty string outside a git repository.
+func configFilePath() string {
+	gitRoot, err := findGitRoot()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(gitRoot, configFileName)
+}
+
+// loadFileConfig reads the config file at path. A missing file is an empty config;
+// unknown keys are errors so typos don't go unnoticed.
+func loadFileConfig(path string) (fileConfig, error) {
+	var config fileConfig
+	if path == "" {
+		return config, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return config, nil
+	}
+	if err != nil {
+		return config, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	// A file without any content besides comments decodes to io.EOF
+	if err := decoder.Decode(&config); err != nil && !errors.Is(err, io.EOF) {
+		return config, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return config, nil
+}
+
+// apply sets the flags of flags that the config file configures, except for the lists
+// applyLists sets. It runs before the command line is parsed, so flags given there take
+// precedence: they replace single values, while -formatter overrides replace the config
+// file's commands only for their own languages.
+// Values are set on the flags directly so that they still count as unset, which keeps
+// -prompt-file working over a configured prompt.
+func (c fileConfig) apply(flags *flag.FlagSet) error {
+	set := func(name, value string) error {
+		return setConfigFlag(flags, name, value)
+	}
+
+	var errs []error
+	if c.BatchSize != nil {
+		errs = append(errs, set("batch-size", strconv.Itoa(*c.BatchSize)))
+	}
+	if c.Prompt != nil {
+		errs = append(errs, set("prompt", *c.Prompt))
+	}
+	if c.Model != nil {
+		errs = append(errs, set("model", *c.Model))
+	}
+	for _, lang := range slices.Sorted(maps.Keys(c.Formatters)) {
+		errs = append(errs, set("formatter", lang+"="+c.Formatters[lang]))
+	}
+	if c.KeepDirectives != nil {
+		errs = append(errs, set("keep-directives", strings.Join(c.KeepDirectives, ",")))
+	}
+	return errors.Join(errs...)
+}
+
+// applyLists sets the -exclude and -include flags to the config file's lists unless the
+// command line set them, in which case only its values are used. Since repeated flags add
+// to each other, it runs after the command line is parsed to see which ones were given.
+func (c fileConfig) applyLists(flags *flag.FlagSet) error {
+	given := make(map[string]bool)
+	flags.Visit(func(f *flag.Flag) {
+		given[f.Name] = true
+	})
+
+	var errs []error
+	if !given["exclude"] {
+		for _, pattern := range c.Exclude {
+			errs = append(errs, setConfigFlag(flags, "exclude", pattern))
+		}
+	}
+	if c.Include != nil && !given["include"] {
+		errs = append(errs, setConfigFlag(flags, "include", strings.Join(c.Include, ",")))
+	}
+	return errors.Join(errs...)
+}
+
+// setConfigFlag sets the flag name of flags to a value from the config file.
+func setConfigFlag(flags *flag.FlagSet, name, value string) error {
+	if err := flags.Lookup(name).Value.Set(value); err != nil {
+		return fmt.Errorf("invalid %s in %s: %w", name, configFileName, err)
+	}
+	return nil
+}
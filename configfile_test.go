@@ -0,0 +1,194 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestLoadFileConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), configFileName)
+	data := `batch_size: 8
+model: sonnet
+prompt: |
+  Comment {filename}
+formatters:
+  go: gofumpt -w
+  python: ""
+exclude:
+  - vendor
+  - "*.pb.go"
+keep_directives: [go:, nolint]
+include: [.go, .ts]
+`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatalf("os.WriteFile() error = %v", err)
+	}
+
+	config, err := loadFileConfig(path)
+	if err != nil {
+		t.Fatalf("loadFileConfig() error = %v", err)
+	}
+	if config.BatchSize == nil || *config.BatchSize != 8 {
+		t.Errorf("BatchSize = %v, want 8", config.BatchSize)
+	}
+	if config.Model == nil || *config.Model != "sonnet" {
+		t.Errorf("Model = %v, want sonnet", config.Model)
+	}
+	if config.Prompt == nil || *config.Prompt != "Comment {filename}\n" {
+		t.Errorf("Prompt = %v, want %q", config.Prompt, "Comment {filename}\n")
+	}
+	if config.Formatters["go"] != "gofumpt -w" || config.Formatters["python"] != "" || len(config.Formatters) != 2 {
+		t.Errorf("Formatters = %v", config.Formatters)
+	}
+	if !slices.Equal(config.Exclude, []string{"vendor", "*.pb.go"}) {
+		t.Errorf("Exclude = %v", config.Exclude)
+	}
+	if !slices.Equal(config.KeepDirectives, []string{"go:", "nolint"}) {
+		t.Errorf("KeepDirectives = %v", config.KeepDirectives)
+	}
+	if !slices.Equal(config.Include, []string{".go", ".ts"}) {
+		t.Errorf("Include = %v", config.Include)
+	}
+}
+
+func TestLoadFileConfigErrors(t *testing.T) {
+	dir := t.TempDir()
+
+	// Missing and empty files configure nothing
+	if config, err := loadFileConfig(filepath.Join(dir, "missing.yaml")); err != nil || config.Model != nil {
+		t.Errorf("loadFileConfig() of missing file = %+v, %v, want empty config", config, err)
+	}
+	empty := filepath.Join(dir, "empty.yaml")
+	if err := os.WriteFile(empty, []byte("# nothing yet\n"), 0o644); err != nil {
+		t.Fatalf("os.WriteFile() error = %v", err)
+	}
+	if _, err := loadFileConfig(empty); err != nil {
+		t.Errorf("loadFileConfig() of empty file error = %v", err)
+	}
+
+	typo := filepath.Join(dir, "typo.yaml")
+	if err := os.WriteFile(typo, []byte("batchsize: 8\n"), 0o644); err != nil {
+		t.Fatalf("os.WriteFile() error = %v", err)
+	}
+	if _, err := loadFileConfig(typo); err == nil || !strings.Contains(err.Error(), "batchsize") {
+		t.Errorf("loadFileConfig() of unknown key error = %v, want it named", err)
+	}
+}
+
+func TestFileConfigPrecedence(t *testing.T) {
+	// A flag set mirroring the flags main defines for the configurable options
+	flags := flag.NewFlagSet("nocomms", flag.ContinueOnError)
+	batchSize := flags.Int("batch-size", 24, "")
+	model := flags.String("model", defaultModel, "")
+	prompt := flags.String("prompt", "default prompt", "")
+	keepDirectives := flags.String("keep-directives", "go:", "")
+	var excludes []string
+	flags.Func("exclude", "", func(pattern string) error {
+		excludes = append(excludes, pattern)
+		return nil
+	})
+	formatters := make(map[string]string)
+	flags.Func("formatter", "", func(value string) error {
+		lang, command, _ := strings.Cut(value, "=")
+		formatters[lang] = command
+		return nil
+	})
+
+	batch := 8
+	configModel := "sonnet"
+	configPrompt := "configured prompt"
+	config := fileConfig{
+		BatchSize:      &batch,
+		Model:          &configModel,
+		Prompt:         &configPrompt,
+		Formatters:     map[string]string{"go": "gofumpt -w", "python": "black"},
+		Exclude:        []string{"vendor"},
+		KeepDirectives: []string{"nolint"},
+	}
+	if err := config.apply(flags); err != nil {
+		t.Fatalf("apply() error = %v", err)
+	}
+	if err := flags.Parse([]string{"-model", "opus", "-formatter", "python=ruff format", "-exclude", "*.pb.go"}); err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if err := config.applyLists(flags); err != nil {
+		t.Fatalf("applyLists() error = %v", err)
+	}
+
+	if *batchSize != 8 {
+		t.Errorf("batch-size = %d, want 8 from the config file", *batchSize)
+	}
+	if *model != "opus" {
+		t.Errorf("model = %q, want opus from the command line", *model)
+	}
+	if *prompt != "configured prompt" {
+		t.Errorf("prompt = %q, want the configured prompt", *prompt)
+	}
+	if *keepDirectives != "nolint" {
+		t.Errorf("keep-directives = %q, want nolint", *keepDirectives)
+	}
+	if !slices.Equal(excludes, []string{"*.pb.go"}) {
+		t.Errorf("excludes = %v, want only the command line's", excludes)
+	}
+	if formatters["go"] != "gofumpt -w" || formatters["python"] != "ruff format" {
+		t.Errorf("formatters = %v, want go from the config file and python from the command line", formatters)
+	}
+
+	// Configured values don't count as set, so -prompt-file still applies over them
+	flags.Visit(func(f *flag.Flag) {
+		if f.Name == "prompt" || f.Name == "batch-size" {
+			t.Errorf("flag %s counts as set on the command line", f.Name)
+		}
+	})
+}
+
+func TestFileConfigListsReplacedByCommandLine(t *testing.T) {
+	config := fileConfig{Include: []string{".go", ".py"}, Exclude: []string{"vendor"}}
+
+	tests := []struct {
+		name         string
+		args         []string
+		wantIncludes []string
+		wantExcludes []string
+	}{
+		{"config file only", nil, []string{".go", ".py"}, []string{"vendor"}},
+		{"include narrows the run", []string{"-include", ".go"}, []string{".go"}, []string{"vendor"}},
+		{"exclude replaces the file's", []string{"-exclude", "testdata", "-exclude", "*.pb.go"}, []string{".go", ".py"}, []string{"testdata", "*.pb.go"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flags := flag.NewFlagSet("nocomms", flag.ContinueOnError)
+			var includes, excludes []string
+			flags.Func("include", "", func(value string) error {
+				includes = append(includes, strings.Split(value, ",")...)
+				return nil
+			})
+			flags.Func("exclude", "", func(pattern string) error {
+				excludes = append(excludes, pattern)
+				return nil
+			})
+
+			if err := config.apply(flags); err != nil {
+				t.Fatalf("apply() error = %v", err)
+			}
+			if err := flags.Parse(tt.args); err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if err := config.applyLists(flags); err != nil {
+				t.Fatalf("applyLists() error = %v", err)
+			}
+
+			if !slices.Equal(includes, tt.wantIncludes) {
+				t.Errorf("includes = %v, want %v", includes, tt.wantIncludes)
+			}
+			if !slices.Equal(excludes, tt.wantExcludes) {
+				t.Errorf("excludes = %v, want %v", excludes, tt.wantExcludes)
+			}
+		})
+	}
+}
@@ -1,3 +1,5 @@
 module nocomms
 
 go 1.25.2
+
+require gopkg.in/yaml.v3 v3.0.1
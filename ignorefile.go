@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ignorePattern is a pattern from an -exclude-from file, in gitignore syntax.
+type ignorePattern struct {
+	// pattern is the glob without its leading '!', leading '/', or trailing '/'
+	pattern string
+	// negate re-includes paths that earlier patterns excluded
+	negate bool
+	// dirOnly patterns, written with a trailing '/', only match directories
+	dirOnly bool
+	// anchored patterns contain a '/' before their end, so they match paths relative
+	// to the root rather than names at any depth
+	anchored bool
+}
+
+// ignoreMatcher matches paths against the patterns of an -exclude-from file the way git
+// matches a .gitignore file in root.
+type ignoreMatcher struct {
+	root     string
+	patterns []ignorePattern
+}
+
+// loadIgnoreFile reads the gitignore-syntax patterns in path, matched relative to root.
+func loadIgnoreFile(path, root string) (*ignoreMatcher, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read exclude file: %w", err)
+	}
+
+	patterns, err := parseIgnorePatterns(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern in %s: %w", path, err)
+	}
+	return &ignoreMatcher{root: root, patterns: patterns}, nil
+}
+
+// parseIgnorePatterns parses the lines of a gitignore file. Blank lines and lines
+// starting with '#' are skipped, and a backslash escapes a leading '#' or '!' or a
+// trailing space.
+func parseIgnorePatterns(data string) ([]ignorePattern, error) {
+	var patterns []ignorePattern
+	for i, line := range strings.Split(data, "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		trimmed := strings.TrimRight(line, " ")
+		if strings.HasSuffix(trimmed, `\`) && len(trimmed) < len(line) {
+			trimmed += " "
+		}
+		line = trimmed
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var p ignorePattern
+		if strings.HasPrefix(line, "!") {
+			p.negate = true
+			line = line[1:]
+		} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			p.dirOnly = true
+			line = strings.TrimSuffix(line, "/")
+		}
+		if strings.Contains(line, "/") {
+			p.anchored = true
+			line = strings.TrimPrefix(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+
+		for _, segment := range strings.Split(line, "/") {
+			if _, err := filepath.Match(segment, ""); err != nil {
+				return nil, fmt.Errorf("line %d: %w", i+1, err)
+			}
+		}
+		p.pattern = line
+		patterns = append(patterns, p)
+	}
+	return patterns, nil
+}
+
+// ignored reports whether the file at path is excluded. As in git, a file can't be
+// re-included once a directory above it is excluded, so each parent directory is
+// checked first. Paths outside the root never match.
+func (m *ignoreMatcher) ignored(path string) bool {
+	if m == nil {
+		return false
+	}
+
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	relPath, err := filepath.Rel(m.root, absPath)
+	if err != nil || relPath == ".." || strings.HasPrefix(relPath, ".."+string(filepath.Separator)) {
+		return false
+	}
+
+	segments := strings.Split(filepath.ToSlash(relPath), "/")
+	for i := 1; i <= len(segments); i++ {
+		if m.matches(segments[:i], i < len(segments)) {
+			return true
+		}
+	}
+	return false
+}
+
+// matches reports whether the path made of segments is excluded by itself, without
+// regard to its parent directories. The last pattern that matches decides.
+func (m *ignoreMatcher) matches(segments []string, isDir bool) bool {
+	excluded := false
+	for _, p := range m.patterns {
+		if p.dirOnly && !isDir {
+			continue
+		}
+
+		var matched bool
+		if p.anchored {
+			matched = matchSegments(strings.Split(p.pattern, "/"), segments)
+		} else {
+			matched, _ = filepath.Match(p.pattern, segments[len(segments)-1])
+		}
+		if matched {
+			excluded = !p.negate
+		}
+	}
+	return excluded
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestIgnoreMatcher(t *testing.T) {
+	const patterns = `# comments and blank lines are skipped
+
+vendor/
+*.gen.go
+!keep.gen.go
+/build
+docs/**/*.md
+\#hash.go
+`
+	root := filepath.FromSlash("/repo")
+	matcher := &ignoreMatcher{root: root}
+	var err error
+	if matcher.patterns, err = parseIgnorePatterns(patterns); err != nil {
+		t.Fatalf("parseIgnorePatterns() error = %v", err)
+	}
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"main.go", false},
+		{"vendor/lib/lib.go", true},
+		{"pkg/vendor/lib.go", true},
+		// vendor/ only matches directories
+		{"pkg/vendor", false},
+		{"api.gen.go", true},
+		{"pkg/api/types.gen.go", true},
+		{"pkg/keep.gen.go", false},
+		// A file can't be re-included when its directory is excluded
+		{"vendor/keep.gen.go", true},
+		{"build/out.go", true},
+		{"build", true},
+		{"pkg/build/out.go", false},
+		{"docs/README.md", true},
+		{"docs/guide/intro/setup.md", true},
+		{"pkg/docs/README.md", false},
+		{"#hash.go", true},
+		{"../other/vendor/lib.go", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			path := filepath.Join(root, filepath.FromSlash(tt.path))
+			if got := matcher.ignored(path); got != tt.want {
+				t.Errorf("ignored(%s) = %v, want %v", path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseIgnorePatternsInvalid(t *testing.T) {
+	if _, err := parseIgnorePatterns("vendor/\n[abc\n"); err == nil {
+		t.Error("parseIgnorePatterns() error = nil, want an error for a malformed pattern")
+	}
+}
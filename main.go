@@ -1,41 +1,202 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"io/fs"
+	"math"
+	"math/rand/v2"
 	"os"
 	"os/exec"
+	"os/signal"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
+	"syscall"
+	"text/tabwriter"
 	"time"
+
+	"nocomms/stripper"
 )
 
 type Config struct {
 	Files        []string
 	BatchSize    int
 	Prompt       string
+	Model        string
 	ForceProcess bool
 	CacheOnly    bool
+	// Concurrency caps how many files are commented at once across the whole run; zero
+	// means the batch size
+	Concurrency int
+	// Offset and Limit select a window of the files in sorted order, so that a large
+	// backlog can be worked through across invocations; zero Limit means no limit
+	Offset int
+	Limit  int
+	// Since keeps only files modified within this long before the run; zero keeps every file
+	Since time.Duration
+	// BatchDelay is the pause between batches, to stay under API rate limits
+	BatchDelay time.Duration
+	// Backend selects the Commenter: backendClaude or backendOllama
+	Backend string
+	// OllamaURL is the base URL of the Ollama server used by backendOllama
+	OllamaURL string
+	// ClaudeBin is the Claude CLI executable and ClaudeArgs are extra arguments passed to it
+	ClaudeBin  string
+	ClaudeArgs []string
+	// Retries is how many times a transient Claude failure is retried, waiting
+	// RetryBackoff before the first retry and doubling the wait after each one
+	Retries      int
+	RetryBackoff time.Duration
+	// Timeout bounds how long Claude may spend on a single file, retries included;
+	// zero disables it
+	Timeout time.Duration
+	// FailFast stops the run at the first file that fails, canceling the files still
+	// being commented and leaving those that haven't started alone
+	FailFast bool
+	// DryRun strips comments and formats files but only prints the prompts Claude
+	// would receive, leaving the cache untouched
+	DryRun bool
+	// Backup copies each file into backupDirName before comments are removed
+	Backup bool
+	// Validate runs a syntax check from defaultValidators on each commented file, failing
+	// files the check rejects and, with Backup, restoring them from their backups
+	Validate bool
+	// Stream shows Claude output live; otherwise each file's output is buffered and
+	// printed as one block when the file completes so parallel runs don't interleave
+	Stream bool
+	// JSONC routes plain .json files through the JSONC remover, since many tools
+	// (tsconfig.json, VS Code settings) accept comments in .json files
+	JSONC bool
+	// KeepDirectives holds the prefixes of tooling directive comments to preserve, such
+	// as //go:generate; see directiveKeeper
+	KeepDirectives []string
+	// KeepHeader preserves the comment block at the top of each file, such as a license
+	// header; see splitHeader
+	KeepHeader bool
+	// KeepRegionStart and KeepRegionEnd mark regions of each file that are left untouched,
+	// from -keep-regions; see stripper.Options
+	KeepRegionStart string
+	KeepRegionEnd   string
+	// KeepTrailingWhitespace keeps trailing whitespace on lines without comments, such
+	// as Markdown hard line breaks, which some removers otherwise trim from every line
+	KeepTrailingWhitespace bool
+	// KeepDocs preserves documentation comments: Go doc comments, Rust /// and //!,
+	// JSDoc /** */, and Solidity NatSpec
+	KeepDocs bool
+	// KeepNatSpec preserves Solidity NatSpec documentation comments (/// and /** */)
+	KeepNatSpec bool
+	// AsmDialect selects the comment syntax of assembly files, stripper.AsmGAS or
+	// stripper.AsmNASM; empty means NASM for .asm files and GAS otherwise
+	AsmDialect string
+	// CollapseNewlines collapses the blank lines left behind by removed comments
+	CollapseNewlines bool
+	// PruneCache drops cache entries for files that no longer exist before processing
+	PruneCache bool
+	// Restage runs git add on processed files afterwards so a pre-commit hook commits the
+	// new comments; only set together with -staged
+	Restage bool
+	// CacheFile overrides the cache location (see getCachePath)
+	CacheFile string
+	// CacheMode selects how changed files are detected: cacheModeMtime or cacheModeHash
+	CacheMode string
+	// Exclude holds glob patterns for paths to skip when expanding directory arguments
+	Exclude []string
+	// ExcludeFrom names a file of gitignore-syntax patterns for files to skip, matched
+	// relative to the root from findRoot
+	ExcludeFrom string
+	// Include holds the extensions, such as ".go", of the files to process; empty means
+	// every supported file
+	Include []string
+	// ExcludeExtensions holds the extensions of files to leave alone, from the -exclude
+	// patterns that name an extension
+	ExcludeExtensions []string
+	// Output selects how progress is reported: outputText or outputJSON
+	Output string
+	// LogLevel selects how much progress is printed: levelQuiet, levelNormal, or
+	// levelVerbose
+	LogLevel logLevel
+	// Formatters overrides the formatter command of languages, keyed by stripper
+	// language; an empty command disables formatting for the language
+	Formatters map[string][]string
+	// NoFormat disables formatting entirely
+	NoFormat bool
+	// SkipUnchanged leaves out files that have no comments to remove, so that only
+	// previously commented code is commented again
+	SkipUnchanged bool
+	// Safe keeps the original contents of each file in memory while it is commented, and
+	// restores them when Claude doesn't comment the file
+	Safe bool
+}
+
+const (
+	cacheModeMtime = "mtime"
+	cacheModeHash  = "hash"
+)
+
+// defaultModel is the Claude model used to add comments unless -model says otherwise.
+const defaultModel = "haiku"
+
+// defaultClaudeArgs are passed to Claude unless -claude-args replaces them.
+// bypassPermissions mode is required because Claude needs write access to modify files,
+// and interactive permission prompts would block batch processing.
+const defaultClaudeArgs = "--dangerously-skip-permissions --permission-mode bypassPermissions"
+
+// version identifies the nocomms build and is recorded in the cache alongside the model.
+// Release builds set it with -ldflags "-X main.version=...".
+var version = "dev"
+
+// ProcessingInfo records what produced the comments in a processed file. An entry whose
+// info differs from the current run's is stale even if the file itself is unchanged.
+type ProcessingInfo struct {
+	Model   string `json:"model"`
+	Version string `json:"version"`
 }
 
 type FileCache struct {
 	ProcessedFiles map[string]time.Time `json:"processed_files"`
+	// Hashes holds the SHA-256 of each file's contents when it was processed in hash mode
+	Hashes map[string]string `json:"hashes,omitempty"`
+	// Provenance holds the model and tool version each file was processed with
+	Provenance map[string]ProcessingInfo `json:"provenance,omitempty"`
+	// Mode is the change detection mode for this run; it is not persisted so that a
+	// cache written in one mode can be reused in the other
+	Mode string `json:"-"`
+	// Current is the model and tool version of this run, compared against Provenance.
+	// Caches without it, as built in tests, skip the comparison
+	Current ProcessingInfo `json:"-"`
+
+	// path is where the cache was loaded from and is saved to
+	path string
 }
 
-// ErrUnsupportedFileType is returned when a file type is not supported
-type ErrUnsupportedFileType struct {
-	Extension string
-}
+// ErrNoComments is returned by processFile with -skip-unchanged for files that removing
+// comments doesn't change, since there are no comments for Claude to regenerate.
+var ErrNoComments = errors.New("no comments to regenerate")
 
-func (e *ErrUnsupportedFileType) Error() string {
-	return fmt.Sprintf("unsupported file type: %s", e.Extension)
-}
+// binarySampleSize is how much of a streamed file checkText looks at, since checking
+// all of it would mean reading it twice.
+const binarySampleSize = 8 << 10
 
 const cacheFileName = ".nocomms-cache.json"
 
+// cacheEnvVar names the environment variable that overrides the cache file location.
+const cacheEnvVar = "NOCOMMS_CACHE"
+
+// backupDirName is the directory in the root that -backup copies files into, mirroring
+// their paths relative to the root.
+const backupDirName = ".nocomms-backups"
+
 // findGitRoot walks up the directory tree to locate the git repository root.
 // This approach ensures cache files are stored at the repository level rather than
 // scattered across subdirectories, providing consistent cache behavior regardless
@@ -61,25 +222,55 @@ func findGitRoot() (string, error) {
 	}
 }
 
-func getCachePath() (string, error) {
-	gitRoot, err := findGitRoot()
+// findRoot returns the directory that the cache and its keys are relative to: the git
+// repository root, or the working directory outside a repository so that the tool still
+// works on standalone files.
+func findRoot() (string, error) {
+	if gitRoot, err := findGitRoot(); err == nil {
+		return gitRoot, nil
+	}
+
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", fmt.Errorf("failed to get working directory: %w", err)
+	}
+	return dir, nil
+}
+
+// getCachePath returns where the cache is stored: override if set, else the path in the
+// NOCOMMS_CACHE environment variable, else .nocomms-cache.json at the root from findRoot.
+// Overrides let monorepos and CI jobs with read-only checkouts keep the cache elsewhere;
+// cache keys stay root-relative either way.
+func getCachePath(override string) (string, error) {
+	if override == "" {
+		override = os.Getenv(cacheEnvVar)
+	}
+	if override != "" {
+		absPath, err := filepath.Abs(override)
+		if err != nil {
+			return "", fmt.Errorf("failed to resolve cache path: %w", err)
+		}
+		return absPath, nil
+	}
+
+	root, err := findRoot()
 	if err != nil {
-		return "", fmt.Errorf("failed to find git repository root: %w", err)
+		return "", err
 	}
 
-	return filepath.Join(gitRoot, cacheFileName), nil
+	return filepath.Join(root, cacheFileName), nil
 }
 
-// toRelativePath converts absolute paths to git-root-relative paths for cache storage.
+// toRelativePath converts absolute paths to root-relative paths for cache storage.
 // Relative paths are used in the cache because they remain valid when the repository
 // is moved or accessed from different mount points, making the cache portable.
 func toRelativePath(absolutePath string) (string, error) {
-	gitRoot, err := findGitRoot()
+	root, err := findRoot()
 	if err != nil {
 		return "", err
 	}
 
-	relPath, err := filepath.Rel(gitRoot, absolutePath)
+	relPath, err := filepath.Rel(root, absolutePath)
 	if err != nil {
 		return "", fmt.Errorf("failed to make path relative: %w", err)
 	}
@@ -88,31 +279,38 @@ func toRelativePath(absolutePath string) (string, error) {
 }
 
 func toAbsolutePath(relativePath string) (string, error) {
-	gitRoot, err := findGitRoot()
+	root, err := findRoot()
 	if err != nil {
 		return "", err
 	}
 
-	return filepath.Join(gitRoot, relativePath), nil
+	return filepath.Join(root, relativePath), nil
 }
 
 // isGitIgnored checks if a file is ignored by git using git check-ignore.
-// This respects all .gitignore files in the repository hierarchy.
+// This respects all .gitignore files in the repository hierarchy. Outside a repository
+// check-ignore exits with an error, so no file is treated as ignored.
 func isGitIgnored(filePath string) bool {
+	// Backups are never inputs, even in repositories that don't ignore them
+	if slices.Contains(strings.Split(filepath.ToSlash(filePath), "/"), backupDirName) {
+		return true
+	}
+
 	cmd := exec.Command("git", "check-ignore", "-q", filePath)
 	// check-ignore returns 0 if file is ignored, 1 if not ignored
 	err := cmd.Run()
 	return err == nil
 }
 
-func loadCache() (*FileCache, error) {
-	cachePath, err := getCachePath()
-	if err != nil {
-		return nil, err
-	}
-
+// loadCache reads the cache at cachePath, which later saves write back to. A corrupt
+// cache, such as one truncated by a crash before saves were atomic, only costs redundant
+// work, so it is discarded with a warning instead of failing the run.
+func loadCache(cachePath string) (*FileCache, error) {
 	cache := &FileCache{
 		ProcessedFiles: make(map[string]time.Time),
+		Hashes:         make(map[string]string),
+		Provenance:     make(map[string]ProcessingInfo),
+		path:           cachePath,
 	}
 
 	data, err := os.ReadFile(cachePath)
@@ -125,16 +323,32 @@ func loadCache() (*FileCache, error) {
 	}
 
 	if err := json.Unmarshal(data, cache); err != nil {
-		return nil, fmt.Errorf("failed to parse cache file: %w", err)
+		fmt.Fprintf(os.Stderr, "Warning: ignoring corrupt cache file %s: %v\n", cachePath, err)
+		return &FileCache{
+			ProcessedFiles: make(map[string]time.Time),
+			Hashes:         make(map[string]string),
+			path:           cachePath,
+		}, nil
+	}
+
+	// Caches written by older versions lack the hashes and provenance entries
+	if cache.Hashes == nil {
+		cache.Hashes = make(map[string]string)
+	}
+	if cache.Provenance == nil {
+		cache.Provenance = make(map[string]ProcessingInfo)
 	}
 
 	return cache, nil
 }
 
 func (c *FileCache) save() error {
-	cachePath, err := getCachePath()
-	if err != nil {
-		return err
+	cachePath := c.path
+	if cachePath == "" {
+		var err error
+		if cachePath, err = getCachePath(""); err != nil {
+			return err
+		}
 	}
 
 	data, err := json.MarshalIndent(c, "", "  ")
@@ -142,16 +356,206 @@ func (c *FileCache) save() error {
 		return fmt.Errorf("failed to marshal cache: %w", err)
 	}
 
-	if err := os.WriteFile(cachePath, data, 0o644); err != nil {
+	if err := writeFileAtomic(cachePath, data); err != nil {
 		return fmt.Errorf("failed to write cache file: %w", err)
 	}
 
 	return nil
 }
 
+// writeFileAtomic writes data to a temporary file in the same directory as path and
+// renames it into place, so an interrupted write never leaves a truncated file behind.
+// The temporary file must share the directory because rename is only atomic within a
+// single filesystem.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	// Removing after a successful rename fails harmlessly since the name no longer exists
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// errLocked is returned by lockFile when another process holds the lock.
+var errLocked = errors.New("lock held by another process")
+
+// acquireCacheLock locks a lockfile next to the cache so that concurrent runs, which
+// would otherwise each save their own copy of the cache and lose the other's progress,
+// fail fast instead. The returned function releases the lock and may be called more than
+// once. The lockfile itself is left in place; a lock left by a killed run is not held by
+// a running process, so it doesn't stop the next run.
+func acquireCacheLock(cachePath string) (func(), error) {
+	lockPath := cachePath + ".lock"
+
+	f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cache lock: %w", err)
+	}
+	if err := lockFile(f); err != nil {
+		f.Close()
+		if errors.Is(err, errLocked) {
+			return nil, fmt.Errorf("another nocomms run is using the cache (lockfile %s)", lockPath)
+		}
+		return nil, fmt.Errorf("failed to lock cache: %w", err)
+	}
+
+	// The PID helps identify the run holding the lock
+	f.Truncate(0)
+	f.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0)
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			f.Truncate(0)
+			f.Close()
+		})
+	}, nil
+}
+
+// releaseOnSignal calls release and exits when the run is interrupted or terminated,
+// since deferred calls don't run then. The returned function stops watching for the
+// signals.
+func releaseOnSignal(release func()) func() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	done := make(chan struct{})
+	go func() {
+		select {
+		case sig := <-signals:
+			release()
+			fmt.Fprintf(os.Stderr, "Stopped by %s\n", sig)
+			os.Exit(1)
+		case <-done:
+		}
+	}()
+
+	return func() {
+		signal.Stop(signals)
+		close(done)
+	}
+}
+
+// clearCache deletes the cache file at cachePath and reports how many entries it held.
+// A missing cache file is not an error, and an unreadable one is still deleted since the
+// point is to start from a clean slate.
+func clearCache(cachePath string) (int, error) {
+	data, err := os.ReadFile(cachePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to read cache file: %w", err)
+	}
+
+	var cache FileCache
+	entries := 0
+	if err := json.Unmarshal(data, &cache); err == nil {
+		entries = len(cache.ProcessedFiles)
+	}
+
+	if err := os.Remove(cachePath); err != nil {
+		return 0, fmt.Errorf("failed to remove cache file: %w", err)
+	}
+
+	return entries, nil
+}
+
+// backupFile saves content, the original contents of file, under the backup directory
+// so that -restore can undo the run.
+func backupFile(file string, content []byte) error {
+	root, err := findRoot()
+	if err != nil {
+		return err
+	}
+
+	relPath, err := filepath.Rel(root, file)
+	if err != nil || relPath == ".." || strings.HasPrefix(relPath, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("%s is outside %s", file, root)
+	}
+
+	backupPath := filepath.Join(root, backupDirName, relPath)
+	if err := os.MkdirAll(filepath.Dir(backupPath), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(backupPath, content, 0o644)
+}
+
+// restoreBackup copies the backup of file that backupFile saved back over file.
+func restoreBackup(file string) error {
+	root, err := findRoot()
+	if err != nil {
+		return err
+	}
+
+	relPath, err := filepath.Rel(root, file)
+	if err != nil {
+		return err
+	}
+	content, err := os.ReadFile(filepath.Join(root, backupDirName, relPath))
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(file, content, 0o644)
+}
+
+// restoreBackups copies every backed up file back to its original location and removes
+// the backup directory, reporting how many files were restored. The backup directory is
+// only removed once all files are restored, so a failed restore can be retried.
+func restoreBackups() (int, error) {
+	root, err := findRoot()
+	if err != nil {
+		return 0, err
+	}
+	backupDir := filepath.Join(root, backupDirName)
+
+	restored := 0
+	err = filepath.WalkDir(backupDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		relPath, err := filepath.Rel(backupDir, p)
+		if err != nil {
+			return err
+		}
+		content, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(root, relPath), content, 0o644); err != nil {
+			return err
+		}
+		restored++
+		return nil
+	})
+	if errors.Is(err, fs.ErrNotExist) && restored == 0 {
+		return 0, nil
+	}
+	if err != nil {
+		return restored, fmt.Errorf("failed to restore backups: %w", err)
+	}
+
+	return restored, os.RemoveAll(backupDir)
+}
+
 // shouldProcess determines if a file needs processing by comparing modification times.
 // Files are reprocessed only if modified after their last processing time, avoiding
-// redundant Claude API calls and preserving rate limits.
+// redundant Claude API calls and preserving rate limits. In hash mode the file's contents
+// are compared instead, so a touch or checkout that only changes the mtime is ignored.
+// Files processed with a different model or tool version are always reprocessed.
 func (c *FileCache) shouldProcess(filePath string) (bool, error) {
 	info, err := os.Stat(filePath)
 	if err != nil {
@@ -163,6 +567,25 @@ func (c *FileCache) shouldProcess(filePath string) (bool, error) {
 		return false, fmt.Errorf("failed to convert to relative path: %w", err)
 	}
 
+	// Entries from before provenance was recorded are trusted rather than reprocessing
+	// everything on upgrade
+	if info, exists := c.Provenance[relPath]; exists && c.Current != (ProcessingInfo{}) && info != c.Current {
+		return true, nil
+	}
+
+	if c.Mode == cacheModeHash {
+		lastHash, exists := c.Hashes[relPath]
+		if !exists {
+			return true, nil
+		}
+
+		hash, err := hashFile(filePath)
+		if err != nil {
+			return false, err
+		}
+		return hash != lastHash, nil
+	}
+
 	lastProcessed, exists := c.ProcessedFiles[relPath]
 	if !exists {
 		return true, nil
@@ -174,7 +597,8 @@ func (c *FileCache) shouldProcess(filePath string) (bool, error) {
 
 // markProcessed records the file's current modification time, not the current time.
 // This ensures the cache accurately reflects when the file content was last changed,
-// preventing false cache misses if the file is touched but not modified.
+// preventing false cache misses if the file is touched but not modified. In hash mode the
+// content hash is recorded as well.
 func (c *FileCache) markProcessed(filePath string) error {
 	info, err := os.Stat(filePath)
 	if err != nil {
@@ -187,9 +611,61 @@ func (c *FileCache) markProcessed(filePath string) error {
 	}
 
 	c.ProcessedFiles[relPath] = info.ModTime()
+
+	if c.Current != (ProcessingInfo{}) {
+		if c.Provenance == nil {
+			c.Provenance = make(map[string]ProcessingInfo)
+		}
+		c.Provenance[relPath] = c.Current
+	}
+
+	if c.Mode == cacheModeHash {
+		hash, err := hashFile(filePath)
+		if err != nil {
+			return err
+		}
+		if c.Hashes == nil {
+			c.Hashes = make(map[string]string)
+		}
+		c.Hashes[relPath] = hash
+	}
+
 	return nil
 }
 
+// prune removes entries for files that no longer exist on disk and returns how many were
+// removed. Deleted and renamed files otherwise stay in the cache forever.
+func (c *FileCache) prune() (int, error) {
+	removed := 0
+
+	for relPath := range c.ProcessedFiles {
+		absPath, err := toAbsolutePath(relPath)
+		if err != nil {
+			return removed, fmt.Errorf("failed to convert to absolute path: %w", err)
+		}
+
+		if _, err := os.Stat(absPath); os.IsNotExist(err) {
+			delete(c.ProcessedFiles, relPath)
+			delete(c.Hashes, relPath)
+			delete(c.Provenance, relPath)
+			removed++
+		}
+	}
+
+	return removed, nil
+}
+
+// hashFile returns the hex-encoded SHA-256 of the file's contents.
+func hashFile(filePath string) (string, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read file: %w", err)
+	}
+
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:]), nil
+}
+
 // getStagedFiles retrieves the list of staged files from git.
 // These are files that have been added to the git staging area via git add.
 func getStagedFiles() ([]string, error) {
@@ -199,16 +675,10 @@ func getStagedFiles() ([]string, error) {
 		return nil, fmt.Errorf("failed to get staged files: %w", err)
 	}
 
-	// Split by newlines and filter out empty strings
-	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
-	files := make([]string, 0, len(lines))
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line != "" {
-			files = append(files, line)
-		}
+	files, err := resolveGitPaths(parseFileList(string(output)))
+	if err != nil {
+		return nil, err
 	}
-
 	if len(files) == 0 {
 		return nil, fmt.Errorf("no staged files found")
 	}
@@ -216,400 +686,2151 @@ func getStagedFiles() ([]string, error) {
 	return files, nil
 }
 
-func main() {
-	batchSize := flag.Int("batch-size", 24, "Number of files to process in parallel per batch")
-	forceProcess := flag.Bool("force", false, "Force reprocessing of all files, ignoring cache")
-	cacheOnly := flag.Bool("cache-only", false, "Mark files as cached without processing (useful for initialization)")
-	staged := flag.Bool("staged", false, "Process only staged files from git")
-	prompt := flag.String("prompt", `You are tasked with adding thoughtful, meaningful comments to the
-{filename} ONLY. Do not modify any other files or suggest
-changes to other files.
+// getModifiedFiles retrieves the files changed in the working tree relative to the index,
+// plus untracked files that are not gitignored. Deleted files are left out since there is
+// nothing left to process.
+func getModifiedFiles() ([]string, error) {
+	cmd := exec.Command("git", "diff", "--name-status")
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get modified files: %w", err)
+	}
+	files := parseNameStatus(string(output))
 
-## Context Gathering for Complex Code
-Before adding comments, thoroughly analyze the codebase context:
-1. **Examine imports and dependencies** - Look at what external libraries, modules, or APIs this code relies on and their specific behaviors or constraints
-2. **Study related files** - Consider how this code interacts with other parts of the system, especially interfaces, shared types, or utility functions
-3. **Understand the broader architecture** - Identify the role this code plays in the overall system and any architectural patterns or constraints
-4. **Research external context** - When you encounter unfamiliar APIs, frameworks, or domain-specific logic, reason about what additional context would help explain the code's purpose and constraints
-5. **Identify complexity indicators** - Pay special attention to:
-   - Complex algorithms or data structures that aren't immediately obvious
-   - Error handling that suggests specific edge cases or failure modes
-   - Performance optimizations that trade readability for efficiency
-   - Security considerations or access control logic
-   - Business logic that requires domain knowledge to understand
+	// --full-name lists untracked files relative to the repository root like git diff does
+	cmd = exec.Command("git", "ls-files", "--others", "--exclude-standard", "--full-name")
+	output, err = cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get untracked files: %w", err)
+	}
+	files, err = resolveGitPaths(append(files, parseFileList(string(output))...))
+	if err != nil {
+		return nil, err
+	}
 
-## Core Principles
-1. **Focus on "Why", not "What"**: Comments should explain the RATIONALE and INTENT behind the code, not describe what it does. If a comment starts with "This code..." or "This function...", it's likely describing "what" instead of "why". Good comments answer questions like:
-   - Why was this approach chosen over simpler alternatives?
-   - Why must this edge case be handled this way?
-   - Why is this performance optimization structured like this?
-   - Why does this business rule require this specific logic?
-   - Why is this seemingly unusual code actually the correct approach?
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no modified files found")
+	}
 
-2. **Embrace Strategic Silence**: Most code is self-explanatory through good naming and should have NO comments. Only comment when there's a genuine gap between what the code appears to do and why it must do it that way. Ask: "Would a reasonably experienced developer understand both WHAT this code does AND WHY it must work this way without comments?"
+	return files, nil
+}
 
-3. **Target Only True Complexity**: Add comments ONLY for:
-	- Language-specific subtleties (e.g., "Closure captures loop variable by reference, not value")
-	- Business logic nuances (e.g., "Must check both user role AND subscription status for access")
-	- Performance-critical sections with non-obvious optimizations (e.g., "O(n) vs O(n²) choice due to data size constraints")
-	- Complex algorithms that aren't immediately clear (e.g., "Using A* search because Dijkstra's would be O(n²) for this graph density")
-	- APIs that require careful usage to avoid errors (e.g., "Must call Close() to prevent resource leaks in this framework")
-	- Code that appears unusual but is intentional (e.g., "Polling used instead of events because USB driver doesn't support async notifications")
-	- External dependencies with specific constraints (e.g., "PostgreSQL JSONB used for schemaless data despite MongoDB alternative")
-4. **Preserve Code Clarity**: If the code can be made clearer
-through better naming rather than comments, note this but DO NOT
-rename anything - only add comments to the existing code as-is.
-5. **Improve Code Formatting**: Add appropriate newlines to improve
-readability and logical grouping. Follow language-specific conventions:
-	- Add blank lines between logical sections
-	- Separate related but distinct operations with blank lines
-	- Group related statements together without blank lines
-	- Follow standard formatting conventions for the language
+// resolveGitPaths returns the absolute paths of files listed by git diff, which names them
+// relative to the repository root wherever it runs. They are resolved from the working
+// directory through the path back up to the root, so they match paths given on the
+// command line even when the working directory is reached through a symlink.
+func resolveGitPaths(files []string) ([]string, error) {
+	output, err := exec.Command("git", "rev-parse", "--show-cdup").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to find git repository root: %w", err)
+	}
+	cdup := strings.TrimSpace(string(output))
 
-## What to Comment
-- **Why** a particular approach was chosen over alternatives
-- **Why** certain edge cases are handled in specific ways
-- **Why** performance optimizations are structured as they are
-- **Why** business rules require specific logic flow
-- **Why** specific external APIs or frameworks are used and their constraints
-- **Why** complex data structures or algorithms are implemented this way
-- Assumptions that must hold true for the code to work correctly
-- Side effects that aren't immediately obvious
-- Relationships between distant parts of the code (e.g., callbacks
-defined far from their usage)
-- External context or domain knowledge required to understand the code
+	paths := make([]string, 0, len(files))
+	for _, file := range files {
+		absPath, err := filepath.Abs(filepath.Join(cdup, filepath.FromSlash(file)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve absolute path for %s: %w", file, err)
+		}
+		paths = append(paths, absPath)
+	}
+	return paths, nil
+}
 
-## What NOT to Comment
-- **Any comment that starts with "This..."**: "This function...", "This code...", "This variable..." - these describe WHAT, not WHY
-- **Obvious operations**: "Increment counter", "Return true if valid", "Loop through array"
-- **Simple getters/setters**: "Get user name", "Set user age"
-- **Standard patterns**: "Check if nil", "Handle error", "Validate input"
-- **Trivial functions**: Functions with 1-3 lines that do exactly what their name suggests
-- **Redundant explanations**: Comments that just rephrase what clear variable/function names already express
-- **Well-known APIs**: Standard usage of fmt.Println, json.Unmarshal, http.Get, etc.
-- **Self-documenting code**: Any code where good naming makes the purpose and logic crystal clear
+// parseNameStatus extracts the paths that still exist from git diff --name-status output.
+// Each line is a status letter and a tab-separated path; renames and copies (R100, C75)
+// list the old and new paths, of which only the new one exists. Deleted entries are skipped.
+func parseNameStatus(output string) []string {
+	var files []string
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Split(strings.TrimSpace(line), "\t")
+		if len(fields) < 2 || strings.HasPrefix(fields[0], "D") {
+			continue
+		}
+		files = append(files, unquoteGitPath(fields[len(fields)-1]))
+	}
+	return files
+}
 
-## Output Format
-Write to the same file with comments added in the
-appropriate language-specific comment syntax AND improved formatting
-with appropriate newlines. Preserve all existing code exactly as-is -
-only add comments and improve whitespace/newline placement for better
-readability.
+// getPartiallyStagedFiles returns the absolute paths of files with unstaged changes. When
+// such a file is also staged, only some of its changes are meant for the commit, and
+// re-adding the whole file after processing would stage the rest too.
+func getPartiallyStagedFiles() (map[string]bool, error) {
+	cmd := exec.Command("git", "diff", "--name-only")
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get unstaged files: %w", err)
+	}
 
-Remember: **Strategic silence is golden.** Most code needs no comments when well-named. Comments should make future maintainers' lives easier by explaining the non-obvious, not burden them with noise. Only comment when there's a genuine gap between what the code appears to do and why it must work that specific way. When you encounter complex code that would benefit from external context, explain what additional context would be helpful for future maintainers.
-`, "Prompt to send to Claude")
+	files, err := resolveGitPaths(parseFileList(string(output)))
+	if err != nil {
+		return nil, err
+	}
+	unstaged := make(map[string]bool, len(files))
+	for _, file := range files {
+		unstaged[file] = true
+	}
+
+	return unstaged, nil
+}
+
+// filesToRestage returns the processed files that can safely be re-staged, leaving out
+// partially staged ones.
+func filesToRestage(processed []string, partiallyStaged map[string]bool) []string {
+	files := make([]string, 0, len(processed))
+	for _, file := range processed {
+		if !partiallyStaged[file] {
+			files = append(files, file)
+		}
+	}
+	return files
+}
+
+// gitAddCommand builds the git add invocation for re-staging files. The -- keeps file
+// names that start with a dash from being read as options.
+func gitAddCommand(files []string) *exec.Cmd {
+	return exec.Command("git", append([]string{"add", "--"}, files...)...)
+}
+
+// parseDiffRange splits a -diff argument of the form base..head into its two refs.
+func parseDiffRange(spec string) (base, head string, err error) {
+	parts := strings.Split(spec, "..")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" || strings.HasPrefix(parts[1], ".") {
+		return "", "", fmt.Errorf("invalid diff range %q: expected <base>..<head>", spec)
+	}
+
+	// Refs are passed to git as arguments, so a leading dash would be read as an option
+	for _, ref := range parts {
+		if strings.HasPrefix(ref, "-") {
+			return "", "", fmt.Errorf("invalid git ref %q in diff range", ref)
+		}
+	}
+
+	return parts[0], parts[1], nil
+}
+
+// getDiffFiles retrieves the files changed between two git refs, such as the base and
+// head of a pull request. Deleted files are excluded with --diff-filter=d.
+func getDiffFiles(base, head string) ([]string, error) {
+	// Check each ref up front since git's own error for a bad range is hard to read
+	for _, ref := range []string{base, head} {
+		if err := exec.Command("git", "rev-parse", "--verify", "--quiet", ref+"^{commit}").Run(); err != nil {
+			return nil, fmt.Errorf("unknown git ref %q", ref)
+		}
+	}
+
+	cmd := exec.Command("git", "diff", "--name-only", "--diff-filter=d", base+".."+head)
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get changed files: %w", err)
+	}
+
+	files, err := resolveGitPaths(parseFileList(string(output)))
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no files changed between %s and %s", base, head)
+	}
+
+	return files, nil
+}
+
+// parseFileList splits git output listing one path per line, dropping blank lines.
+func parseFileList(output string) []string {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	files := make([]string, 0, len(lines))
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line != "" {
+			files = append(files, unquoteGitPath(line))
+		}
+	}
+	return files
+}
+
+// readFileList reads the paths listed in the file at path, or in stdin if path is "-".
+// Paths are separated by NUL bytes, as printed by git -z or rg -0, if there are any, and
+// otherwise by newlines, in which case they are trimmed and git-quoted paths are decoded.
+func readFileList(path string, stdin io.Reader) ([]string, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file list: %w", err)
+	}
+
+	if bytes.IndexByte(data, 0) == -1 {
+		return parseFileList(string(data)), nil
+	}
+	// NUL-separated names may hold any other byte, so they are used as they are
+	var files []string
+	for file := range strings.SplitSeq(string(data), "\x00") {
+		if file = strings.TrimSuffix(file, "\n"); file != "" {
+			files = append(files, file)
+		}
+	}
+	return files, nil
+}
+
+// unquoteGitPath decodes a path that git printed in quoted form. With core.quotePath (the
+// default), paths containing quotes, control characters, or non-ASCII bytes are wrapped
+// in double quotes with C-style escapes, e.g. "src/caf\303\251.go". Go's string literal
+// syntax accepts the same escapes, including octal bytes.
+func unquoteGitPath(path string) string {
+	if len(path) < 2 || path[0] != '"' || path[len(path)-1] != '"' {
+		return path
+	}
+
+	unquoted, err := strconv.Unquote(path)
+	if err != nil {
+		return path
+	}
+	return unquoted
+}
+
+func main() {
+	model := flag.String("model", defaultModel, "Claude model used to add comments (e.g. haiku, sonnet)")
+	backend := flag.String("backend", backendClaude, "LLM backend that adds comments: claude or ollama")
+	ollamaURL := flag.String("ollama-url", defaultOllamaURL, "Base URL of the Ollama server used with -backend ollama")
+	claudeBin := flag.String("claude-bin", "claude", "Claude CLI executable to run")
+	claudeArgs := flag.String("claude-args", defaultClaudeArgs, "Extra arguments passed to Claude, separated by spaces (replaces the default permission flags)")
+	retries := flag.Int("retries", 2, "Number of times to retry Claude after a transient failure such as a rate limit")
+	retryBackoff := flag.Duration("retry-backoff", 2*time.Second, "Wait before the first retry; doubles with each further retry")
+	timeout := flag.Duration("timeout", 10*time.Minute, "Maximum time Claude may spend on a single file, retries included (0 disables)")
+	failFast := flag.Bool("fail-fast", false, "Stop at the first file Claude fails on, canceling the files still running")
+	stream := flag.Bool("stream", false, "Show Claude output live as it runs instead of one block per completed file")
+	batchSize := flag.Int("batch-size", 24, "Number of files commented between cache saves")
+	batchDelay := flag.Duration("batch-delay", 0, "Pause before starting each further batch of files to stay under API rate limits (e.g. 30s)")
+	concurrency := flag.Int("concurrency", 0, "Maximum number of files commented at once across the run (default: the batch size)")
+	limit := flag.Int("limit", 0, "Process at most this many files, in sorted order, not counting gitignored, excluded, or unsupported ones (default: no limit)")
+	offset := flag.Int("offset", 0, "Skip this many files, in sorted order and counted like -limit, before processing (see -limit)")
+	since := flag.Duration("since", 0, "Only process files modified within this long (e.g. 24h), by modification time")
+	forceProcess := flag.Bool("force", false, "Force reprocessing of all files, ignoring cache")
+	cacheOnly := flag.Bool("cache-only", false, "Mark files as cached without processing (useful for initialization)")
+	check := flag.Bool("check", false, "Exit with an error listing the files that contain comments, without modifying anything")
+	dryRun := flag.Bool("dry-run", false, "Remove comments and format files, then print the prompts instead of running Claude")
+	staged := flag.Bool("staged", false, "Process only staged files from git")
+	stdin := flag.Bool("stdin", false, "Remove comments from the source read on stdin and print it to stdout, without the cache, git, or Claude")
+	lang := flag.String("lang", "", "Language of the -stdin source, e.g. go or python (default: detected from a #! line or the content)")
+	filesFrom := flag.String("files-from", "", "Also process the paths listed in this file, one per line or NUL-separated (- for stdin)")
+	var excludes, excludeExts []string
+	flag.Func("exclude", "Glob pattern for files or directories to skip when walking directory arguments, or comma-separated extensions of files to skip, e.g. .yaml,.yml (repeatable)", func(pattern string) error {
+		// Extensions still act as glob patterns so that "-exclude .venv" prunes directories
+		if exts, ok := parseExtensions(pattern); ok {
+			excludes = append(excludes, exts...)
+			excludeExts = append(excludeExts, exts...)
+			return nil
+		}
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return err
+		}
+		excludes = append(excludes, pattern)
+		return nil
+	})
+	excludeFrom := flag.String("exclude-from", "", "File of gitignore-syntax patterns for files to skip, relative to the git root")
+	var includes []string
+	flag.Func("include", "Comma-separated extensions of the files to process, e.g. .go,.ts (repeatable)", func(value string) error {
+		exts, ok := parseExtensions(value)
+		if !ok {
+			return fmt.Errorf("want comma-separated extensions such as .go,.ts")
+		}
+		includes = append(includes, exts...)
+		return nil
+	})
+	formatters := make(map[string][]string)
+	flag.Func("formatter", "Formatter command for a language as <language>=<command>, e.g. go=gofumpt -w; an empty command disables it (repeatable)", func(value string) error {
+		lang, command, ok := strings.Cut(value, "=")
+		if !ok {
+			return fmt.Errorf("want <language>=<command>")
+		}
+		lang = strings.ToLower(strings.TrimSpace(lang))
+		if stripper.Name(lang) == "" {
+			return fmt.Errorf("unknown language %q", lang)
+		}
+		formatters[lang] = strings.Fields(command)
+		return nil
+	})
+	noFormat := flag.Bool("no-format", false, "Don't run formatters on files")
+	safe := flag.Bool("safe", false, "Restore a file's original contents, comments included, when Claude fails on it")
+	skipUnchanged := flag.Bool("skip-unchanged", false, "Skip files that have no comments to remove instead of sending them to Claude")
+	diffRange := flag.String("diff", "", "Process only files changed between two git refs, given as <base>..<head>")
+	restage := flag.Bool("restage", true, "With -staged, git add processed files again so the commit includes the new comments")
+	modified := flag.Bool("modified", false, "Process only modified and untracked files in the git working tree")
+	cacheFile := flag.String("cache-file", "", "Cache file location (default: .nocomms-cache.json at the git root, or $NOCOMMS_CACHE)")
+	listLanguagesFlag := flag.Bool("list-languages", false, "Print the supported file extensions with their language and formatter, and exit")
+	clearCacheFlag := flag.Bool("clear-cache", false, "Delete the cache file and exit")
+	backup := flag.Bool("backup", false, "Copy files to "+backupDirName+" in the repository root before modifying them")
+	validate := flag.Bool("validate", false, "Check that commented Go, Python, and JavaScript files still parse, failing those that don't (restored from their backups with -backup)")
+	restore := flag.Bool("restore", false, "Restore the files saved by -backup, delete the backups, and exit")
+	jsonc := flag.Bool("jsonc", false, "Treat .json files as JSON with comments instead of skipping them")
+	keepDirectives := flag.String("keep-directives", strings.Join(stripper.DefaultDirectives, ","), "Comma-separated prefixes of directive comments to preserve, matched after the comment marker (empty to remove all)")
+	keepHeader := flag.Bool("keep-header", false, "Preserve the comment block at the top of each file, such as a license header")
+	keepTrailingWhitespace := flag.Bool("keep-trailing-whitespace", false, "Only trim the whitespace before removed comments, keeping trailing whitespace on other lines")
+	keepDocs := flag.Bool("keep-docs", false, "Preserve documentation comments (Go doc comments, Rust ///, JSDoc /** */, Solidity NatSpec)")
+	keepNatSpec := flag.Bool("keep-natspec", false, "Preserve Solidity NatSpec comments (/// and /** */)")
+	var asmDialect string
+	flag.Func("asm-dialect", "Comment syntax of assembly files, gas or nasm (default: nasm for .asm files, gas otherwise)", func(value string) error {
+		if !slices.Contains(stripper.AsmDialects, value) {
+			return fmt.Errorf("must be one of %s", strings.Join(stripper.AsmDialects, ", "))
+		}
+		asmDialect = value
+		return nil
+	})
+	var keepRegionStart, keepRegionEnd string
+	flag.Func("keep-regions", "Leave regions from a START comment line through an END one untouched, given as START,END (e.g. nocomms:off,nocomms:on)", func(value string) error {
+		start, end, ok := strings.Cut(value, ",")
+		start, end = strings.TrimSpace(start), strings.TrimSpace(end)
+		if !ok || start == "" || end == "" || strings.Contains(end, ",") {
+			return fmt.Errorf("must be two markers separated by a comma, as in nocomms:off,nocomms:on")
+		}
+		keepRegionStart, keepRegionEnd = start, end
+		return nil
+	})
+	pruneCache := flag.Bool("prune-cache", false, "Remove cache entries for files that no longer exist")
+	cacheMode := flag.String("cache-mode", cacheModeMtime, "How to detect changed files: mtime (modification time) or hash (content SHA-256)")
+	collapseNewlines := flag.Bool("collapse-newlines", true, "Collapse blank lines left behind by removed comments")
+	output := flag.String("output", outputText, "Output format: text, or json for a report of every file on stdout")
+	var verbose, quiet bool
+	flag.BoolVar(&verbose, "verbose", false, "Also print each file's formatter and backend steps")
+	flag.BoolVar(&verbose, "v", false, "Shorthand for -verbose")
+	flag.BoolVar(&quiet, "quiet", false, "Print only errors, warnings, and the final summary")
+	flag.BoolVar(&quiet, "q", false, "Shorthand for -quiet")
+	promptFile := flag.String("prompt-file", "", "Read the prompt from this file (-prompt takes precedence when both are set)")
+	prompt := flag.String("prompt", `You are tasked with adding thoughtful, meaningful comments to the
+{filename} ONLY. Do not modify any other files or suggest
+changes to other files.
+
+## Context Gathering for Complex Code
+Before adding comments, thoroughly analyze the codebase context:
+1. **Examine imports and dependencies** - Look at what external libraries, modules, or APIs this code relies on and their specific behaviors or constraints
+2. **Study related files** - Consider how this code interacts with other parts of the system, especially interfaces, shared types, or utility functions
+3. **Understand the broader architecture** - Identify the role this code plays in the overall system and any architectural patterns or constraints
+4. **Research external context** - When you encounter unfamiliar APIs, frameworks, or domain-specific logic, reason about what additional context would help explain the code's purpose and constraints
+5. **Identify complexity indicators** - Pay special attention to:
+   - Complex algorithms or data structures that aren't immediately obvious
+   - Error handling that suggests specific edge cases or failure modes
+   - Performance optimizations that trade readability for efficiency
+   - Security considerations or access control logic
+   - Business logic that requires domain knowledge to understand
+
+## Core Principles
+1. **Focus on "Why", not "What"**: Comments should explain the RATIONALE and INTENT behind the code, not describe what it does. If a comment starts with "This code..." or "This function...", it's likely describing "what" instead of "why". Good comments answer questions like:
+   - Why was this approach chosen over simpler alternatives?
+   - Why must this edge case be handled this way?
+   - Why is this performance optimization structured like this?
+   - Why does this business rule require this specific logic?
+   - Why is this seemingly unusual code actually the correct approach?
+
+2. **Embrace Strategic Silence**: Most code is self-explanatory through good naming and should have NO comments. Only comment when there's a genuine gap between what the code appears to do and why it must do it that way. Ask: "Would a reasonably experienced developer understand both WHAT this code does AND WHY it must work this way without comments?"
+
+3. **Target Only True Complexity**: Add comments ONLY for:
+	- Language-specific subtleties (e.g., "Closure captures loop variable by reference, not value")
+	- Business logic nuances (e.g., "Must check both user role AND subscription status for access")
+	- Performance-critical sections with non-obvious optimizations (e.g., "O(n) vs O(n²) choice due to data size constraints")
+	- Complex algorithms that aren't immediately clear (e.g., "Using A* search because Dijkstra's would be O(n²) for this graph density")
+	- APIs that require careful usage to avoid errors (e.g., "Must call Close() to prevent resource leaks in this framework")
+	- Code that appears unusual but is intentional (e.g., "Polling used instead of events because USB driver doesn't support async notifications")
+	- External dependencies with specific constraints (e.g., "PostgreSQL JSONB used for schemaless data despite MongoDB alternative")
+4. **Preserve Code Clarity**: If the code can be made clearer
+through better naming rather than comments, note this but DO NOT
+rename anything - only add comments to the existing code as-is.
+5. **Improve Code Formatting**: Add appropriate newlines to improve
+readability and logical grouping. Follow language-specific conventions:
+	- Add blank lines between logical sections
+	- Separate related but distinct operations with blank lines
+	- Group related statements together without blank lines
+	- Follow standard formatting conventions for the language
+
+## What to Comment
+- **Why** a particular approach was chosen over alternatives
+- **Why** certain edge cases are handled in specific ways
+- **Why** performance optimizations are structured as they are
+- **Why** business rules require specific logic flow
+- **Why** specific external APIs or frameworks are used and their constraints
+- **Why** complex data structures or algorithms are implemented this way
+- Assumptions that must hold true for the code to work correctly
+- Side effects that aren't immediately obvious
+- Relationships between distant parts of the code (e.g., callbacks
+defined far from their usage)
+- External context or domain knowledge required to understand the code
+
+## What NOT to Comment
+- **Any comment that starts with "This..."**: "This function...", "This code...", "This variable..." - these describe WHAT, not WHY
+- **Obvious operations**: "Increment counter", "Return true if valid", "Loop through array"
+- **Simple getters/setters**: "Get user name", "Set user age"
+- **Standard patterns**: "Check if nil", "Handle error", "Validate input"
+- **Trivial functions**: Functions with 1-3 lines that do exactly what their name suggests
+- **Redundant explanations**: Comments that just rephrase what clear variable/function names already express
+- **Well-known APIs**: Standard usage of fmt.Println, json.Unmarshal, http.Get, etc.
+- **Self-documenting code**: Any code where good naming makes the purpose and logic crystal clear
+
+## Output Format
+Write to the same file with comments added in the
+appropriate language-specific comment syntax AND improved formatting
+with appropriate newlines. Preserve all existing code exactly as-is -
+only add comments and improve whitespace/newline placement for better
+readability.
+
+Remember: **Strategic silence is golden.** Most code needs no comments when well-named. Comments should make future maintainers' lives easier by explaining the non-obvious, not burden them with noise. Only comment when there's a genuine gap between what the code appears to do and why it must work that specific way. When you encounter complex code that would benefit from external context, explain what additional context would be helpful for future maintainers.
+`, "Prompt to send to Claude, or - to read it from stdin")
+
+	// The config file only supplies defaults, so it is applied before flags are parsed
+	fileConfig, err := loadFileConfig(configFilePath())
+	if err == nil {
+		err = fileConfig.apply(flag.CommandLine)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	flag.Parse()
+	if err := fileConfig.applyLists(flag.CommandLine); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *clearCacheFlag {
+		cachePath, err := getCachePath(*cacheFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		entries, err := clearCache(cachePath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Cleared cache: removed %d entries from %s\n", entries, cachePath)
+		return
+	}
+
+	if *listLanguagesFlag {
+		config := Config{JSONC: *jsonc, Formatters: formatters, NoFormat: *noFormat}
+		if err := listLanguages(os.Stdout, config); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *restore {
+		restored, err := restoreBackups()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Restored %d file(s) from %s\n", restored, backupDirName)
+		return
+	}
+
+	// Stdin mode only strips, so it exits before anything reads a prompt, files, or the cache
+	if *stdin {
+		if flag.NArg() > 0 || *staged || *modified || *diffRange != "" || *filesFrom != "" || *check {
+			fmt.Fprintln(os.Stderr, "Error: -stdin cannot be combined with file arguments, -staged, -modified, -diff, -files-from, or -check")
+			os.Exit(1)
+		}
+		config := Config{
+			KeepDirectives:         parseDirectives(*keepDirectives),
+			KeepHeader:             *keepHeader,
+			KeepRegionStart:        keepRegionStart,
+			KeepRegionEnd:          keepRegionEnd,
+			KeepTrailingWhitespace: *keepTrailingWhitespace,
+			KeepDocs:               *keepDocs,
+			KeepNatSpec:            *keepNatSpec,
+			AsmDialect:             asmDialect,
+			CollapseNewlines:       *collapseNewlines,
+		}
+		if err := stripStdin(strings.ToLower(*lang), config, os.Stdin, os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if *lang != "" {
+		fmt.Fprintln(os.Stderr, "Error: -lang is only used with -stdin")
+		os.Exit(1)
+	}
+
+	promptSet := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "prompt" {
+			promptSet = true
+		}
+	})
+	if *prompt == "-" && *filesFrom == "-" {
+		fmt.Fprintln(os.Stderr, "Error: -prompt - cannot be combined with -files-from -, since both read stdin")
+		os.Exit(1)
+	}
+	loadedPrompt, err := loadPrompt(*prompt, promptSet, *promptFile, os.Stdin)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	*prompt = loadedPrompt
+
+	if *prompt == "" {
+		fmt.Fprintln(os.Stderr, "Error: -prompt flag is required")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	if strings.TrimSpace(*model) == "" {
+		fmt.Fprintln(os.Stderr, "Error: -model must not be empty")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	switch *backend {
+	case backendClaude:
+	case backendOllama:
+		// The default model is a Claude model, so a local one has to be named
+		if *model == defaultModel {
+			fmt.Fprintln(os.Stderr, "Error: -backend ollama requires -model to name a local model (e.g. llama3.1)")
+			flag.Usage()
+			os.Exit(1)
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "Error: invalid -backend %q (must be %q or %q)\n", *backend, backendClaude, backendOllama)
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	if *concurrency < 0 {
+		fmt.Fprintln(os.Stderr, "Error: -concurrency must not be negative")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	if *limit < 0 || *offset < 0 {
+		fmt.Fprintln(os.Stderr, "Error: -limit and -offset must not be negative")
+		flag.Usage()
+		os.Exit(1)
+	}
+	if *since < 0 {
+		fmt.Fprintln(os.Stderr, "Error: -since must not be negative")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	if *dryRun && *cacheOnly {
+		fmt.Fprintln(os.Stderr, "Error: -dry-run cannot be combined with -cache-only")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	if *cacheMode != cacheModeMtime && *cacheMode != cacheModeHash {
+		fmt.Fprintf(os.Stderr, "Error: invalid -cache-mode %q (must be %q or %q)\n", *cacheMode, cacheModeMtime, cacheModeHash)
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	if *output != outputText && *output != outputJSON {
+		fmt.Fprintf(os.Stderr, "Error: invalid -output %q (must be %q or %q)\n", *output, outputText, outputJSON)
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	if verbose && quiet {
+		fmt.Fprintln(os.Stderr, "Error: -verbose cannot be combined with -quiet")
+		os.Exit(1)
+	}
+	level := levelNormal
+	if verbose {
+		level = levelVerbose
+	} else if quiet {
+		level = levelQuiet
+	}
+	// JSON output keeps stdout for the report, so progress goes to stderr as the JSON
+	// reporter's does
+	progressOut := os.Stdout
+	if *output == outputJSON {
+		progressOut = os.Stderr
+	}
+	progress := logger{progressOut, level}
+
+	var files []string
+
+	if *staged {
+		// Get staged files from git when -staged flag is set
+		files, err = getStagedFiles()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		progress.printf(levelNormal, "Found %d staged file(s)", len(files))
+	} else if *modified {
+		files, err = getModifiedFiles()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		progress.printf(levelNormal, "Found %d modified file(s)", len(files))
+	} else if *diffRange != "" {
+		base, head, err := parseDiffRange(*diffRange)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		files, err = getDiffFiles(base, head)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		progress.printf(levelNormal, "Found %d changed file(s) between %s and %s", len(files), base, head)
+	} else {
+		// Use command-line arguments when no git file selection flag is set
+		files = flag.Args()
+		if len(files) == 0 && *filesFrom == "" {
+			fmt.Fprintln(os.Stderr, "Error: No files provided. Use -staged, -modified, -diff, or -files-from or provide file paths as arguments")
+			flag.Usage()
+			os.Exit(1)
+		}
+	}
+
+	files, err = expandGlobs(files)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Listed paths are added after glob expansion since other tools print them literally
+	if *filesFrom != "" {
+		listed, err := readFileList(*filesFrom, os.Stdin)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		progress.printf(levelNormal, "Read %d file(s) from %s", len(listed), *filesFrom)
+		files = append(files, listed...)
+	}
+
+	// Convert all input paths to absolute paths upfront to ensure consistent
+	// cache key generation and avoid ambiguity between relative path interpretations.
+	// A file named by several sources, such as -staged and -files-from, is processed once.
+	absoluteFiles := make([]string, 0, len(files))
+	seen := make(map[string]bool, len(files))
+	for _, file := range files {
+		absPath, err := filepath.Abs(file)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to resolve absolute path for %s: %v\n", file, err)
+			os.Exit(1)
+		}
+		if !seen[absPath] {
+			seen[absPath] = true
+			absoluteFiles = append(absoluteFiles, absPath)
+		}
+	}
+
+	config := Config{
+		Files:                  absoluteFiles,
+		BatchSize:              *batchSize,
+		Concurrency:            *concurrency,
+		Offset:                 *offset,
+		Limit:                  *limit,
+		Since:                  *since,
+		BatchDelay:             *batchDelay,
+		Prompt:                 *prompt,
+		Model:                  *model,
+		Backend:                *backend,
+		OllamaURL:              *ollamaURL,
+		ClaudeBin:              *claudeBin,
+		ClaudeArgs:             strings.Fields(*claudeArgs),
+		Retries:                *retries,
+		RetryBackoff:           *retryBackoff,
+		Timeout:                *timeout,
+		FailFast:               *failFast,
+		ForceProcess:           *forceProcess,
+		CacheOnly:              *cacheOnly,
+		DryRun:                 *dryRun,
+		Backup:                 *backup,
+		Validate:               *validate,
+		Stream:                 *stream,
+		JSONC:                  *jsonc,
+		KeepDirectives:         parseDirectives(*keepDirectives),
+		KeepHeader:             *keepHeader,
+		KeepRegionStart:        keepRegionStart,
+		KeepRegionEnd:          keepRegionEnd,
+		KeepTrailingWhitespace: *keepTrailingWhitespace,
+		KeepDocs:               *keepDocs,
+		KeepNatSpec:            *keepNatSpec,
+		AsmDialect:             asmDialect,
+		CollapseNewlines:       *collapseNewlines,
+		Restage:                *staged && *restage,
+		CacheFile:              *cacheFile,
+		PruneCache:             *pruneCache,
+		CacheMode:              *cacheMode,
+		Output:                 *output,
+		LogLevel:               level,
+		Formatters:             formatters,
+		NoFormat:               *noFormat,
+		Exclude:                excludes,
+		ExcludeFrom:            *excludeFrom,
+		Include:                includes,
+		ExcludeExtensions:      excludeExts,
+		SkipUnchanged:          *skipUnchanged,
+		Safe:                   *safe,
+	}
+
+	config.Files, err = expandDirectories(config.Files, config)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *check {
+		commented, err := checkFiles(config.Files, config)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		for _, file := range commented {
+			fmt.Printf("Contains comments: %s\n", file)
+		}
+		if len(commented) > 0 {
+			fmt.Fprintf(os.Stderr, "Error: %d file(s) contain comments\n", len(commented))
+			os.Exit(1)
+		}
+		fmt.Println("No comments found")
+		return
+	}
+
+	reporter := newReporter(config, os.Stdout, os.Stderr)
+	result, err := run(config, reporter)
+	reporter.Finish(result, err)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// selectFiles returns the files of config.Files that a run works on, narrowed by
+// extension, -since, and -offset and -limit, reporting how many each narrowing kept with
+// progressf. Files outside the targeted languages aren't part of the run, so their
+// removal goes unreported. The window counts only files that can be processed, leaving
+// out gitignored ones, those the -exclude-from patterns ignore, and those of unsupported
+// types, so that -limit N processes N files when there are that many. It still counts
+// files the cache has, so that consecutive offsets cover a backlog as it is worked through.
+func selectFiles(config Config, excluded *ignoreMatcher, progressf func(format string, args ...any)) []string {
+	files := filterExtensions(config.Files, config)
+	if config.Since > 0 {
+		total := len(files)
+		files = modifiedSince(files, time.Now().Add(-config.Since))
+		progressf("Selected %d of %d files modified in the last %s", len(files), total, config.Since)
+	}
+	if config.Offset > 0 || config.Limit > 0 {
+		files = slices.DeleteFunc(slices.Clone(files), func(file string) bool {
+			if isGitIgnored(file) || excluded.ignored(file) {
+				return true
+			}
+			_, err := commentRemover(file, config)
+			return err != nil
+		})
+		total := len(files)
+		files = fileWindow(files, config.Offset, config.Limit)
+		progressf("Selected %d of %d files starting at offset %d", len(files), total, config.Offset)
+	}
+	return files
+}
+
+// loadExcludeFrom returns the patterns of the -exclude-from file, or nil, which ignores
+// nothing, when there is none.
+func loadExcludeFrom(config Config) (*ignoreMatcher, error) {
+	if config.ExcludeFrom == "" {
+		return nil, nil
+	}
+	root, err := findRoot()
+	if err != nil {
+		return nil, err
+	}
+	return loadIgnoreFile(config.ExcludeFrom, root)
+}
+
+func run(config Config, reporter Reporter) (RunResult, error) {
+	var result RunResult
+	excluded, err := loadExcludeFrom(config)
+	if err != nil {
+		return result, err
+	}
+	config.Files = selectFiles(config, excluded, reporter.Progressf)
+
+	// Without the Claude CLI every file would fail, after its comments had been removed
+	if config.Backend != backendOllama && !config.DryRun && !config.CacheOnly {
+		if _, err := lookPath(config.ClaudeBin); err != nil {
+			return result, fmt.Errorf("%w; install the Claude CLI (npm install -g @anthropic-ai/claude-code) or point -claude-bin at it", err)
+		}
+	}
+
+	cachePath, err := getCachePath(config.CacheFile)
+	if err != nil {
+		return result, err
+	}
+
+	release, err := acquireCacheLock(cachePath)
+	if err != nil {
+		return result, err
+	}
+	defer release()
+	defer releaseOnSignal(release)()
+
+	cache, err := loadCache(cachePath)
+	if err != nil {
+		return result, fmt.Errorf("failed to load cache: %w", err)
+	}
+	cache.Mode = config.CacheMode
+	// Recording the model means switching models reprocesses files commented by the previous one
+	cache.Current = ProcessingInfo{Model: config.Model, Version: version}
+
+	// Dry runs leave the cache alone, so pruning is skipped along with the saves
+	if config.PruneCache && !config.DryRun {
+		removed, err := cache.prune()
+		if err != nil {
+			return result, fmt.Errorf("failed to prune cache: %w", err)
+		}
+
+		// Save right away since later saves only happen when files are processed
+		if err := cache.save(); err != nil {
+			return result, fmt.Errorf("failed to save cache: %w", err)
+		}
+		reporter.Progressf("Pruned %d stale cache entries", removed)
+	}
+
+	// Partially staged files must be detected before comment removal modifies the working tree
+	var partiallyStaged map[string]bool
+	if config.Restage {
+		partiallyStaged, err = getPartiallyStagedFiles()
+		if err != nil {
+			return result, err
+		}
+	}
+
+	// Cache-only mode allows initializing the cache without expensive processing,
+	// useful for marking existing commented code as "already processed"
+	if config.CacheOnly {
+		reporter.Progressf("Cache-only mode: marking files as cached without processing")
+
+		for _, file := range config.Files {
+			// Skip gitignored files even in cache-only mode
+			if isGitIgnored(file) || excluded.ignored(file) {
+				reporter.Skipped(file, skipGitIgnored)
+				result.skip(file, skipGitIgnored)
+				continue
+			}
+
+			if err := cache.markProcessed(file); err != nil {
+				err = fmt.Errorf("failed to mark as cached: %w", err)
+				reporter.Failed(file, err)
+				result.fail(file, err)
+				continue
+			}
+			reporter.Cached(file)
+			result.Processed = append(result.Processed, file)
+		}
+
+		if len(result.Processed) == 0 {
+			return result, fmt.Errorf("no files were successfully cached")
+		}
+
+		if err := cache.save(); err != nil {
+			return result, fmt.Errorf("failed to save cache: %w", err)
+		}
+
+		reporter.Progressf("\nMarked %d files as cached", len(result.Processed))
+		return result, nil
+	}
+
+	// Filter files before expensive Claude processing to avoid unnecessary API calls
+	processedFiles := make([]string, 0, len(config.Files))
+	// With -safe, the contents of each file before its comments were removed
+	originals := make(map[string][]byte)
+
+	for _, file := range config.Files {
+		// Skip gitignored files, and those the -exclude-from patterns ignore the same way
+		if isGitIgnored(file) || excluded.ignored(file) {
+			reporter.Skipped(file, skipGitIgnored)
+			result.skip(file, skipGitIgnored)
+			continue
+		}
+
+		shouldProcess := config.ForceProcess
+		if !shouldProcess {
+			var err error
+			shouldProcess, err = cache.shouldProcess(file)
+			if err != nil {
+				// On cache check failure, err on the side of processing to ensure correctness
+				reporter.Warnf("failed to check cache for %s: %v", file, err)
+				shouldProcess = true
+			}
+		}
+
+		if !shouldProcess {
+			reporter.Skipped(file, skipUnchanged)
+			result.skip(file, skipUnchanged)
+			continue
+		}
+
+		var original []byte
+		if config.Safe && !config.DryRun {
+			var err error
+			if original, err = os.ReadFile(file); err != nil {
+				err = fmt.Errorf("failed to read file: %w", err)
+				reporter.Failed(file, err)
+				result.fail(file, err)
+				continue
+			}
+		}
+
+		// Comment removal happens before Claude processing to provide clean input,
+		// allowing Claude to focus on adding meaningful comments without existing noise
+		if err := processFile(file, config); err != nil {
+			// Check if this is an unsupported file type error
+			var unsupportedErr *stripper.ErrUnsupportedFileType
+			if errors.As(err, &unsupportedErr) {
+				reporter.Skipped(file, skipUnsupported)
+				result.skip(file, skipUnsupported)
+				continue
+			}
+			var binaryErr *stripper.ErrBinaryFile
+			if errors.As(err, &binaryErr) {
+				reporter.Skipped(file, skipBinary)
+				result.skip(file, skipBinary)
+				continue
+			}
+			if errors.Is(err, ErrNoComments) {
+				reporter.Skipped(file, skipUncommented)
+				result.skip(file, skipUncommented)
+				continue
+			}
+			// Other errors only fail this file
+			reporter.Failed(file, err)
+			result.fail(file, err)
+			continue
+		}
+
+		processedFiles = append(processedFiles, file)
+		if original != nil {
+			originals[file] = original
+		}
+		reporter.Removed(file)
+	}
+
+	// Argument, git, and directory walk order vary between invocations and platforms, so
+	// files are started in sorted order to keep runs and their logs reproducible
+	sortByRelativePath(processedFiles)
+
+	if len(processedFiles) == 0 {
+		if len(result.Skipped) > 0 {
+			reporter.Progressf("\nAll %d files are up to date (no changes needed)", len(result.Skipped))
+			return result, nil
+		}
+		return result, fmt.Errorf("no files were successfully processed")
+	}
+
+	if config.DryRun {
+		reporter.Progressf("\nDry run: Claude would process %d files\n", len(processedFiles))
+		for _, file := range processedFiles {
+			if err := formatFile(file, config); err != nil {
+				reporter.Warnf("formatter failed for %s: %v", file, err)
+			}
+			reporter.Progressf("%s\n  Prompt: %s", file, renderPrompt(file, config))
+		}
+		result.Processed = processedFiles
+		return result, nil
+	}
+
+	reporter.Progressf("\nProcessing %d files, %d at a time...\n", len(processedFiles), concurrencyLimit(config, len(processedFiles)))
+
+	newCommenterForFile := func(stdout, stderr io.Writer) Commenter {
+		return newCommenter(config, stdout, stderr)
+	}
+	stats, err := processBatches(processedFiles, config, cache, newCommenterForFile, reporter)
+	restoreUncommented(processedFiles, originals, stats, reporter)
+	// Files that never started, after -fail-fast stopped the run, are left out
+	for _, file := range processedFiles {
+		if fileErr, failed := stats.Errors[file]; failed {
+			result.fail(file, fileErr)
+		} else if _, ok := stats.Usage[file]; ok {
+			result.Processed = append(result.Processed, file)
+		}
+	}
+	result.Usage = stats.Usage
+	result.Total = stats.Total
+	if err != nil {
+		return result, err
+	}
+
+	if config.Restage {
+		for _, file := range processedFiles {
+			if partiallyStaged[file] {
+				reporter.Warnf("not re-staging partially staged file %s; stage its changes manually", file)
+			}
+		}
+
+		if files := filesToRestage(processedFiles, partiallyStaged); len(files) > 0 {
+			if output, err := gitAddCommand(files).CombinedOutput(); err != nil {
+				return result, fmt.Errorf("failed to re-stage files: %w (output: %s)", err, string(output))
+			}
+			reporter.Progressf("Re-staged %d file(s)", len(files))
+		}
+	}
+
+	return result, nil
+}
+
+// restoreUncommented writes back the original contents of the files that Claude failed
+// on or never ran on, whose comments were removed, for -safe. originals is empty
+// without -safe, leaving those files as they are.
+func restoreUncommented(files []string, originals map[string][]byte, stats Stats, reporter Reporter) {
+	for _, file := range files {
+		original, ok := originals[file]
+		if !ok {
+			continue
+		}
+		if _, commented := stats.Usage[file]; commented {
+			continue
+		}
+		// Claude may have partially edited the file before failing, so it is replaced whole
+		if err := os.WriteFile(file, original, 0o644); err != nil {
+			reporter.Warnf("failed to restore %s: %v", file, err)
+			continue
+		}
+		reporter.Progressf("Restored the original contents of %s", file)
+	}
+}
+
+// parseDirectives splits the comma-separated -keep-directives value, ignoring empty entries.
+func parseDirectives(value string) []string {
+	var directives []string
+	for _, directive := range strings.Split(value, ",") {
+		if directive = strings.TrimSpace(directive); directive != "" {
+			directives = append(directives, directive)
+		}
+	}
+	return directives
+}
+
+// checkFiles returns the files that still contain comments, without modifying them.
+// Files are selected as in a normal run, and gitignored, excluded, and unsupported files
+// are skipped.
+func checkFiles(files []string, config Config) ([]string, error) {
+	config.Files = files
+	excluded, err := loadExcludeFrom(config)
+	if err != nil {
+		return nil, err
+	}
+
+	var commented []string
+	for _, file := range selectFiles(config, excluded, func(string, ...any) {}) {
+		if isGitIgnored(file) || excluded.ignored(file) {
+			continue
+		}
+
+		removeComments, err := commentRemover(file, config)
+		if err != nil {
+			var unsupportedErr *stripper.ErrUnsupportedFileType
+			if errors.As(err, &unsupportedErr) {
+				continue
+			}
+			return nil, err
+		}
+
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", file, err)
+		}
+		if stripper.CheckText(content, false) != nil {
+			continue
+		}
+
+		if commentsRemoved(string(content), removeComments(string(content))) {
+			commented = append(commented, file)
+		}
+	}
+
+	return commented, nil
+}
+
+// commentsRemoved reports whether cleaned, content with its comments removed, differs from
+// content in more than whitespace. Removers also tidy whitespace, so that alone doesn't
+// mean a comment was removed.
+func commentsRemoved(content, cleaned string) bool {
+	return strings.Join(strings.Fields(cleaned), " ") != strings.Join(strings.Fields(content), " ")
+}
+
+// stripStdin removes comments from source in lang read from r and writes the result to
+// w. An empty lang is detected from the source. Unlike processFile there is no file, so
+// nothing is cached, backed up, or staged.
+func stripStdin(lang string, config Config, r io.Reader, w io.Writer) error {
+	if lang != "" && stripper.Name(lang) == "" {
+		return fmt.Errorf("unknown language %q (supported: %s)", lang, strings.Join(stripper.Languages, ", "))
+	}
+
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("failed to read stdin: %w", err)
+	}
+	if err := stripper.CheckText(content, false); err != nil {
+		return err
+	}
+	if lang == "" {
+		var ok bool
+		if lang, ok = stripper.DetectLanguageFromContent("", string(content)); !ok {
+			return fmt.Errorf("could not detect the language of stdin; select it with -lang")
+		}
+	}
+
+	cleaned, err := stripper.StripWithOptions(lang, string(content), stripOptions(config))
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, stripper.MatchFinalNewline(string(content), cleaned))
+	return err
+}
+
+func processFile(inputPath string, config Config) error {
+	// Backups need the whole original content in memory anyway
+	if info, err := os.Stat(inputPath); err == nil && info.Size() > streamThreshold && !config.Backup {
+		if lang, ok := detectLanguage(inputPath, config); ok && stripper.CanStream(lang, stripOptions(config)) {
+			return streamFile(inputPath, lang, info.Mode().Perm(), config)
+		}
+	}
+
+	cleaned, err := stripper.StripFile(inputPath, stripOptions(config))
+	if err != nil {
+		return err
+	}
+
+	var content []byte
+	if config.Backup || config.SkipUnchanged {
+		content, err = os.ReadFile(inputPath)
+		if err != nil {
+			return fmt.Errorf("failed to read file: %w", err)
+		}
+	}
+	if config.SkipUnchanged && !commentsRemoved(string(content), cleaned) {
+		return ErrNoComments
+	}
+	if config.Backup {
+		if err := backupFile(inputPath, content); err != nil {
+			return fmt.Errorf("failed to back up file: %w", err)
+		}
+	}
+
+	if err := os.WriteFile(inputPath, []byte(cleaned), 0o644); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+
+	return nil
+}
+
+// streamThreshold is the size above which files are stripped line by line as they are
+// read instead of in memory, when their language allows it; tests lower it.
+var streamThreshold int64 = 8 << 20
+
+// streamFile removes comments from a large file line by line into a temporary file,
+// which then replaces it.
+func streamFile(inputPath, lang string, perm fs.FileMode, config Config) error {
+	in, err := os.Open(inputPath)
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+	defer in.Close()
+
+	sample := make([]byte, binarySampleSize)
+	n, err := in.ReadAt(sample, 0)
+	if err != nil && err != io.EOF {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+	if err := stripper.CheckText(sample[:n], n == len(sample)); err != nil {
+		return err
+	}
+
+	out, err := os.CreateTemp(filepath.Dir(inputPath), filepath.Base(inputPath)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(out.Name())
+
+	// The line-based removers keep a final newline, so only its absence needs handling
+	finalNewline, err := endsWithNewline(in)
+	if err != nil {
+		out.Close()
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+	var w io.Writer = out
+	if !finalNewline {
+		w = &newlineTrimmingWriter{w: out}
+	}
+
+	if err := stripper.StripStream(lang, in, w, stripper.FileOptions(inputPath, lang, stripOptions(config))); err != nil {
+		out.Close()
+		return fmt.Errorf("failed to remove comments: %w", err)
+	}
+	if err := out.Close(); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	if config.SkipUnchanged {
+		same, err := sameWords(inputPath, out.Name())
+		if err != nil {
+			return err
+		}
+		if same {
+			return ErrNoComments
+		}
+	}
+	// CreateTemp files are private, so restore the original permissions
+	if err := os.Chmod(out.Name(), perm); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := os.Rename(out.Name(), inputPath); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	return nil
+}
+
+// sameWords reports whether the files at a and b hold the same text apart from
+// whitespace, as commentsRemoved compares it, reading them a word at a time since
+// streamed files are large.
+func sameWords(a, b string) (bool, error) {
+	fa, err := os.Open(a)
+	if err != nil {
+		return false, fmt.Errorf("failed to read file: %w", err)
+	}
+	defer fa.Close()
+	fb, err := os.Open(b)
+	if err != nil {
+		return false, fmt.Errorf("failed to read file: %w", err)
+	}
+	defer fb.Close()
+
+	wordsA, wordsB := bufio.NewScanner(fa), bufio.NewScanner(fb)
+	for _, words := range []*bufio.Scanner{wordsA, wordsB} {
+		words.Split(bufio.ScanWords)
+		// Minified and generated files can hold very long words
+		words.Buffer(nil, math.MaxInt32)
+	}
+	for {
+		moreA, moreB := wordsA.Scan(), wordsB.Scan()
+		for _, words := range []*bufio.Scanner{wordsA, wordsB} {
+			if err := words.Err(); err != nil {
+				return false, fmt.Errorf("failed to read file: %w", err)
+			}
+		}
+		if moreA != moreB || moreA && !bytes.Equal(wordsA.Bytes(), wordsB.Bytes()) {
+			return false, nil
+		}
+		if !moreA {
+			return true, nil
+		}
+	}
+}
+
+// endsWithNewline reports whether the file f ends with a newline, without moving its
+// read offset.
+func endsWithNewline(f *os.File) (bool, error) {
+	info, err := f.Stat()
+	if err != nil || info.Size() == 0 {
+		return false, err
+	}
+	last := make([]byte, 1)
+	if _, err := f.ReadAt(last, info.Size()-1); err != nil {
+		return false, err
+	}
+	return last[0] == '\n', nil
+}
+
+// newlineTrimmingWriter writes to w all but the line breaks at the very end of the
+// output, like stripper.MatchFinalNewline does for a file without a final newline. Line breaks
+// are held back until something else follows them.
+type newlineTrimmingWriter struct {
+	w       io.Writer
+	pending []byte
+}
+
+func (t *newlineTrimmingWriter) Write(p []byte) (int, error) {
+	text := bytes.TrimRight(p, "\r\n")
+	if len(text) > 0 {
+		if _, err := t.w.Write(t.pending); err != nil {
+			return 0, err
+		}
+		if _, err := t.w.Write(text); err != nil {
+			return 0, err
+		}
+		t.pending = t.pending[:0]
+	}
+	t.pending = append(t.pending, p[len(text):]...)
+	return len(p), nil
+}
+
+// commentRemover returns the comment remover for the file's language, or
+// stripper.ErrUnsupportedFileType if there is none.
+func commentRemover(inputPath string, config Config) (func(string) string, error) {
+	lang, ok := detectLanguage(inputPath, config)
+	if !ok {
+		// Return special error type to indicate unsupported file should be skipped
+		return nil, &stripper.ErrUnsupportedFileType{Extension: filepath.Ext(inputPath)}
+	}
+
+	opts := stripper.FileOptions(inputPath, lang, stripOptions(config))
+	return func(content string) string {
+		// lang always comes from detectLanguage, so it is supported
+		cleaned, _ := stripper.StripWithOptions(lang, content, opts)
+		return stripper.MatchFinalNewline(content, cleaned)
+	}, nil
+}
+
+// stripOptions returns the stripper options selected by config.
+func stripOptions(config Config) stripper.Options {
+	return stripper.Options{
+		KeepDirectives:         config.KeepDirectives,
+		KeepDocs:               config.KeepDocs,
+		KeepNatSpec:            config.KeepNatSpec,
+		AsmDialect:             config.AsmDialect,
+		KeepHeader:             config.KeepHeader,
+		KeepRegionStart:        config.KeepRegionStart,
+		KeepRegionEnd:          config.KeepRegionEnd,
+		KeepTrailingWhitespace: config.KeepTrailingWhitespace,
+		CollapseNewlines:       config.CollapseNewlines,
+		JSONC:                  config.JSONC,
+	}
+}
+
+// detectLanguage returns the stripper language of a file; see stripper.FileLanguage.
+func detectLanguage(path string, config Config) (string, bool) {
+	return stripper.FileLanguage(path, stripOptions(config))
+}
+
+// languageName returns the human-readable name of the language of a supported file,
+// or an empty string for other files.
+func languageName(path string, config Config) string {
+	lang, _ := detectLanguage(path, config)
+	return stripper.Name(lang)
+}
+
+// expandDirectories replaces directory arguments with the supported files beneath them.
+// .git directories, gitignored directories, and paths matching an exclude pattern are
+// pruned; gitignored files are left for run to skip like any other argument.
+func expandDirectories(paths []string, config Config) ([]string, error) {
+	files := make([]string, 0, len(paths))
+
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil || !info.IsDir() {
+			// Files, and paths that fail to stat, are passed through so they are reported per file
+			files = append(files, path)
+			continue
+		}
 
-	flag.Parse()
+		err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
 
-	if *prompt == "" {
-		fmt.Fprintln(os.Stderr, "Error: -prompt flag is required")
-		flag.Usage()
-		os.Exit(1)
-	}
+			relPath, err := filepath.Rel(path, p)
+			if err != nil {
+				return err
+			}
 
-	var files []string
-	var err error
+			if d.IsDir() {
+				if p != path && (d.Name() == ".git" || isExcluded(relPath, config.Exclude) || isGitIgnored(p)) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
 
-	if *staged {
-		// Get staged files from git when -staged flag is set
-		files, err = getStagedFiles()
+			if isExcluded(relPath, config.Exclude) {
+				return nil
+			}
+			if _, err := commentRemover(p, config); err == nil {
+				files = append(files, p)
+			}
+			return nil
+		})
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-			os.Exit(1)
-		}
-		fmt.Printf("Found %d staged file(s)\n", len(files))
-	} else {
-		// Use command-line arguments when -staged flag is not set
-		files = flag.Args()
-		if len(files) == 0 {
-			fmt.Fprintln(os.Stderr, "Error: No files provided. Use -staged flag or provide file paths as arguments")
-			flag.Usage()
-			os.Exit(1)
+			return nil, fmt.Errorf("failed to walk %s: %w", path, err)
 		}
 	}
 
-	// Convert all input paths to absolute paths upfront to ensure consistent
-	// cache key generation and avoid ambiguity between relative path interpretations
-	absoluteFiles := make([]string, 0, len(files))
-	for _, file := range files {
-		absPath, err := filepath.Abs(file)
+	return files, nil
+}
+
+// expandGlobs expands glob arguments that the shell left unexpanded, such as quoted
+// patterns or ** on shells without globstar. Patterns may use ** to match any number of
+// directories. Arguments that name an existing path or contain no glob characters are
+// passed through unchanged, and a pattern that matches nothing is an error.
+func expandGlobs(args []string) ([]string, error) {
+	files := make([]string, 0, len(args))
+
+	for _, arg := range args {
+		if !strings.ContainsAny(arg, "*?[") {
+			files = append(files, arg)
+			continue
+		}
+		if _, err := os.Stat(arg); err == nil {
+			files = append(files, arg)
+			continue
+		}
+
+		var matches []string
+		var err error
+		if strings.Contains(arg, "**") {
+			matches, err = globDoublestar(arg)
+		} else {
+			matches, err = filepath.Glob(arg)
+		}
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error: failed to resolve absolute path for %s: %v\n", file, err)
-			os.Exit(1)
+			return nil, fmt.Errorf("invalid pattern %q: %w", arg, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("pattern %q matched no files", arg)
 		}
-		absoluteFiles = append(absoluteFiles, absPath)
-	}
 
-	config := Config{
-		Files:        absoluteFiles,
-		BatchSize:    *batchSize,
-		Prompt:       *prompt,
-		ForceProcess: *forceProcess,
-		CacheOnly:    *cacheOnly,
+		files = append(files, matches...)
 	}
 
-	if err := run(config); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
-	}
+	return files, nil
 }
 
-func run(config Config) error {
-	cache, err := loadCache()
-	if err != nil {
-		return fmt.Errorf("failed to load cache: %w", err)
-	}
+// globDoublestar returns the files matching a pattern containing **. It walks from the
+// longest leading part of the pattern without glob characters, skipping .git directories.
+func globDoublestar(pattern string) ([]string, error) {
+	segments := strings.Split(filepath.ToSlash(filepath.Clean(pattern)), "/")
 
-	// Cache-only mode allows initializing the cache without expensive processing,
-	// useful for marking existing commented code as "already processed"
-	if config.CacheOnly {
-		fmt.Println("Cache-only mode: marking files as cached without processing")
-		cachedCount := 0
+	// Validate every segment up front so a bad pattern is reported even if nothing is walked
+	for _, segment := range segments {
+		if _, err := filepath.Match(segment, ""); err != nil {
+			return nil, err
+		}
+	}
 
-		for _, file := range config.Files {
-			// Skip gitignored files even in cache-only mode
-			if isGitIgnored(file) {
-				fmt.Printf("Skipping (gitignored): %s\n", file)
-				continue
-			}
+	static := 0
+	for static < len(segments) && !strings.ContainsAny(segments[static], "*?[") {
+		static++
+	}
+	root := "."
+	if static > 0 {
+		root = filepath.FromSlash(strings.Join(segments[:static], "/"))
+		// An absolute pattern's first segment is empty, leaving the root as "/"
+		if root == "" {
+			root = string(filepath.Separator)
+		}
+	}
 
-			if err := cache.markProcessed(file); err != nil {
-				fmt.Fprintf(os.Stderr, "Warning: failed to mark %s as cached: %v\n", file, err)
-				continue
+	var matches []string
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
 			}
-			fmt.Printf("Cached: %s\n", file)
-			cachedCount++
+			return nil
 		}
 
-		if cachedCount == 0 {
-			return fmt.Errorf("no files were successfully cached")
+		if matchSegments(segments, strings.Split(filepath.ToSlash(p), "/")) {
+			matches = append(matches, p)
 		}
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
 
-		if err := cache.save(); err != nil {
-			return fmt.Errorf("failed to save cache: %w", err)
-		}
+	return matches, nil
+}
 
-		fmt.Printf("\nMarked %d files as cached\n", cachedCount)
-		return nil
+// matchSegments matches path segments against pattern segments, where a ** segment
+// matches zero or more path segments and any other segment is a filepath.Match pattern.
+func matchSegments(pattern, path []string) bool {
+	if len(pattern) == 0 {
+		return len(path) == 0
 	}
 
-	// Filter files before expensive Claude processing to avoid unnecessary API calls
-	processedFiles := make([]string, 0, len(config.Files))
-	skippedFiles := 0
+	if pattern[0] == "**" {
+		for i := 0; i <= len(path); i++ {
+			if matchSegments(pattern[1:], path[i:]) {
+				return true
+			}
+		}
+		return false
+	}
 
-	for _, file := range config.Files {
-		// Skip gitignored files
-		if isGitIgnored(file) {
-			fmt.Printf("Skipping (gitignored): %s\n", file)
-			skippedFiles++
-			continue
+	if len(path) == 0 {
+		return false
+	}
+	if matched, _ := filepath.Match(pattern[0], path[0]); !matched {
+		return false
+	}
+	return matchSegments(pattern[1:], path[1:])
+}
+
+// isExcluded reports whether relPath, or its base name, matches any of the glob patterns.
+// Matching the base name lets "-exclude vendor" or "-exclude '*.pb.go'" apply at any depth.
+func isExcluded(relPath string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := filepath.Match(pattern, relPath); matched {
+			return true
 		}
+		if matched, _ := filepath.Match(pattern, filepath.Base(relPath)); matched {
+			return true
+		}
+	}
+	return false
+}
 
-		shouldProcess := config.ForceProcess
-		if !shouldProcess {
-			var err error
-			shouldProcess, err = cache.shouldProcess(file)
-			if err != nil {
-				// On cache check failure, err on the side of processing to ensure correctness
-				fmt.Fprintf(os.Stderr, "Warning: failed to check cache for %s: %v\n", file, err)
-				shouldProcess = true
-			}
+// parseExtensions parses a comma-separated list of file extensions such as ".go,.ts". It
+// reports false if any entry is not an extension, so that glob patterns can be told apart.
+func parseExtensions(value string) ([]string, bool) {
+	var exts []string
+	for ext := range strings.SplitSeq(value, ",") {
+		ext = strings.TrimSpace(ext)
+		if len(ext) < 2 || ext[0] != '.' || strings.ContainsAny(ext[1:], `./\*?[`) {
+			return nil, false
 		}
+		exts = append(exts, ext)
+	}
+	return exts, true
+}
 
-		if !shouldProcess {
-			fmt.Printf("Skipping (unchanged): %s\n", file)
-			skippedFiles++
+// filterExtensions returns the files whose extension is in config.Include, if set, and
+// not in config.ExcludeExtensions.
+func filterExtensions(files []string, config Config) []string {
+	if len(config.Include) == 0 && len(config.ExcludeExtensions) == 0 {
+		return files
+	}
+
+	filtered := make([]string, 0, len(files))
+	for _, file := range files {
+		ext := filepath.Ext(file)
+		if len(config.Include) > 0 && !slices.Contains(config.Include, ext) {
 			continue
 		}
-
-		// Comment removal happens before Claude processing to provide clean input,
-		// allowing Claude to focus on adding meaningful comments without existing noise
-		if err := processFile(file); err != nil {
-			// Check if this is an unsupported file type error
-			var unsupportedErr *ErrUnsupportedFileType
-			if errors.As(err, &unsupportedErr) {
-				fmt.Printf("Skipping (unsupported): %s\n", file)
-				skippedFiles++
-				continue
-			}
-			// Other errors are warnings
-			fmt.Fprintf(os.Stderr, "Warning: failed to process %s: %v\n", file, err)
+		if slices.Contains(config.ExcludeExtensions, ext) {
 			continue
 		}
+		filtered = append(filtered, file)
+	}
+	return filtered
+}
 
-		processedFiles = append(processedFiles, file)
-		fmt.Printf("Removed comments from: %s\n", file)
+// sortByRelativePath sorts files by their paths relative to the root from findRoot,
+// which, unlike absolute paths, don't depend on where the repository is checked out.
+func sortByRelativePath(files []string) {
+	keys := make(map[string]string, len(files))
+	for _, file := range files {
+		relPath, err := toRelativePath(file)
+		if err != nil {
+			relPath = file
+		}
+		keys[file] = filepath.ToSlash(relPath)
 	}
+	slices.SortStableFunc(files, func(a, b string) int {
+		return strings.Compare(keys[a], keys[b])
+	})
+}
 
-	if len(processedFiles) == 0 {
-		if skippedFiles > 0 {
-			fmt.Printf("\nAll %d files are up to date (no changes needed)\n", skippedFiles)
-			return nil
+// modifiedSince returns the files last modified at or after cutoff. Files that can't be
+// stat'ed are kept, so that processing reports why they can't be read.
+func modifiedSince(files []string, cutoff time.Time) []string {
+	var recent []string
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil || !info.ModTime().Before(cutoff) {
+			recent = append(recent, file)
 		}
-		return fmt.Errorf("no files were successfully processed")
 	}
+	return recent
+}
+
+// fileWindow returns up to limit of files, after skipping the first offset of them in
+// the order of sortByRelativePath. Sorting makes the window independent of the order files
+// were listed in, so consecutive offsets cover every file exactly once. A zero limit means
+// no limit.
+func fileWindow(files []string, offset, limit int) []string {
+	sorted := slices.Clone(files)
+	sortByRelativePath(sorted)
+	start := min(offset, len(sorted))
+	end := len(sorted)
+	if limit > 0 {
+		end = min(start+limit, end)
+	}
+	return sorted[start:end]
+}
 
-	fmt.Printf("\nProcessing %d files in batches of %d...\n\n", len(processedFiles), config.BatchSize)
+// Stats summarizes a run: how many files were processed, skipped, and failed, and
+// the tokens the backend used for them.
+type Stats struct {
+	Processed int
+	Skipped   int
+	Failed    int
+	// Usage is the token usage per successfully processed file
+	Usage map[string]Usage
+	Total Usage
+	// Errors is the error of each failed file
+	Errors map[string]error
+}
 
-	if err := processBatches(processedFiles, config.BatchSize, config.Prompt, cache); err != nil {
-		return err
+// record adds the usage of a successfully processed file.
+func (s *Stats) record(file string, usage Usage) {
+	if s.Usage == nil {
+		s.Usage = make(map[string]Usage)
 	}
+	s.Usage[file] = usage
+	s.Processed++
+	s.Total.add(usage)
+}
 
-	return nil
+// fail records a file that failed to process.
+func (s *Stats) fail(file string, err error) {
+	if s.Errors == nil {
+		s.Errors = make(map[string]error)
+	}
+	s.Errors[file] = err
+	s.Failed++
 }
 
-func processFile(inputPath string) error {
-	content, err := os.ReadFile(inputPath)
-	if err != nil {
-		return fmt.Errorf("failed to read file: %w", err)
+// merge adds the counts, usage, and errors of other to s.
+func (s *Stats) merge(other Stats) {
+	for file, usage := range other.Usage {
+		s.record(file, usage)
+	}
+	for file, err := range other.Errors {
+		s.fail(file, err)
 	}
+	s.Skipped += other.Skipped
+}
 
-	ext := filepath.Ext(inputPath)
-	var cleaned string
-
-	switch ext {
-	case ".js", ".ts", ".jsx", ".tsx":
-		cleaned = removeJSComments(string(content))
-	case ".go":
-		cleaned = removeGoComments(string(content))
-	case ".py":
-		cleaned = removePythonComments(string(content))
-	case ".rs":
-		cleaned = removeRustComments(string(content))
-	case ".tf", ".tfvars":
-		cleaned = removeTerraformComments(string(content))
-	case ".yaml", ".yml":
-		cleaned = removeYAMLComments(string(content))
-	default:
-		// Return special error type to indicate unsupported file should be skipped
-		return &ErrUnsupportedFileType{Extension: ext}
+// print writes the end-of-run summary to w.
+func (s Stats) print(w io.Writer) {
+	fmt.Fprintf(w, "\nSummary: %d processed, %d skipped, %d failed\n", s.Processed, s.Skipped, s.Failed)
+	if s.Processed == 0 {
+		return
 	}
 
-	if err := os.WriteFile(inputPath, []byte(cleaned), 0o644); err != nil {
-		return fmt.Errorf("failed to write file: %w", err)
+	estimated := ""
+	if s.Total.Estimated {
+		estimated = " (estimated)"
+	}
+	fmt.Fprintf(w, "Tokens: %d input, %d output%s\n", s.Total.InputTokens, s.Total.OutputTokens, estimated)
+	if s.Total.CostUSD > 0 {
+		fmt.Fprintf(w, "Cost: $%.4f\n", s.Total.CostUSD)
 	}
+}
 
-	return nil
+// Reasons a file is skipped, as reported in SkippedFile.
+const (
+	skipGitIgnored  = "gitignored"
+	skipUnchanged   = "unchanged"
+	skipUnsupported = "unsupported"
+	skipBinary      = "binary"
+	// skipUncommented is for files without comments to regenerate, with -skip-unchanged
+	skipUncommented = "uncommented"
+)
+
+// RunResult describes what a run did with each file. Skipped files are in input order,
+// and the files that reached the backend in the sorted order they were processed in.
+type RunResult struct {
+	// Processed lists the files that were commented, or in cache-only and dry-run mode,
+	// the files that were cached or would have been commented
+	Processed []string
+	Skipped   []SkippedFile
+	Failed    []FailedFile
+	// Usage is the token usage per processed file, and Total their sum
+	Usage map[string]Usage
+	Total Usage
 }
 
-func processBatches(files []string, batchSize int, prompt string, cache *FileCache) error {
-	for i := 0; i < len(files); i += batchSize {
-		end := min(i+batchSize, len(files))
-		batch := files[i:end]
+// SkippedFile is a file that a run left alone, and why.
+type SkippedFile struct {
+	File string
+	// Reason is one of the skip reasons: "gitignored", "unchanged", "unsupported",
+	// "binary", or "uncommented"
+	Reason string
+}
 
-		fmt.Printf("Processing batch %d/%d (%d files)...\n", (i/batchSize)+1, (len(files)+batchSize-1)/batchSize, len(batch))
+// FailedFile is a file that a run failed to process.
+type FailedFile struct {
+	File string
+	Err  error
+}
 
-		if err := processBatch(batch, prompt); err != nil {
-			return fmt.Errorf("batch processing failed: %w", err)
-		}
+func (r *RunResult) skip(file, reason string) {
+	r.Skipped = append(r.Skipped, SkippedFile{File: file, Reason: reason})
+}
 
-		// Cache updates happen after each successful batch to prevent data loss
-		// if processing is interrupted partway through
-		for _, file := range batch {
-			if err := cache.markProcessed(file); err != nil {
-				fmt.Fprintf(os.Stderr, "Warning: failed to update cache for %s: %v\n", file, err)
-			}
-		}
+func (r *RunResult) fail(file string, err error) {
+	r.Failed = append(r.Failed, FailedFile{File: file, Err: err})
+}
 
+// Stats returns the end-of-run counts and token usage.
+func (r RunResult) Stats() Stats {
+	return Stats{
+		Processed: len(r.Processed),
+		Skipped:   len(r.Skipped),
+		Failed:    len(r.Failed),
+		Usage:     r.Usage,
+		Total:     r.Total,
+	}
+}
+
+// processBatches comments files, saving the cache each time another config.BatchSize
+// of them finish so that an interrupted run keeps its progress without a cache write per
+// file. Batches only set how often the cache is saved: how many files are commented at
+// once is up to commentFiles.
+func processBatches(files []string, config Config, cache *FileCache, newCommenter commenterFactory, reporter Reporter) (Stats, error) {
+	batchSize := max(config.BatchSize, 1)
+	finished := 0
+	save := func() {
 		// Cache save failures are warnings rather than errors because processing succeeded;
 		// worst case is redundant work on next run
 		if err := cache.save(); err != nil {
-			fmt.Fprintf(os.Stderr, "Warning: failed to save cache: %v\n", err)
+			reporter.Warnf("failed to save cache: %v", err)
 		}
 	}
 
-	return nil
+	// commentFiles reports one file at a time, so the cache needs no lock of its own
+	stats, err := commentFiles(files, config, newCommenter, reporter, func(file string, err error) {
+		if err == nil {
+			if err := cache.markProcessed(file); err != nil {
+				reporter.Warnf("failed to update cache for %s: %v", file, err)
+			}
+		}
+		finished++
+		if finished%batchSize == 0 {
+			save()
+			reporter.Progressf("Finished %d/%d files", finished, len(files))
+		}
+	})
+	// Files finished since the last full batch, or before -fail-fast stopped the run
+	if finished%batchSize != 0 {
+		save()
+	}
+	if err != nil {
+		return stats, fmt.Errorf("processing failed: %w", err)
+	}
+	return stats, nil
+}
+
+// sleep pauses between batches; tests replace it to observe the delays.
+var sleep = time.Sleep
+
+// concurrencyLimit returns how many of n files commentFiles comments at once:
+// config.Concurrency, or by default the batch size, or failing that all of them.
+func concurrencyLimit(config Config, n int) int {
+	switch {
+	case config.Concurrency > 0:
+		return config.Concurrency
+	case config.BatchSize > 0:
+		return config.BatchSize
+	}
+	return max(n, 1)
 }
 
-// processBatch runs Claude in parallel for all files in a batch but waits for completion
-// before returning. This controlled parallelism respects rate limits while maximizing
-// throughput, unlike unbounded parallelism which could overwhelm the Claude API.
-func processBatch(files []string, prompt string) error {
+// commentFiles comments files in parallel, at most concurrencyLimit at a time, and waits
+// for completion before returning. Files start in order as earlier ones finish, so a slow
+// file holds up a single slot rather than a whole batch, while the limit keeps the
+// backend's rate limits in check. With config.BatchDelay, starting each further batch of
+// config.BatchSize files waits that long. done, if not nil, is called as each file
+// finishes, one call at a time.
+func commentFiles(files []string, config Config, newCommenter commenterFactory, reporter Reporter, done func(file string, err error)) (Stats, error) {
 	var wg sync.WaitGroup
+	var outputMu, statsMu sync.Mutex
+	var stats Stats
 	errChan := make(chan error, len(files))
 
-	for _, file := range files {
+	// With -fail-fast, the first failure cancels the files still running
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Each running file holds a slot, so waiting files don't start a subprocess
+	slots := make(chan struct{}, concurrencyLimit(config, len(files)))
+
+	for i, file := range files {
+		if config.BatchDelay > 0 && config.BatchSize > 0 && i > 0 && i%config.BatchSize == 0 {
+			reporter.Progressf("Waiting %s before starting the next batch...", config.BatchDelay)
+			sleep(config.BatchDelay)
+		}
+		slots <- struct{}{}
+		// Files that haven't started when the run is canceled never do
+		if ctx.Err() != nil {
+			<-slots
+			break
+		}
+
 		wg.Add(1)
 		// File parameter is passed to goroutine to avoid closure capture issues
 		// where all goroutines would reference the final loop value
 		go func(f string) {
 			defer wg.Done()
-			if err := runClaude(f, prompt); err != nil {
+			defer func() { <-slots }()
+
+			var usage Usage
+			var err error
+			start := time.Now()
+			if config.Stream {
+				usage, err = commentFile(ctx, f, config, newCommenter(reporter.Log(), reporter.ErrLog()), reporter.Log(), reporter.ErrLog())
+			} else {
+				// Stdout and stderr are buffered apart so each is flushed to its own stream,
+				// and the backend may write them from separate goroutines
+				var stdoutBuf, stderrBuf bytes.Buffer
+				stdout := &lockedWriter{mu: new(sync.Mutex), w: &stdoutBuf}
+				stderr := &lockedWriter{mu: new(sync.Mutex), w: &stderrBuf}
+				usage, err = commentFile(ctx, f, config, newCommenter(stdout, stderr), stdout, stderr)
+				outputMu.Lock()
+				reporter.Log().Write(stdoutBuf.Bytes())
+				reporter.ErrLog().Write(stderrBuf.Bytes())
+				outputMu.Unlock()
+			}
+
+			statsMu.Lock()
+			defer statsMu.Unlock()
+			if done != nil {
+				defer done(f, err)
+			}
+			if err != nil {
+				reporter.Failed(f, err)
+				stats.fail(f, err)
 				errChan <- fmt.Errorf("%s: %w", f, err)
+				if config.FailFast {
+					cancel()
+				}
+				return
 			}
+			reporter.Commented(f, usage, time.Since(start))
+			stats.record(f, usage)
 		}(file)
 	}
 
 	wg.Wait()
 	close(errChan)
 
-	// Collect all errors rather than failing fast to provide complete feedback
-	// on which files failed in the batch
+	// Without -fail-fast, every file ran, so all of their errors are collected to
+	// provide complete feedback on which files failed
 	var errors []string
 	for err := range errChan {
 		errors = append(errors, err.Error())
 	}
 
 	if len(errors) > 0 {
-		return fmt.Errorf("errors occurred:\n  %s", strings.Join(errors, "\n  "))
+		return stats, fmt.Errorf("errors occurred:\n  %s", strings.Join(errors, "\n  "))
 	}
 
-	return nil
+	return stats, nil
 }
 
-// runClaude formats before processing to ensure consistent code style,
-// preventing Claude from being distracted by formatting issues
-func runClaude(file, prompt string) error {
-	fmt.Printf("  [%s] Running Claude...\n", filepath.Base(file))
+// commentFile formats before processing to ensure consistent code style,
+// preventing the model from being distracted by formatting issues. Progress is written
+// to stdout and problems to stderr; the individual steps only at the verbose level.
+// Canceling ctx stops the backend and any retries.
+func commentFile(ctx context.Context, file string, config Config, commenter Commenter, stdout, stderr io.Writer) (Usage, error) {
+	progress := logger{stdout, config.LogLevel}
+	progress.printf(levelVerbose, "  [%s] Adding comments...", filepath.Base(file))
 
-	if err := formatFile(file); err != nil {
+	if err := formatFile(file, config); err != nil {
 		// Formatter failures are warnings because formatting is a quality-of-life feature,
 		// not critical to comment generation
-		fmt.Fprintf(os.Stderr, "  [%s] Warning: formatter failed: %v\n", filepath.Base(file), err)
+		fmt.Fprintf(stderr, "  [%s] Warning: formatter failed: %v\n", filepath.Base(file), err)
 	} else {
-		fmt.Printf("  [%s] Formatted\n", filepath.Base(file))
+		progress.printf(levelVerbose, "  [%s] Formatted", filepath.Base(file))
+	}
+
+	if config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, config.Timeout)
+		defer cancel()
 	}
 
-	// bypassPermissions mode is required because Claude needs write access to modify files,
-	// and interactive permission prompts would block batch processing
-	cmd := exec.Command("claude", "--dangerously-skip-permissions", "--model", "haiku", "--permission-mode", "bypassPermissions", "-p", strings.Replace(prompt, "{filename}", file, 1))
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	// Failed attempts still used tokens, so their usage counts towards the file's
+	var usage Usage
+	for attempt := 0; ; attempt++ {
+		progress.printf(levelVerbose, "  [%s] Requesting comments (attempt %d/%d)", filepath.Base(file), attempt+1, config.Retries+1)
+		attemptUsage, err := commenter.Comment(ctx, file, renderPrompt(file, config))
+		usage.add(attemptUsage)
+		if err == nil {
+			break
+		}
+		if ctx.Err() != nil {
+			return usage, interruptedError(ctx, config.Timeout)
+		}
+		var transient *transientError
+		if attempt >= config.Retries || !errors.As(err, &transient) {
+			return usage, err
+		}
 
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("claude command failed: %w", err)
+		delay := retryDelay(config.RetryBackoff, attempt)
+		fmt.Fprintf(stderr, "  [%s] Transient failure, retrying in %s (%d/%d): %v\n", filepath.Base(file), delay.Round(time.Millisecond), attempt+1, config.Retries, err)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return usage, interruptedError(ctx, config.Timeout)
+		}
 	}
 
-	if err := formatFile(file); err != nil {
+	if err := formatFile(file, config); err != nil {
 		// Formatter failures are warnings because formatting is a quality-of-life feature,
 		// not critical to comment generation
-		fmt.Fprintf(os.Stderr, "  [%s] Warning: formatter failed: %v\n", filepath.Base(file), err)
+		fmt.Fprintf(stderr, "  [%s] Warning: formatter failed: %v\n", filepath.Base(file), err)
 	} else {
-		fmt.Printf("  [%s] Formatted\n", filepath.Base(file))
+		progress.printf(levelVerbose, "  [%s] Formatted", filepath.Base(file))
+	}
+
+	if config.Validate {
+		if err := validateFile(file, config); errors.Is(err, errNoValidator) {
+			fmt.Fprintf(stderr, "  [%s] Warning: not validated: %v\n", filepath.Base(file), err)
+		} else if err != nil {
+			return usage, rollBack(file, config, fmt.Errorf("validation failed: %w", err))
+		} else {
+			progress.printf(levelVerbose, "  [%s] Validated", filepath.Base(file))
+		}
+	}
+
+	progress.printf(levelNormal, "  [%s] Completed (%d input, %d output tokens)", filepath.Base(file), usage.InputTokens, usage.OutputTokens)
+	return usage, nil
+}
+
+// rollBack restores file from its backup when there is one, after Claude left it in a
+// state that failed with err, and returns err amended with the outcome.
+func rollBack(file string, config Config, err error) error {
+	if !config.Backup {
+		return err
+	}
+	if restoreErr := restoreBackup(file); restoreErr != nil {
+		return fmt.Errorf("%w; restoring the backup also failed: %v", err, restoreErr)
+	}
+	return fmt.Errorf("%w; restored the original from its backup", err)
+}
+
+// interruptedError describes why the context of a file being commented ended: its
+// timeout, or with -fail-fast, another file failing.
+func interruptedError(ctx context.Context, timeout time.Duration) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("timed out after %s", timeout)
+	}
+	return errors.New("canceled after another file failed")
+}
+
+// retryDelay returns the wait before retry number attempt+1: base doubled for each
+// earlier attempt, plus up to base of random jitter so that files running at once that
+// hit a rate limit together don't all retry at the same moment.
+func retryDelay(base time.Duration, attempt int) time.Duration {
+	if base <= 0 {
+		return 0
+	}
+	return base<<attempt + rand.N(base)
+}
+
+// loadPrompt resolves the prompt from the -prompt and -prompt-file flags. A -prompt of
+// "-" is read from stdin, and an explicitly set -prompt wins over -prompt-file, which in
+// turn replaces the default prompt.
+func loadPrompt(prompt string, promptSet bool, promptFile string, stdin io.Reader) (string, error) {
+	if prompt == "-" {
+		data, err := io.ReadAll(stdin)
+		if err != nil {
+			return "", fmt.Errorf("failed to read prompt from stdin: %w", err)
+		}
+		if strings.TrimSpace(string(data)) == "" {
+			return "", fmt.Errorf("prompt read from stdin is empty")
+		}
+		return string(data), nil
+	}
+
+	if promptSet || promptFile == "" {
+		return prompt, nil
+	}
+
+	data, err := os.ReadFile(promptFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read prompt file: %w", err)
+	}
+	if strings.TrimSpace(string(data)) == "" {
+		return "", fmt.Errorf("prompt file %s is empty", promptFile)
+	}
+	return string(data), nil
+}
+
+// renderPrompt fills the placeholders of the prompt for file: {filename} (the path),
+// {basename}, {ext} (without the dot), {reldir} (the directory relative to the root),
+// and {language}.
+func renderPrompt(file string, config Config) string {
+	relDir := "."
+	if relPath, err := toRelativePath(file); err == nil {
+		relDir = filepath.Dir(relPath)
+	}
+
+	// A single pass keeps a substituted value that happens to contain a placeholder intact
+	replacer := strings.NewReplacer(
+		"{filename}", file,
+		"{basename}", filepath.Base(file),
+		"{ext}", strings.TrimPrefix(filepath.Ext(file), "."),
+		"{reldir}", relDir,
+		"{language}", languageName(file, config),
+	)
+	return replacer.Replace(config.Prompt)
+}
+
+// defaultFormatters are the formatter commands run on each language, with the file
+// appended as the last argument.
+var defaultFormatters = map[string][]string{
+	stripper.Go:         {"gofmt", "-w"},
+	stripper.JavaScript: {"biome", "format", "--write"},
+	stripper.TypeScript: {"biome", "format", "--write"},
+	stripper.Python:     {"ruff", "format"},
+	stripper.Rust:       {"rustfmt"},
+	stripper.Terraform:  {"terraform", "fmt"},
+	stripper.YAML:       {"yamlfmt"},
+	stripper.Java:       {"google-java-format", "--replace"},
+	stripper.Shell:      {"shfmt", "-w"},
+	stripper.SQL:        {"sqlfluff", "format"},
+	stripper.TOML:       {"taplo", "fmt"},
+	stripper.Haskell:    {"ormolu", "--mode", "inplace"},
+	stripper.Kotlin:     {"ktlint", "-F"},
+	stripper.Scala:      {"scalafmt"},
+	stripper.Elixir:     {"mix", "format"},
+	// -bext=/ tells perltidy to modify the file in place without keeping a backup
+	stripper.Perl:     {"perltidy", "-b", "-bext=/"},
+	stripper.Solidity: {"forge", "fmt"},
+	stripper.Julia:    {"julia", "-e", "using JuliaFormatter; format_file(ARGS[1])"},
+	stripper.JSONC:    {"prettier", "--write"},
+	stripper.GraphQL:  {"prettier", "--write"},
+	stripper.Markdown: {"prettier", "--write"},
+	stripper.Protobuf: {"buf", "format", "-w"},
+	stripper.OCaml:    {"ocamlformat", "--inplace"},
+	stripper.Nix:      {"nixpkgs-fmt"},
+}
+
+// fallbackFormatters are run instead of a language's default formatter when its command
+// isn't on PATH.
+var fallbackFormatters = map[string][]string{
+	// gofmt ships with Go, but installs that only put the go command on PATH can
+	// still format through go fmt
+	stripper.Go: {"go", "fmt"},
+	// Projects that don't use buf often have clang-format, which also formats Protobuf
+	stripper.Protobuf: {"clang-format", "-i"},
+	// alejandra formats in place like nixpkgs-fmt, just in its own style
+	stripper.Nix: {"alejandra"},
+}
+
+// formatCommand returns the formatter command for file, or nil if it isn't formatted.
+// config.Formatters overrides defaultFormatters per language.
+func formatCommand(file string, config Config) *exec.Cmd {
+	if config.NoFormat {
+		return nil
 	}
+	lang, ok := detectLanguage(file, config)
+	if !ok {
+		return nil
+	}
+
+	command := formatterCommand(lang, config)
+	if len(command) == 0 {
+		return nil
+	}
+	return exec.Command(command[0], append(slices.Clone(command[1:]), file)...)
+}
+
+// formatterCommand returns the formatter command run on files in lang, without the file
+// argument, or nil if they aren't formatted.
+func formatterCommand(lang string, config Config) []string {
+	if config.NoFormat {
+		return nil
+	}
+	command, ok := config.Formatters[lang]
+	if !ok {
+		command = defaultFormatters[lang]
+		if fallback, ok := fallbackFormatters[lang]; ok {
+			if _, err := lookPath(command[0]); err != nil {
+				command = fallback
+			}
+		}
+	}
+	return command
+}
+
+// listLanguages writes every file extension that nocomms processes, sorted, with its
+// language and the formatter run on it, for -list-languages.
+func listLanguages(w io.Writer, config Config) error {
+	type entry struct{ ext, lang string }
+	var entries []entry
+	for _, lang := range stripper.Languages {
+		for _, ext := range stripper.Extensions(lang) {
+			entries = append(entries, entry{ext, lang})
+		}
+	}
+	if config.JSONC {
+		entries = append(entries, entry{".json", stripper.JSONC})
+	}
+	// Dockerfiles are detected by name rather than extension
+	entries = append(entries, entry{"Dockerfile", stripper.Dockerfile})
+	slices.SortFunc(entries, func(a, b entry) int { return strings.Compare(a.ext, b.ext) })
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "EXTENSION\tLANGUAGE\tFORMATTER")
+	for _, e := range entries {
+		formatter := "none"
+		if command := formatterCommand(e.lang, config); len(command) > 0 {
+			// Quote arguments with spaces so the command can be copied into a shell
+			args := slices.Clone(command)
+			for i, arg := range args {
+				if strings.ContainsAny(arg, " \t") {
+					args[i] = strconv.Quote(arg)
+				}
+			}
+			formatter = strings.Join(args, " ")
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", e.ext, stripper.Name(e.lang), formatter)
+	}
+	return tw.Flush()
+}
+
+// lookPath finds the Claude CLI, formatter, and validator executables; tests replace it
+// to simulate missing tools.
+var lookPath = exec.LookPath
+
+// defaultValidators are the syntax checks -validate runs on each language, with the file
+// appended as the last argument. They exit with an error for files that don't parse.
+var defaultValidators = map[string][]string{
+	stripper.Go: {"gofmt", "-e", "-l"},
+	// E9 selects syntax errors, so lint findings don't count as broken files
+	stripper.Python:     {"ruff", "check", "--quiet", "--select", "E9"},
+	stripper.JavaScript: {"node", "--check"},
+}
+
+// errNoValidator is returned by validateFile when the syntax check for a file's language
+// isn't installed.
+var errNoValidator = errors.New("validator not found")
 
-	fmt.Printf("  [%s] Completed\n", filepath.Base(file))
+// validateFile runs the syntax check for file's language, returning an error with its
+// output if the file doesn't parse. Languages without a check always pass.
+func validateFile(file string, config Config) error {
+	lang, ok := detectLanguage(file, config)
+	if !ok {
+		return nil
+	}
+	command, ok := defaultValidators[lang]
+	if !ok {
+		return nil
+	}
+	if _, err := lookPath(command[0]); err != nil {
+		return fmt.Errorf("%w: %v", errNoValidator, err)
+	}
+
+	cmd := exec.Command(command[0], append(slices.Clone(command[1:]), file)...)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%s: %w (output: %s)", command[0], err, strings.TrimSpace(string(output)))
+	}
 	return nil
 }
 
-func formatFile(file string) error {
-	ext := filepath.Ext(file)
-	var cmd *exec.Cmd
-
-	switch ext {
-	case ".go":
-		cmd = exec.Command("go", "fmt", file)
-	case ".js", ".ts", ".jsx", ".tsx":
-		cmd = exec.Command("biome", "format", "--write", file)
-	case ".py":
-		cmd = exec.Command("ruff", "format", file)
-	case ".rs":
-		cmd = exec.Command("rustfmt", file)
-	case ".tf", ".tfvars":
-		cmd = exec.Command("terraform", "fmt", file)
-	case ".yaml", ".yml":
-		cmd = exec.Command("yamlfmt", file)
-	default:
+func formatFile(file string, config Config) error {
+	cmd := formatCommand(file, config)
+	if cmd == nil {
 		// No formatter configured for this file type; skip silently
 		return nil
 	}
@@ -1,11 +1,25 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
+
+	"nocomms/stripper"
 )
 
 func TestFindGitRoot(t *testing.T) {
@@ -255,3 +269,2711 @@ func TestCacheJSONFormat(t *testing.T) {
 		}
 	}
 }
+
+func TestProcessFileJSONCOptIn(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tsconfig.json")
+	input := "{\n  \"strict\": true // comment\n}"
+	if err := os.WriteFile(path, []byte(input), 0o644); err != nil {
+		t.Fatalf("os.WriteFile() error = %v", err)
+	}
+
+	// Without -jsonc, plain .json files are skipped as unsupported
+	err := processFile(path, Config{})
+	var unsupportedErr *stripper.ErrUnsupportedFileType
+	if !errors.As(err, &unsupportedErr) {
+		t.Fatalf("processFile() error = %v, want stripper.ErrUnsupportedFileType", err)
+	}
+
+	if err := processFile(path, Config{JSONC: true}); err != nil {
+		t.Fatalf("processFile() with JSONC error = %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("os.ReadFile() error = %v", err)
+	}
+	if want := "{\n  \"strict\": true\n}"; string(data) != want {
+		t.Errorf("processFile() wrote %q, want %q", string(data), want)
+	}
+}
+
+func TestProcessFileRegisteredRemover(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "init.lua")
+	input := "local x = 1 -- one\n"
+	if err := os.WriteFile(path, []byte(input), 0o644); err != nil {
+		t.Fatalf("os.WriteFile() error = %v", err)
+	}
+
+	// Lua has no built-in remover, so it is unsupported until one is registered
+	err := processFile(path, Config{})
+	var unsupportedErr *stripper.ErrUnsupportedFileType
+	if !errors.As(err, &unsupportedErr) {
+		t.Fatalf("processFile() error = %v, want stripper.ErrUnsupportedFileType", err)
+	}
+
+	stripper.RegisterRemover(".lua", func(content string) string {
+		return strings.ReplaceAll(content, " -- one", "")
+	})
+
+	if err := processFile(path, Config{}); err != nil {
+		t.Fatalf("processFile() with registered remover error = %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("os.ReadFile() error = %v", err)
+	}
+	if want := "local x = 1\n"; string(data) != want {
+		t.Errorf("processFile() wrote %q, want %q", string(data), want)
+	}
+}
+
+func TestProcessFileStreamsLargeFiles(t *testing.T) {
+	dir := t.TempDir()
+	input := "package main // main\n\n\n\n// helper\nfunc helper() {} /* inline */\n"
+	config := Config{KeepDirectives: parseDirectives("go:"), CollapseNewlines: true}
+
+	// The same file is processed in memory and, past the lowered threshold, streamed
+	var outputs []string
+	for _, threshold := range []int64{1 << 20, 0} {
+		path := filepath.Join(dir, fmt.Sprintf("main%d.go", threshold))
+		if err := os.WriteFile(path, []byte(input), 0o640); err != nil {
+			t.Fatalf("os.WriteFile() error = %v", err)
+		}
+
+		original := streamThreshold
+		streamThreshold = threshold
+		err := processFile(path, config)
+		streamThreshold = original
+		if err != nil {
+			t.Fatalf("processFile() error = %v", err)
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("os.ReadFile() error = %v", err)
+		}
+		outputs = append(outputs, string(data))
+
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatalf("os.Stat() error = %v", err)
+		}
+		if perm := info.Mode().Perm(); perm != 0o640 {
+			t.Errorf("permissions = %v, want %v", perm, fs.FileMode(0o640))
+		}
+	}
+
+	if outputs[0] != outputs[1] {
+		t.Errorf("streamed output = %q, want %q", outputs[1], outputs[0])
+	}
+	if want := "package main\n\nfunc helper() {}\n"; outputs[0] != want {
+		t.Errorf("processFile() wrote %q, want %q", outputs[0], want)
+	}
+
+	// The temporary file is renamed over the original, so none are left behind
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("os.ReadDir() error = %v", err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("directory holds %d entries, want 2", len(entries))
+	}
+}
+
+func TestProcessFileFinalNewline(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     string
+		input    string
+		expected string
+	}{
+		{"go with final newline", "main.go", "package main\n\nfunc f() {} // f\n// end\n", "package main\n\nfunc f() {}\n\n"},
+		{"go without final newline", "main.go", "package main\n\nfunc f() {} // f\n// end", "package main\n\nfunc f() {}"},
+		{"go code on last line", "main.go", "package main // main", "package main"},
+		{"python with final newline", "main.py", "x = 1  # one\n# end\n", "x = 1\n\n"},
+		{"python without final newline", "main.py", "x = 1  # one\n\n# end", "x = 1"},
+		{"python only comments", "main.py", "# a\n# b", ""},
+		{"crlf with final newline", "main.go", "package main // main\r\n// end\r\n", "package main\r\n\r\n"},
+		{"crlf without final newline", "main.go", "package main\r\n\r\n// end", "package main"},
+	}
+
+	for _, tt := range tests {
+		// Large files are streamed, so both ways of writing the file must agree
+		for _, threshold := range []int64{1 << 20, 0} {
+			t.Run(fmt.Sprintf("%s/threshold %d", tt.name, threshold), func(t *testing.T) {
+				path := filepath.Join(t.TempDir(), tt.file)
+				if err := os.WriteFile(path, []byte(tt.input), 0o644); err != nil {
+					t.Fatalf("os.WriteFile() error = %v", err)
+				}
+
+				original := streamThreshold
+				streamThreshold = threshold
+				err := processFile(path, Config{})
+				streamThreshold = original
+				if err != nil {
+					t.Fatalf("processFile() error = %v", err)
+				}
+
+				data, err := os.ReadFile(path)
+				if err != nil {
+					t.Fatalf("os.ReadFile() error = %v", err)
+				}
+				if string(data) != tt.expected {
+					t.Errorf("processFile() wrote %q, want %q", data, tt.expected)
+				}
+			})
+		}
+	}
+}
+
+func TestProcessFileBinary(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content []byte
+	}{
+		{"png with source extension", "logo.go", []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR // not code")},
+		{"nul byte", "data.py", []byte("x = 1  # one\n\x00\n")},
+		{"invalid utf-8", "latin1.py", []byte("s = 'caf\xe9'  # latin-1\n")},
+	}
+
+	for _, tt := range tests {
+		// Streamed files are only checked at their start, which is enough for these
+		for _, threshold := range []int64{1 << 20, 0} {
+			t.Run(fmt.Sprintf("%s/threshold %d", tt.name, threshold), func(t *testing.T) {
+				path := filepath.Join(t.TempDir(), tt.file)
+				if err := os.WriteFile(path, tt.content, 0o644); err != nil {
+					t.Fatalf("os.WriteFile() error = %v", err)
+				}
+
+				original := streamThreshold
+				streamThreshold = threshold
+				err := processFile(path, Config{})
+				streamThreshold = original
+
+				var binaryErr *stripper.ErrBinaryFile
+				if !errors.As(err, &binaryErr) {
+					t.Fatalf("processFile() error = %v, want stripper.ErrBinaryFile", err)
+				}
+				data, err := os.ReadFile(path)
+				if err != nil {
+					t.Fatalf("os.ReadFile() error = %v", err)
+				}
+				if !bytes.Equal(data, tt.content) {
+					t.Errorf("processFile() modified the file: %q", data)
+				}
+			})
+		}
+	}
+}
+
+func TestProcessFileCollapseNewlines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "main.go")
+	input := "// Package main does things.\n// More header text.\n\npackage main\n\n// helper\n\n\nfunc helper() {}\n"
+	if err := os.WriteFile(path, []byte(input), 0o644); err != nil {
+		t.Fatalf("os.WriteFile() error = %v", err)
+	}
+
+	if err := processFile(path, Config{CollapseNewlines: true}); err != nil {
+		t.Fatalf("processFile() error = %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("os.ReadFile() error = %v", err)
+	}
+	if want := "package main\n\nfunc helper() {}\n"; string(data) != want {
+		t.Errorf("processFile() wrote %q, want %q", string(data), want)
+	}
+}
+
+func TestFileCacheHashMode(t *testing.T) {
+	gitRoot, err := findGitRoot()
+	if err != nil {
+		t.Skipf("not in a git repository, skipping test: %v", err)
+	}
+
+	f, err := os.CreateTemp(gitRoot, ".nocomms-hash-test-*.go")
+	if err != nil {
+		t.Fatalf("os.CreateTemp() error = %v", err)
+	}
+	testFile := f.Name()
+	f.Close()
+	defer os.Remove(testFile)
+
+	original := []byte("package main\n")
+	if err := os.WriteFile(testFile, original, 0o644); err != nil {
+		t.Fatalf("os.WriteFile() error = %v", err)
+	}
+
+	cache := &FileCache{
+		ProcessedFiles: make(map[string]time.Time),
+		Hashes:         make(map[string]string),
+		Mode:           cacheModeHash,
+	}
+	if err := cache.markProcessed(testFile); err != nil {
+		t.Fatalf("markProcessed() error = %v", err)
+	}
+
+	assertShouldProcess := func(want bool) {
+		t.Helper()
+		got, err := cache.shouldProcess(testFile)
+		if err != nil {
+			t.Fatalf("shouldProcess() error = %v", err)
+		}
+		if got != want {
+			t.Errorf("shouldProcess() = %v, want %v", got, want)
+		}
+	}
+
+	// Touch without edit: a newer mtime alone does not trigger reprocessing
+	future := time.Now().Add(time.Hour)
+	if err := os.Chtimes(testFile, future, future); err != nil {
+		t.Fatalf("os.Chtimes() error = %v", err)
+	}
+	assertShouldProcess(false)
+
+	// Edit with an older mtime: changed content is still detected
+	if err := os.WriteFile(testFile, []byte("package other\n"), 0o644); err != nil {
+		t.Fatalf("os.WriteFile() error = %v", err)
+	}
+	past := time.Now().Add(-24 * time.Hour)
+	if err := os.Chtimes(testFile, past, past); err != nil {
+		t.Fatalf("os.Chtimes() error = %v", err)
+	}
+	assertShouldProcess(true)
+
+	// Content revert: restoring the processed content needs no reprocessing
+	if err := os.WriteFile(testFile, original, 0o644); err != nil {
+		t.Fatalf("os.WriteFile() error = %v", err)
+	}
+	assertShouldProcess(false)
+
+	// The same cache in mtime mode still uses the recorded timestamp
+	cache.Mode = cacheModeMtime
+	if err := os.Chtimes(testFile, future, future); err != nil {
+		t.Fatalf("os.Chtimes() error = %v", err)
+	}
+	assertShouldProcess(true)
+}
+
+func TestClearCache(t *testing.T) {
+	cachePath := filepath.Join(t.TempDir(), cacheFileName)
+
+	cache := &FileCache{
+		ProcessedFiles: map[string]time.Time{
+			"main.go":      time.Now(),
+			"src/utils.go": time.Now(),
+		},
+	}
+	data, err := json.Marshal(cache)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	if err := os.WriteFile(cachePath, data, 0o644); err != nil {
+		t.Fatalf("os.WriteFile() error = %v", err)
+	}
+
+	entries, err := clearCache(cachePath)
+	if err != nil {
+		t.Fatalf("clearCache() error = %v", err)
+	}
+	if entries != 2 {
+		t.Errorf("clearCache() = %d entries, want 2", entries)
+	}
+	if _, err := os.Stat(cachePath); !os.IsNotExist(err) {
+		t.Errorf("cache file still exists after clearCache(): %v", err)
+	}
+
+	// Clearing again finds nothing to remove
+	entries, err = clearCache(cachePath)
+	if err != nil {
+		t.Fatalf("clearCache() on missing file error = %v", err)
+	}
+	if entries != 0 {
+		t.Errorf("clearCache() on missing file = %d entries, want 0", entries)
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, cacheFileName)
+
+	if err := os.WriteFile(path, []byte("old"), 0o644); err != nil {
+		t.Fatalf("os.WriteFile() error = %v", err)
+	}
+	if err := writeFileAtomic(path, []byte("new")); err != nil {
+		t.Fatalf("writeFileAtomic() error = %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("os.ReadFile() error = %v", err)
+	}
+	if string(data) != "new" {
+		t.Errorf("writeFileAtomic() wrote %q, want %q", string(data), "new")
+	}
+
+	// The temporary file is renamed into place, so nothing else is left in the directory
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("os.ReadDir() error = %v", err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("directory has %d entries after writeFileAtomic(), want 1", len(entries))
+	}
+}
+
+func TestLoadCacheRecoversFromCorruption(t *testing.T) {
+	path := filepath.Join(t.TempDir(), cacheFileName)
+	if err := os.WriteFile(path, []byte(`{"processed_files": {"main.go": "2025-10-`), 0o644); err != nil {
+		t.Fatalf("os.WriteFile() error = %v", err)
+	}
+
+	cache, err := loadCache(path)
+	if err != nil {
+		t.Fatalf("loadCache() error = %v", err)
+	}
+	if len(cache.ProcessedFiles) != 0 {
+		t.Errorf("loadCache() returned %d entries from a corrupt cache, want 0", len(cache.ProcessedFiles))
+	}
+
+	// The recovered cache must be usable for recording new entries
+	cache.ProcessedFiles["main.go"] = time.Now()
+}
+
+func TestFileCachePrune(t *testing.T) {
+	gitRoot, err := findGitRoot()
+	if err != nil {
+		t.Skipf("not in a git repository, skipping test: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(gitRoot, "main.go")); err != nil {
+		t.Skipf("main.go not found, skipping test")
+	}
+
+	cache := &FileCache{
+		ProcessedFiles: map[string]time.Time{
+			"main.go":                      time.Now(),
+			"deleted.go":                   time.Now(),
+			filepath.Join("gone", "old.s"): time.Now(),
+		},
+		Hashes: map[string]string{
+			"main.go":    "abc",
+			"deleted.go": "def",
+		},
+	}
+
+	removed, err := cache.prune()
+	if err != nil {
+		t.Fatalf("prune() error = %v", err)
+	}
+	if removed != 2 {
+		t.Errorf("prune() removed %d entries, want 2", removed)
+	}
+
+	if _, exists := cache.ProcessedFiles["main.go"]; !exists {
+		t.Errorf("prune() removed existing file main.go")
+	}
+	if _, exists := cache.Hashes["main.go"]; !exists {
+		t.Errorf("prune() removed hash for existing file main.go")
+	}
+	if len(cache.ProcessedFiles) != 1 || len(cache.Hashes) != 1 {
+		t.Errorf("prune() left %v and %v, want only main.go", cache.ProcessedFiles, cache.Hashes)
+	}
+}
+
+func TestCachePathOverride(t *testing.T) {
+	dir := t.TempDir()
+	flagPath := filepath.Join(dir, "flag-cache.json")
+	envPath := filepath.Join(dir, "env-cache.json")
+
+	t.Setenv(cacheEnvVar, envPath)
+
+	got, err := getCachePath("")
+	if err != nil {
+		t.Fatalf("getCachePath() error = %v", err)
+	}
+	if got != envPath {
+		t.Errorf("getCachePath() with %s = %q, want %q", cacheEnvVar, got, envPath)
+	}
+
+	// The flag takes precedence over the environment variable
+	got, err = getCachePath(flagPath)
+	if err != nil {
+		t.Fatalf("getCachePath() error = %v", err)
+	}
+	if got != flagPath {
+		t.Errorf("getCachePath() with override = %q, want %q", got, flagPath)
+	}
+
+	cache, err := loadCache(got)
+	if err != nil {
+		t.Fatalf("loadCache() error = %v", err)
+	}
+	cache.ProcessedFiles["main.go"] = time.Now().Truncate(time.Second)
+	if err := cache.save(); err != nil {
+		t.Fatalf("save() error = %v", err)
+	}
+
+	if _, err := os.Stat(flagPath); err != nil {
+		t.Fatalf("save() did not write to the override path: %v", err)
+	}
+
+	reloaded, err := loadCache(flagPath)
+	if err != nil {
+		t.Fatalf("loadCache() error = %v", err)
+	}
+	if _, exists := reloaded.ProcessedFiles["main.go"]; !exists {
+		t.Errorf("loadCache() from override path is missing main.go")
+	}
+}
+
+func TestAcquireCacheLock(t *testing.T) {
+	cachePath := filepath.Join(t.TempDir(), cacheFileName)
+
+	release, err := acquireCacheLock(cachePath)
+	if err != nil {
+		t.Fatalf("acquireCacheLock() error = %v", err)
+	}
+
+	// A second run fails fast while the lock is held
+	if _, err := acquireCacheLock(cachePath); err == nil {
+		t.Fatalf("acquireCacheLock() succeeded while the lock was held")
+	}
+
+	release()
+	release, err = acquireCacheLock(cachePath)
+	if err != nil {
+		t.Fatalf("acquireCacheLock() after release error = %v", err)
+	}
+	release()
+}
+
+func TestAcquireCacheLockIgnoresStaleLockfile(t *testing.T) {
+	cachePath := filepath.Join(t.TempDir(), cacheFileName)
+	// A run that was killed leaves its lockfile behind, naming a process that is gone
+	if err := os.WriteFile(cachePath+".lock", []byte("999999999\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	release, err := acquireCacheLock(cachePath)
+	if err != nil {
+		t.Fatalf("acquireCacheLock() with a stale lockfile error = %v", err)
+	}
+	defer release()
+
+	data, err := os.ReadFile(cachePath + ".lock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := strconv.Itoa(os.Getpid()) + "\n"; string(data) != want {
+		t.Errorf("lockfile = %q, want %q", data, want)
+	}
+}
+
+func TestFileCacheProvenance(t *testing.T) {
+	gitRoot, err := findGitRoot()
+	if err != nil {
+		t.Skipf("not in a git repository, skipping test: %v", err)
+	}
+
+	testFile := filepath.Join(gitRoot, "main.go")
+	if _, err := os.Stat(testFile); err != nil {
+		t.Skipf("main.go not found, skipping test")
+	}
+
+	current := ProcessingInfo{Model: "haiku", Version: "1.0.0"}
+
+	tests := []struct {
+		name           string
+		provenance     map[string]ProcessingInfo
+		expectedResult bool
+	}{
+		{
+			name:           "same model and version - should not process",
+			provenance:     map[string]ProcessingInfo{"main.go": current},
+			expectedResult: false,
+		},
+		{
+			name:           "model changed - should process",
+			provenance:     map[string]ProcessingInfo{"main.go": {Model: "sonnet", Version: "1.0.0"}},
+			expectedResult: true,
+		},
+		{
+			name:           "tool version changed - should process",
+			provenance:     map[string]ProcessingInfo{"main.go": {Model: "haiku", Version: "0.9.0"}},
+			expectedResult: true,
+		},
+		{
+			name:           "no provenance recorded - should not process",
+			provenance:     map[string]ProcessingInfo{},
+			expectedResult: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := &FileCache{
+				ProcessedFiles: map[string]time.Time{
+					"main.go": time.Now().Add(24 * time.Hour),
+				},
+				Provenance: tt.provenance,
+				Current:    current,
+			}
+
+			result, err := cache.shouldProcess(testFile)
+			if err != nil {
+				t.Fatalf("shouldProcess() error = %v", err)
+			}
+			if result != tt.expectedResult {
+				t.Errorf("shouldProcess() = %v, want %v", result, tt.expectedResult)
+			}
+		})
+	}
+}
+
+func TestParseNameStatus(t *testing.T) {
+	output := "M\tmain.go\nA\tsrc/new.go\nD\tremoved.go\nR087\told/name.go\tnew/name.go\nC100\tbase.go\tcopy.go\nT\tlink\n\n"
+
+	got := parseNameStatus(output)
+	want := []string{"main.go", "src/new.go", "new/name.go", "copy.go", "link"}
+
+	if len(got) != len(want) {
+		t.Fatalf("parseNameStatus() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("parseNameStatus()[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestParseFileList(t *testing.T) {
+	got := parseFileList("main.go\nsrc/utils.go\n\n  docs/readme.md  \n")
+	want := []string{"main.go", "src/utils.go", "docs/readme.md"}
+
+	if len(got) != len(want) {
+		t.Fatalf("parseFileList() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("parseFileList()[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+
+	if got := parseFileList(""); len(got) != 0 {
+		t.Errorf("parseFileList(\"\") = %v, want empty", got)
+	}
+}
+
+func TestStripStdin(t *testing.T) {
+	tests := []struct {
+		name   string
+		lang   string
+		config Config
+		input  string
+		want   string
+	}{
+		{
+			"go",
+			stripper.Go,
+			Config{CollapseNewlines: true},
+			"package main\n\n// Main runs.\nfunc main() {} // done\n",
+			"package main\n\nfunc main() {}\n",
+		},
+		{
+			"go directives",
+			stripper.Go,
+			Config{KeepDirectives: []string{"go:"}},
+			"package main\n\n//go:generate stringer\nvar x = 1 // one\n",
+			"package main\n\n//go:generate stringer\nvar x = 1\n",
+		},
+		{
+			"python",
+			stripper.Python,
+			Config{},
+			"def f():\n    # note\n    return '# kept'  # gone\n",
+			"def f():\n\n    return '# kept'\n",
+		},
+		{
+			"no final newline",
+			stripper.Python,
+			Config{},
+			"x = 1  # one",
+			"x = 1",
+		},
+		{
+			"detected from shebang",
+			"",
+			Config{},
+			"#!/bin/sh\necho hi # greet\n",
+			"#!/bin/sh\necho hi\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := stripStdin(tt.lang, tt.config, strings.NewReader(tt.input), &out); err != nil {
+				t.Fatalf("stripStdin() error = %v", err)
+			}
+			if out.String() != tt.want {
+				t.Errorf("stripStdin() output = %q, want %q", out.String(), tt.want)
+			}
+		})
+	}
+
+	if err := stripStdin("cobol", Config{}, strings.NewReader("x"), io.Discard); err == nil || !strings.Contains(err.Error(), "cobol") {
+		t.Errorf("stripStdin() of unknown language error = %v, want it named", err)
+	}
+	if err := stripStdin("", Config{}, strings.NewReader("hello world\n"), io.Discard); err == nil || !strings.Contains(err.Error(), "-lang") {
+		t.Errorf("stripStdin() of undetectable input error = %v, want a hint to set -lang", err)
+	}
+	var binaryErr *stripper.ErrBinaryFile
+	if err := stripStdin(stripper.Go, Config{}, strings.NewReader("a\x00b"), io.Discard); !errors.As(err, &binaryErr) {
+		t.Errorf("stripStdin() of binary input error = %v, want stripper.ErrBinaryFile", err)
+	}
+}
+
+func TestReadFileList(t *testing.T) {
+	dir := t.TempDir()
+	listFile := filepath.Join(dir, "files.txt")
+	if err := os.WriteFile(listFile, []byte("main.go\r\n\nsrc/utils.go\n\"src/caf\\303\\251.go\"\n"), 0o644); err != nil {
+		t.Fatalf("os.WriteFile() error = %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		path  string
+		stdin string
+		want  []string
+	}{
+		{"newline separated file", listFile, "", []string{"main.go", "src/utils.go", "src/café.go"}},
+		{"newline separated stdin", "-", "a.go\nb.go", []string{"a.go", "b.go"}},
+		{"nul separated stdin", "-", "a.go\x00with space.go\x00new\nline.go\x00", []string{"a.go", "with space.go", "new\nline.go"}},
+		{"nul separated with trailing newline", "-", "a.go\x00b.go\x00\n", []string{"a.go", "b.go"}},
+		{"empty stdin", "-", "", []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readFileList(tt.path, strings.NewReader(tt.stdin))
+			if err != nil {
+				t.Fatalf("readFileList() error = %v", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("readFileList() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := readFileList(filepath.Join(dir, "missing.txt"), nil); err == nil {
+		t.Error("readFileList() of missing file error = nil, want error")
+	}
+}
+
+func TestParseDiffRange(t *testing.T) {
+	tests := []struct {
+		spec     string
+		wantBase string
+		wantHead string
+		wantErr  bool
+	}{
+		{spec: "main..HEAD", wantBase: "main", wantHead: "HEAD"},
+		{spec: "origin/main..feature/x", wantBase: "origin/main", wantHead: "feature/x"},
+		{spec: "main", wantErr: true},
+		{spec: "main..", wantErr: true},
+		{spec: "..HEAD", wantErr: true},
+		{spec: "a..b..c", wantErr: true},
+		{spec: "main...HEAD", wantErr: true},
+		{spec: "--output=x..HEAD", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			base, head, err := parseDiffRange(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseDiffRange(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			}
+			if base != tt.wantBase || head != tt.wantHead {
+				t.Errorf("parseDiffRange(%q) = (%q, %q), want (%q, %q)", tt.spec, base, head, tt.wantBase, tt.wantHead)
+			}
+		})
+	}
+}
+
+// initGitRepo creates a git repository in a temporary directory holding files, with
+// everything committed, and returns its root. It skips the test if git is missing.
+func initGitRepo(t *testing.T, files map[string]string) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skipf("git not found, skipping test: %v", err)
+	}
+
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("os.MkdirAll() error = %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("os.WriteFile() error = %v", err)
+		}
+	}
+	gitIn(t, dir, "init", "-q")
+	gitIn(t, dir, "add", "-A")
+	gitIn(t, dir, "-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-m", "initial")
+	return dir
+}
+
+// gitIn runs git with args in dir, failing the test if it fails.
+func gitIn(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v error = %v: %s", args, err, output)
+	}
+}
+
+func TestGetModifiedFilesFromSubdirectory(t *testing.T) {
+	dir := initGitRepo(t, map[string]string{"a.go": "package a\n", "sub/b.go": "package sub\n"})
+	for name, content := range map[string]string{"a.go": "package a // a\n", "sub/b.go": "package sub // b\n", "sub/c.go": "package sub\n"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("os.WriteFile() error = %v", err)
+		}
+	}
+	t.Chdir(filepath.Join(dir, "sub"))
+
+	files, err := getModifiedFiles()
+	if err != nil {
+		t.Fatalf("getModifiedFiles() error = %v", err)
+	}
+	want := []string{filepath.Join(dir, "a.go"), filepath.Join(dir, "sub", "b.go"), filepath.Join(dir, "sub", "c.go")}
+	if slices.Sort(files); !slices.Equal(files, want) {
+		t.Errorf("getModifiedFiles() = %v, want %v", files, want)
+	}
+}
+
+func TestGetDiffFilesUnknownRef(t *testing.T) {
+	if _, err := findGitRoot(); err != nil {
+		t.Skipf("not in a git repository, skipping test: %v", err)
+	}
+
+	if _, err := getDiffFiles("HEAD", "no-such-ref-for-nocomms"); err == nil {
+		t.Errorf("getDiffFiles() with unknown ref succeeded, want error")
+	}
+}
+
+func TestGetDiffFilesFromSubdirectory(t *testing.T) {
+	dir := initGitRepo(t, map[string]string{"a.go": "package a\n", "sub/b.go": "package sub\n"})
+	gitIn(t, dir, "tag", "base")
+	for name, content := range map[string]string{"a.go": "package a // a\n", "sub/b.go": "package sub // b\n"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("os.WriteFile() error = %v", err)
+		}
+	}
+	gitIn(t, dir, "-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-am", "comments")
+	t.Chdir(filepath.Join(dir, "sub"))
+
+	files, err := getDiffFiles("base", "HEAD")
+	if err != nil {
+		t.Fatalf("getDiffFiles() error = %v", err)
+	}
+	want := []string{filepath.Join(dir, "a.go"), filepath.Join(dir, "sub", "b.go")}
+	if !slices.Equal(files, want) {
+		t.Errorf("getDiffFiles() = %v, want %v", files, want)
+	}
+}
+
+func TestGetPartiallyStagedFilesFromSubdirectory(t *testing.T) {
+	dir := initGitRepo(t, map[string]string{"a.go": "package a\n", "sub/b.go": "package sub\n"})
+	if err := os.WriteFile(filepath.Join(dir, "a.go"), []byte("package a // a\n"), 0o644); err != nil {
+		t.Fatalf("os.WriteFile() error = %v", err)
+	}
+	t.Chdir(filepath.Join(dir, "sub"))
+
+	partiallyStaged, err := getPartiallyStagedFiles()
+	if err != nil {
+		t.Fatalf("getPartiallyStagedFiles() error = %v", err)
+	}
+	if want := filepath.Join(dir, "a.go"); len(partiallyStaged) != 1 || !partiallyStaged[want] {
+		t.Errorf("getPartiallyStagedFiles() = %v, want only %s", partiallyStaged, want)
+	}
+}
+
+func TestRestageCommand(t *testing.T) {
+	processed := []string{"/repo/a.go", "/repo/partial.go", "/repo/-dash.go"}
+	partiallyStaged := map[string]bool{"/repo/partial.go": true}
+
+	files := filesToRestage(processed, partiallyStaged)
+	cmd := gitAddCommand(files)
+
+	want := []string{"git", "add", "--", "/repo/a.go", "/repo/-dash.go"}
+	if len(cmd.Args) != len(want) {
+		t.Fatalf("gitAddCommand() args = %v, want %v", cmd.Args, want)
+	}
+	for i := range want {
+		if cmd.Args[i] != want[i] {
+			t.Errorf("gitAddCommand() args[%d] = %q, want %q", i, cmd.Args[i], want[i])
+		}
+	}
+}
+
+func TestUnquoteGitPath(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{name: "plain path", input: "src/main.go", expected: "src/main.go"},
+		{name: "path with spaces is not quoted", input: "my dir/a b.go", expected: "my dir/a b.go"},
+		{name: "octal escapes", input: `"src/caf\303\251.go"`, expected: "src/café.go"},
+		{name: "escaped quote", input: `"say \"hi\".go"`, expected: `say "hi".go`},
+		{name: "escaped backslash and tab", input: `"a\\b\tc.go"`, expected: "a\\b\tc.go"},
+		{name: "malformed quoting left alone", input: `"broken\q"`, expected: `"broken\q"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := unquoteGitPath(tt.input); got != tt.expected {
+				t.Errorf("unquoteGitPath(%q) = %q, want %q", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseGitOutputQuotedPaths(t *testing.T) {
+	files := parseFileList("\"caf\\303\\251.go\"\nplain.go\n")
+	if len(files) != 2 || files[0] != "café.go" || files[1] != "plain.go" {
+		t.Errorf("parseFileList() = %q, want [café.go plain.go]", files)
+	}
+
+	files = parseNameStatus("M\t\"na\\303\\257ve.go\"\nR100\told.go\t\"new \\\"name\\\".go\"\n")
+	if len(files) != 2 || files[0] != "naïve.go" || files[1] != `new "name".go` {
+		t.Errorf("parseNameStatus() = %q, want [naïve.go new \"name\".go]", files)
+	}
+}
+
+func TestRunOutsideGitRepository(t *testing.T) {
+	// Resolve symlinks so paths match the working directory reported by os.Getwd
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatalf("filepath.EvalSymlinks() error = %v", err)
+	}
+	t.Chdir(dir)
+	t.Setenv(cacheEnvVar, "")
+
+	if _, err := findGitRoot(); err == nil {
+		t.Skip("temp directory is inside a git repository")
+	}
+
+	file := filepath.Join(dir, "main.go")
+	if err := os.WriteFile(file, []byte("package main // comment\n"), 0o644); err != nil {
+		t.Fatalf("os.WriteFile() error = %v", err)
+	}
+
+	if err := processFile(file, Config{}); err != nil {
+		t.Fatalf("processFile() error = %v", err)
+	}
+	config := Config{Files: []string{file}, CacheOnly: true}
+	if _, err := run(config, newReporter(config, os.Stdout, os.Stderr)); err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+
+	// The cache falls back to the working directory with keys relative to it
+	cache, err := loadCache(filepath.Join(dir, cacheFileName))
+	if err != nil {
+		t.Fatalf("loadCache() error = %v", err)
+	}
+	if _, exists := cache.ProcessedFiles["main.go"]; !exists {
+		t.Errorf("cache in %s is missing main.go: %v", dir, cache.ProcessedFiles)
+	}
+}
+
+func TestExpandDirectories(t *testing.T) {
+	dir := t.TempDir()
+	for _, rel := range []string{
+		"main.go",
+		"README.txt",
+		filepath.Join("src", "app.ts"),
+		filepath.Join("src", "data.bin"),
+		filepath.Join("src", "nested", "lib.py"),
+		filepath.Join("src", "nested", "gen.pb.go"),
+		filepath.Join("vendor", "dep.go"),
+		filepath.Join(".git", "hooks", "pre-commit.sh"),
+	} {
+		path := filepath.Join(dir, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("os.MkdirAll() error = %v", err)
+		}
+		if err := os.WriteFile(path, nil, 0o644); err != nil {
+			t.Fatalf("os.WriteFile() error = %v", err)
+		}
+	}
+
+	single := filepath.Join(dir, "README.txt")
+	files, err := expandDirectories([]string{dir, single}, Config{Exclude: []string{"vendor", "*.pb.go"}})
+	if err != nil {
+		t.Fatalf("expandDirectories() error = %v", err)
+	}
+
+	want := []string{
+		filepath.Join(dir, "main.go"),
+		filepath.Join(dir, "src", "app.ts"),
+		filepath.Join(dir, "src", "nested", "lib.py"),
+		// File arguments are passed through even if unsupported
+		single,
+	}
+	if len(files) != len(want) {
+		t.Fatalf("expandDirectories() = %v, want %v", files, want)
+	}
+	for i := range want {
+		if files[i] != want[i] {
+			t.Errorf("expandDirectories()[%d] = %q, want %q", i, files[i], want[i])
+		}
+	}
+}
+
+func TestExpandGlobs(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+
+	for _, rel := range []string{"a.go", "b.go", "c.ts", "src/d.ts", "src/deep/e.ts", "src/deep/f.go", ".git/g.ts"} {
+		if err := os.MkdirAll(filepath.Dir(rel), 0o755); err != nil {
+			t.Fatalf("os.MkdirAll() error = %v", err)
+		}
+		if err := os.WriteFile(rel, nil, 0o644); err != nil {
+			t.Fatalf("os.WriteFile() error = %v", err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		args    []string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "single star",
+			args: []string{"*.go"},
+			want: []string{"a.go", "b.go"},
+		},
+		{
+			name: "double star",
+			args: []string{"**/*.ts"},
+			want: []string{"c.ts", filepath.Join("src", "d.ts"), filepath.Join("src", "deep", "e.ts")},
+		},
+		{
+			name: "double star under a directory",
+			args: []string{"src/**/*.go"},
+			want: []string{filepath.Join("src", "deep", "f.go")},
+		},
+		{
+			name: "plain paths pass through",
+			args: []string{"a.go", "missing.go"},
+			want: []string{"a.go", "missing.go"},
+		},
+		{
+			name:    "no matches",
+			args:    []string{"**/*.rs"},
+			wantErr: true,
+		},
+		{
+			name:    "no matches without double star",
+			args:    []string{"*.rs"},
+			wantErr: true,
+		},
+		{
+			name:    "malformed pattern",
+			args:    []string{"src/**/[.go"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := expandGlobs(tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expandGlobs(%v) error = %v, wantErr %v", tt.args, err, tt.wantErr)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("expandGlobs(%v) = %v, want %v", tt.args, got, tt.want)
+			}
+			for i := range tt.want {
+				if got[i] != tt.want[i] {
+					t.Errorf("expandGlobs(%v)[%d] = %q, want %q", tt.args, i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+// claudeCommenters returns a commenterFactory for the Claude backend configured by config.
+func claudeCommenters(config Config) commenterFactory {
+	return func(stdout, stderr io.Writer) Commenter {
+		return newCommenter(config, stdout, stderr)
+	}
+}
+
+// writeFakeClaude writes an executable shell script to dir that stands in for the claude CLI.
+func writeFakeClaude(t *testing.T, dir, script string) string {
+	t.Helper()
+	path := filepath.Join(dir, "fake-claude")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script), 0o755); err != nil {
+		t.Fatalf("os.WriteFile() error = %v", err)
+	}
+	return path
+}
+
+func TestCommentFileRetriesTransientFailures(t *testing.T) {
+	dir := t.TempDir()
+	counter := filepath.Join(dir, "attempts")
+	file := filepath.Join(dir, "notes.txt")
+	if err := os.WriteFile(file, nil, 0o644); err != nil {
+		t.Fatalf("os.WriteFile() error = %v", err)
+	}
+
+	// Fails with a rate limit error twice, then succeeds
+	bin := writeFakeClaude(t, dir, `echo x >> "`+counter+`"
+if [ "$(wc -l < "`+counter+`")" -le 2 ]; then
+  echo "Error: rate limit exceeded" >&2
+  exit 1
+fi
+`)
+
+	config := Config{ClaudeBin: bin, Retries: 3, RetryBackoff: time.Millisecond}
+	if _, err := commentFile(context.Background(), file, config, newCommenter(config, io.Discard, io.Discard), io.Discard, io.Discard); err != nil {
+		t.Fatalf("commentFile() error = %v", err)
+	}
+
+	data, err := os.ReadFile(counter)
+	if err != nil {
+		t.Fatalf("os.ReadFile() error = %v", err)
+	}
+	if attempts := len(data) / len("x\n"); attempts != 3 {
+		t.Errorf("claude ran %d times, want 3", attempts)
+	}
+
+	// Too few retries surface the failure
+	os.Remove(counter)
+	config.Retries = 1
+	if _, err := commentFile(context.Background(), file, config, newCommenter(config, io.Discard, io.Discard), io.Discard, io.Discard); err == nil {
+		t.Errorf("commentFile() with 1 retry succeeded, want error")
+	}
+
+	// Non-transient failures are not retried
+	os.Remove(counter)
+	config.ClaudeBin = writeFakeClaude(t, dir, `echo x >> "`+counter+`"
+echo "Error: invalid model" >&2
+exit 1
+`)
+	config.Retries = 3
+	if _, err := commentFile(context.Background(), file, config, newCommenter(config, io.Discard, io.Discard), io.Discard, io.Discard); err == nil {
+		t.Errorf("commentFile() with permanent failure succeeded, want error")
+	}
+	if data, _ := os.ReadFile(counter); len(data) != len("x\n") {
+		t.Errorf("permanent failure ran claude %d times, want 1", len(data)/len("x\n"))
+	}
+}
+
+func TestCommentFileTimeout(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "notes.txt")
+	if err := os.WriteFile(file, nil, 0o644); err != nil {
+		t.Fatalf("os.WriteFile() error = %v", err)
+	}
+
+	// sleep runs as a child of the shell, so only killing the process group
+	// releases the output pipes promptly
+	config := Config{
+		ClaudeBin: writeFakeClaude(t, dir, "sleep 30\n"),
+		Timeout:   100 * time.Millisecond,
+	}
+
+	start := time.Now()
+	_, err := commentFile(context.Background(), file, config, newCommenter(config, io.Discard, io.Discard), io.Discard, io.Discard)
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Fatalf("commentFile() error = %v, want timeout error", err)
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("commentFile() returned after %s, want prompt return on timeout", elapsed)
+	}
+
+	// commentFiles reports the timeout against the file
+	_, err = commentFiles([]string{file}, config, claudeCommenters(config), newReporter(config, os.Stdout, os.Stderr), nil)
+	if err == nil || !strings.Contains(err.Error(), file+": timed out") {
+		t.Errorf("commentFiles() error = %v, want timeout for %s", err, file)
+	}
+}
+
+func TestCommentFileValidate(t *testing.T) {
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatalf("filepath.EvalSymlinks() error = %v", err)
+	}
+	t.Chdir(dir)
+
+	original := "package main\n\nfunc main() {}\n"
+	file := filepath.Join(dir, "main.go")
+	if err := os.WriteFile(file, []byte(original), 0o644); err != nil {
+		t.Fatalf("os.WriteFile() error = %v", err)
+	}
+
+	// The fake validator rejects files containing BROKEN, standing in for gofmt -e
+	validator := filepath.Join(dir, "fake-validator")
+	script := "#!/bin/sh\nfor arg; do file=$arg; done\nif grep -q BROKEN \"$file\"; then echo \"syntax error\"; exit 1; fi\n"
+	if err := os.WriteFile(validator, []byte(script), 0o755); err != nil {
+		t.Fatalf("os.WriteFile() error = %v", err)
+	}
+	defer func(command []string) { defaultValidators[stripper.Go] = command }(defaultValidators[stripper.Go])
+	defaultValidators[stripper.Go] = []string{validator}
+
+	config := Config{
+		ClaudeBin: writeFakeClaude(t, dir, "for arg; do file=$arg; done\necho \"// Entry point\" >> \"$file\"\n"),
+		Prompt:    "{filename}",
+		Validate:  true,
+	}
+	if _, err := commentFile(context.Background(), file, config, newCommenter(config, io.Discard, io.Discard), io.Discard, io.Discard); err != nil {
+		t.Fatalf("commentFile() error = %v, want valid file to pass", err)
+	}
+
+	// Claude breaks the file, which fails validation and is left as is without -backup
+	config.ClaudeBin = writeFakeClaude(t, dir, "for arg; do file=$arg; done\necho BROKEN >> \"$file\"\n")
+	if err := os.WriteFile(file, []byte(original), 0o644); err != nil {
+		t.Fatalf("os.WriteFile() error = %v", err)
+	}
+	_, err = commentFile(context.Background(), file, config, newCommenter(config, io.Discard, io.Discard), io.Discard, io.Discard)
+	if err == nil || !strings.Contains(err.Error(), "validation failed") || !strings.Contains(err.Error(), "syntax error") {
+		t.Fatalf("commentFile() error = %v, want validation failure", err)
+	}
+	if data, _ := os.ReadFile(file); !strings.Contains(string(data), "BROKEN") {
+		t.Errorf("file without -backup = %q, want it left for inspection", data)
+	}
+
+	// With -backup the original is restored
+	config.Backup = true
+	if err := os.WriteFile(file, []byte(original), 0o644); err != nil {
+		t.Fatalf("os.WriteFile() error = %v", err)
+	}
+	if err := backupFile(file, []byte(original)); err != nil {
+		t.Fatalf("backupFile() error = %v", err)
+	}
+	_, err = commentFile(context.Background(), file, config, newCommenter(config, io.Discard, io.Discard), io.Discard, io.Discard)
+	if err == nil || !strings.Contains(err.Error(), "restored the original") {
+		t.Fatalf("commentFile() error = %v, want validation failure with restore", err)
+	}
+	if data, _ := os.ReadFile(file); string(data) != original {
+		t.Errorf("file after rollback = %q, want %q", data, original)
+	}
+
+	// A missing validator only warns
+	defaultValidators[stripper.Go] = []string{filepath.Join(dir, "missing-validator")}
+	var stderr strings.Builder
+	if _, err := commentFile(context.Background(), file, config, newCommenter(config, io.Discard, io.Discard), io.Discard, &stderr); err != nil {
+		t.Errorf("commentFile() with missing validator error = %v, want nil", err)
+	}
+	if !strings.Contains(stderr.String(), "not validated") {
+		t.Errorf("stderr = %q, want a not validated warning", stderr.String())
+	}
+}
+
+func TestRunDryRun(t *testing.T) {
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatalf("filepath.EvalSymlinks() error = %v", err)
+	}
+	t.Chdir(dir)
+
+	file := filepath.Join(dir, "main.go")
+	if err := os.WriteFile(file, []byte("package main // comment\n"), 0o644); err != nil {
+		t.Fatalf("os.WriteFile() error = %v", err)
+	}
+
+	// The fake Claude leaves a marker behind if it is ever run
+	marker := filepath.Join(dir, "claude-ran")
+	cacheFile := filepath.Join(dir, "cache.json")
+	config := Config{
+		Files:     []string{file},
+		BatchSize: 1,
+		Prompt:    "comment {filename}",
+		ClaudeBin: writeFakeClaude(t, dir, "touch "+marker+"\n"),
+		CacheFile: cacheFile,
+		DryRun:    true,
+	}
+	if _, err := run(config, newReporter(config, os.Stdout, os.Stderr)); err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+
+	if _, err := os.Stat(marker); !os.IsNotExist(err) {
+		t.Errorf("claude was run in dry-run mode")
+	}
+	if _, err := os.Stat(cacheFile); !os.IsNotExist(err) {
+		t.Errorf("cache was written in dry-run mode")
+	}
+
+	// Comment removal still happens so the cleaned input can be inspected
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("os.ReadFile() error = %v", err)
+	}
+	if got := string(data); got != "package main\n" {
+		t.Errorf("file content = %q, want comments removed", got)
+	}
+}
+
+func TestRunExtensionFilters(t *testing.T) {
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatalf("filepath.EvalSymlinks() error = %v", err)
+	}
+	t.Chdir(dir)
+
+	const content = "x = 1 # comment\n"
+	write := func(name string) string {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("os.MkdirAll() error = %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("os.WriteFile() error = %v", err)
+		}
+		return path
+	}
+	script := write("script.py")
+	tool := write(filepath.Join("tools", "gen.py"))
+	config := write("config.yaml")
+	shell := write("run.sh")
+	notes := write("notes.txt")
+	backup := write(filepath.Join(backupDirName, "old.py"))
+
+	// The filters compose with the gitignore and unsupported-type skipping
+	cfg := Config{
+		Files:             []string{script, tool, config, shell, notes, backup},
+		BatchSize:         10,
+		Prompt:            "{basename}",
+		CacheFile:         filepath.Join(dir, "cache.json"),
+		DryRun:            true,
+		NoFormat:          true,
+		Include:           []string{".py", ".yaml", ".txt"},
+		ExcludeExtensions: []string{".yaml"},
+	}
+	result, err := run(cfg, newReporter(cfg, io.Discard, io.Discard))
+	if err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+
+	if want := []string{script, tool}; !slices.Equal(result.Processed, want) {
+		t.Errorf("Processed = %v, want %v", result.Processed, want)
+	}
+	wantSkipped := []SkippedFile{{notes, skipUnsupported}, {backup, skipGitIgnored}}
+	if !slices.Equal(result.Skipped, wantSkipped) {
+		t.Errorf("Skipped = %v, want %v", result.Skipped, wantSkipped)
+	}
+	for _, file := range []string{config, shell} {
+		if data, err := os.ReadFile(file); err != nil || string(data) != content {
+			t.Errorf("%s was modified despite being filtered out", file)
+		}
+	}
+}
+
+func TestRunExcludeFrom(t *testing.T) {
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatalf("filepath.EvalSymlinks() error = %v", err)
+	}
+	if err := os.Mkdir(filepath.Join(dir, ".git"), 0o755); err != nil {
+		t.Fatalf("os.Mkdir() error = %v", err)
+	}
+
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("os.MkdirAll() error = %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("os.WriteFile() error = %v", err)
+		}
+		return path
+	}
+	const content = "package main // comment\n"
+	entry := write(filepath.Join("cmd", "main.go"), content)
+	vendored := write(filepath.Join("vendor", "lib", "lib.go"), content)
+	generated := write(filepath.Join("cmd", "api.gen.go"), content)
+	kept := write(filepath.Join("cmd", "keep.gen.go"), content)
+	excludeFile := write("exclude.txt", "# generated code\nvendor/\n*.gen.go\n!keep.gen.go\n")
+
+	// Patterns are relative to the git root even when run from a subdirectory
+	t.Chdir(filepath.Join(dir, "cmd"))
+
+	config := Config{
+		Files:       []string{entry, vendored, generated, kept},
+		BatchSize:   10,
+		Prompt:      "{basename}",
+		CacheFile:   filepath.Join(dir, "cache.json"),
+		DryRun:      true,
+		NoFormat:    true,
+		ExcludeFrom: excludeFile,
+	}
+	result, err := run(config, newReporter(config, io.Discard, io.Discard))
+	if err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+
+	if want := []string{kept, entry}; !slices.Equal(result.Processed, want) {
+		t.Errorf("Processed = %v, want %v", result.Processed, want)
+	}
+	wantSkipped := []SkippedFile{{vendored, skipGitIgnored}, {generated, skipGitIgnored}}
+	if !slices.Equal(result.Skipped, wantSkipped) {
+		t.Errorf("Skipped = %v, want %v", result.Skipped, wantSkipped)
+	}
+	for _, file := range []string{vendored, generated} {
+		if data, err := os.ReadFile(file); err != nil || string(data) != content {
+			t.Errorf("%s was modified despite being excluded", file)
+		}
+	}
+
+	config.ExcludeFrom = filepath.Join(dir, "missing.txt")
+	if _, err := run(config, newReporter(config, io.Discard, io.Discard)); err == nil {
+		t.Error("run() error = nil, want an error for a missing exclude file")
+	}
+}
+
+func TestFileWindow(t *testing.T) {
+	files := []string{"/r/d.go", "/r/a.go", "/r/c.go", "/r/b.go", "/r/e.go"}
+	tests := []struct {
+		name          string
+		offset, limit int
+		want          []string
+	}{
+		{"no window", 0, 0, []string{"/r/a.go", "/r/b.go", "/r/c.go", "/r/d.go", "/r/e.go"}},
+		{"limit", 0, 2, []string{"/r/a.go", "/r/b.go"}},
+		{"offset and limit", 2, 2, []string{"/r/c.go", "/r/d.go"}},
+		{"last partial chunk", 4, 2, []string{"/r/e.go"}},
+		{"offset only", 3, 0, []string{"/r/d.go", "/r/e.go"}},
+		{"offset past the end", 7, 2, []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := fileWindow(files, tt.offset, tt.limit)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("fileWindow(%d, %d) = %v, want %v", tt.offset, tt.limit, got, tt.want)
+			}
+		})
+	}
+
+	// The input order is left alone
+	if files[0] != "/r/d.go" {
+		t.Errorf("fileWindow() reordered its input: %v", files)
+	}
+}
+
+func TestRunLimitOffset(t *testing.T) {
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatalf("filepath.EvalSymlinks() error = %v", err)
+	}
+	t.Chdir(dir)
+
+	var files []string
+	for _, name := range []string{"d.go", "a.go", "notes.txt", "c.go", "b.go"} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte("package main // comment\n"), 0o644); err != nil {
+			t.Fatalf("os.WriteFile() error = %v", err)
+		}
+		files = append(files, path)
+	}
+
+	// The window applies to the files left after the extension filters, in sorted order
+	config := Config{
+		Files:     files,
+		BatchSize: 10,
+		Prompt:    "{basename}",
+		CacheFile: filepath.Join(dir, "cache.json"),
+		DryRun:    true,
+		NoFormat:  true,
+		Include:   []string{".go"},
+		Offset:    1,
+		Limit:     2,
+	}
+	result, err := run(config, newReporter(config, io.Discard, io.Discard))
+	if err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+
+	want := []string{filepath.Join(dir, "b.go"), filepath.Join(dir, "c.go")}
+	if !slices.Equal(result.Processed, want) {
+		t.Errorf("Processed = %v, want %v", result.Processed, want)
+	}
+	for _, name := range []string{"a.go", "d.go"} {
+		if data, _ := os.ReadFile(filepath.Join(dir, name)); !strings.Contains(string(data), "// comment") {
+			t.Errorf("%s was processed outside the window", name)
+		}
+	}
+}
+
+func TestRunLimitSkipsUnprocessableFiles(t *testing.T) {
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatalf("filepath.EvalSymlinks() error = %v", err)
+	}
+	t.Chdir(dir)
+
+	// Backups are always ignored, and sort first like the unsupported file
+	for _, name := range []string{filepath.Join(backupDirName, "a.go"), "0.unknown", "b.go", "c.go", "d.go"} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("os.MkdirAll() error = %v", err)
+		}
+		if err := os.WriteFile(path, []byte("package main // comment\n"), 0o644); err != nil {
+			t.Fatalf("os.WriteFile() error = %v", err)
+		}
+	}
+
+	config := Config{
+		Files:     []string{filepath.Join(dir, "d.go"), filepath.Join(dir, "0.unknown"), filepath.Join(dir, backupDirName, "a.go"), filepath.Join(dir, "c.go"), filepath.Join(dir, "b.go")},
+		BatchSize: 10,
+		Prompt:    "{basename}",
+		CacheFile: filepath.Join(dir, "cache.json"),
+		DryRun:    true,
+		NoFormat:  true,
+		Limit:     2,
+	}
+	result, err := run(config, newReporter(config, io.Discard, io.Discard))
+	if err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+
+	want := []string{filepath.Join(dir, "b.go"), filepath.Join(dir, "c.go")}
+	if !slices.Equal(result.Processed, want) {
+		t.Errorf("Processed = %v, want %v", result.Processed, want)
+	}
+}
+
+func TestModifiedSince(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	ages := map[string]time.Duration{"new.go": time.Minute, "day.go": 20 * time.Hour, "old.go": 72 * time.Hour}
+	var files []string
+	for _, name := range []string{"old.go", "new.go", "day.go"} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, nil, 0o644); err != nil {
+			t.Fatalf("os.WriteFile() error = %v", err)
+		}
+		mtime := now.Add(-ages[name])
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatalf("os.Chtimes() error = %v", err)
+		}
+		files = append(files, path)
+	}
+	missing := filepath.Join(dir, "missing.go")
+	files = append(files, missing)
+
+	// Unreadable files are kept so that processing reports them
+	got := modifiedSince(files, now.Add(-24*time.Hour))
+	want := []string{filepath.Join(dir, "new.go"), filepath.Join(dir, "day.go"), missing}
+	if !slices.Equal(got, want) {
+		t.Errorf("modifiedSince(24h) = %v, want %v", got, want)
+	}
+
+	got = modifiedSince(files, now.Add(-time.Hour))
+	want = []string{filepath.Join(dir, "new.go"), missing}
+	if !slices.Equal(got, want) {
+		t.Errorf("modifiedSince(1h) = %v, want %v", got, want)
+	}
+}
+
+func TestRunSince(t *testing.T) {
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatalf("filepath.EvalSymlinks() error = %v", err)
+	}
+	t.Chdir(dir)
+
+	var files []string
+	for _, name := range []string{"recent.go", "stale.go"} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte("package main // comment\n"), 0o644); err != nil {
+			t.Fatalf("os.WriteFile() error = %v", err)
+		}
+		files = append(files, path)
+	}
+	stale := time.Now().Add(-48 * time.Hour)
+	if err := os.Chtimes(files[1], stale, stale); err != nil {
+		t.Fatalf("os.Chtimes() error = %v", err)
+	}
+
+	config := Config{
+		Files:     files,
+		BatchSize: 10,
+		Prompt:    "{basename}",
+		CacheFile: filepath.Join(dir, "cache.json"),
+		DryRun:    true,
+		NoFormat:  true,
+		Since:     24 * time.Hour,
+	}
+	result, err := run(config, newReporter(config, io.Discard, io.Discard))
+	if err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+
+	if want := files[:1]; !slices.Equal(result.Processed, want) {
+		t.Errorf("Processed = %v, want %v", result.Processed, want)
+	}
+	if data, _ := os.ReadFile(files[1]); !strings.Contains(string(data), "// comment") {
+		t.Errorf("stale.go was processed despite -since")
+	}
+}
+
+func TestRunSortsFilesBeforeBatching(t *testing.T) {
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatalf("filepath.EvalSymlinks() error = %v", err)
+	}
+	t.Chdir(dir)
+
+	var files []string
+	for _, name := range []string{"pkg/z.go", "b.go", "pkg/a.go", "a.go", "c.go"} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("os.MkdirAll() error = %v", err)
+		}
+		if err := os.WriteFile(path, []byte("package main // comment\n"), 0o644); err != nil {
+			t.Fatalf("os.WriteFile() error = %v", err)
+		}
+		files = append(files, path)
+	}
+
+	// The fake Claude records the order it is asked to comment files in; one file per
+	// batch keeps the batches sequential
+	order := filepath.Join(dir, "order.txt")
+	config := Config{
+		Files:     files,
+		BatchSize: 1,
+		Prompt:    "{filename}",
+		ClaudeBin: writeFakeClaude(t, dir, `for arg; do file=$arg; done
+echo "$file" >> "`+order+`"
+`),
+		CacheFile: filepath.Join(dir, "cache.json"),
+		NoFormat:  true,
+	}
+	result, err := run(config, newReporter(config, io.Discard, io.Discard))
+	if err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+
+	var want []string
+	for _, name := range []string{"a.go", "b.go", "c.go", "pkg/a.go", "pkg/z.go"} {
+		want = append(want, filepath.Join(dir, filepath.FromSlash(name)))
+	}
+	if !slices.Equal(result.Processed, want) {
+		t.Errorf("Processed = %v, want %v", result.Processed, want)
+	}
+
+	data, err := os.ReadFile(order)
+	if err != nil {
+		t.Fatalf("os.ReadFile() error = %v", err)
+	}
+	if got := strings.Fields(string(data)); !slices.Equal(got, want) {
+		t.Errorf("commented in order %v, want %v", got, want)
+	}
+}
+
+func TestRunSkipUnchanged(t *testing.T) {
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatalf("filepath.EvalSymlinks() error = %v", err)
+	}
+	t.Chdir(dir)
+
+	commented := filepath.Join(dir, "commented.go")
+	clean := filepath.Join(dir, "clean.go")
+	for _, skip := range []bool{false, true} {
+		// Each run strips commented.go, so it gets its comment back first
+		for file, content := range map[string]string{commented: "package main // main\n", clean: "package main\n"} {
+			if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+				t.Fatalf("os.WriteFile() error = %v", err)
+			}
+		}
+		config := Config{
+			Files:         []string{commented, clean},
+			BatchSize:     10,
+			Prompt:        "{basename}",
+			CacheFile:     filepath.Join(dir, "cache.json"),
+			DryRun:        true,
+			NoFormat:      true,
+			SkipUnchanged: skip,
+		}
+		var stdout bytes.Buffer
+		result, err := run(config, newReporter(config, &stdout, io.Discard))
+		if err != nil {
+			t.Fatalf("run() error = %v", err)
+		}
+
+		if !skip {
+			if want := []string{clean, commented}; !slices.Equal(result.Processed, want) {
+				t.Errorf("without -skip-unchanged, Processed = %v, want %v", result.Processed, want)
+			}
+			continue
+		}
+		if want := []string{commented}; !slices.Equal(result.Processed, want) {
+			t.Errorf("Processed = %v, want %v", result.Processed, want)
+		}
+		if want := []SkippedFile{{clean, skipUncommented}}; !slices.Equal(result.Skipped, want) {
+			t.Errorf("Skipped = %v, want %v", result.Skipped, want)
+		}
+		if want := "Skipping (no comments to regenerate): " + clean; !strings.Contains(stdout.String(), want) {
+			t.Errorf("output is missing %q:\n%s", want, stdout.String())
+		}
+	}
+}
+
+func TestProcessFileSkipUnchangedStreamed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "clean.go")
+	if err := os.WriteFile(path, []byte("package main\n\nfunc main() {}\n"), 0o644); err != nil {
+		t.Fatalf("os.WriteFile() error = %v", err)
+	}
+
+	original := streamThreshold
+	streamThreshold = 0
+	defer func() { streamThreshold = original }()
+
+	if err := processFile(path, Config{SkipUnchanged: true}); !errors.Is(err, ErrNoComments) {
+		t.Errorf("processFile() error = %v, want ErrNoComments", err)
+	}
+	if err := os.WriteFile(path, []byte("package main // main\n"), 0o644); err != nil {
+		t.Fatalf("os.WriteFile() error = %v", err)
+	}
+	if err := processFile(path, Config{SkipUnchanged: true}); err != nil {
+		t.Errorf("processFile() of commented file error = %v", err)
+	}
+}
+
+func TestProcessFileSkipUnchangedIgnoresWhitespace(t *testing.T) {
+	original := streamThreshold
+	defer func() { streamThreshold = original }()
+
+	// Removers tidy trailing whitespace, which -check doesn't count as a change either
+	for _, threshold := range []int64{original, 0} {
+		streamThreshold = threshold
+		path := filepath.Join(t.TempDir(), "clean.go")
+		if err := os.WriteFile(path, []byte("package main  \n\nfunc main() {}\t\n"), 0o644); err != nil {
+			t.Fatalf("os.WriteFile() error = %v", err)
+		}
+
+		if err := processFile(path, Config{SkipUnchanged: true}); !errors.Is(err, ErrNoComments) {
+			t.Errorf("processFile() with stream threshold %d error = %v, want ErrNoComments", threshold, err)
+		}
+	}
+}
+
+func TestParseExtensions(t *testing.T) {
+	tests := []struct {
+		value string
+		want  []string
+		ok    bool
+	}{
+		{".go", []string{".go"}, true},
+		{".go, .ts,.tsx", []string{".go", ".ts", ".tsx"}, true},
+		{".venv", []string{".venv"}, true},
+		{"vendor", nil, false},
+		{"*.pb.go", nil, false},
+		{".pb.go", nil, false},
+		{".go,vendor", nil, false},
+		{".", nil, false},
+		{"", nil, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseExtensions(tt.value)
+		if ok != tt.ok || !slices.Equal(got, tt.want) {
+			t.Errorf("parseExtensions(%q) = %v, %v, want %v, %v", tt.value, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestRunMissingClaude(t *testing.T) {
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatalf("filepath.EvalSymlinks() error = %v", err)
+	}
+	t.Chdir(dir)
+
+	const content = "package main // comment\n"
+	file := filepath.Join(dir, "main.go")
+	if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+		t.Fatalf("os.WriteFile() error = %v", err)
+	}
+
+	config := Config{
+		Files:     []string{file},
+		BatchSize: 10,
+		Prompt:    "{basename}",
+		ClaudeBin: filepath.Join(dir, "no-such-claude"),
+		CacheFile: filepath.Join(dir, "cache.json"),
+		NoFormat:  true,
+	}
+	result, err := run(config, newReporter(config, io.Discard, io.Discard))
+	if err == nil || !strings.Contains(err.Error(), "-claude-bin") {
+		t.Fatalf("run() error = %v, want a missing Claude CLI error", err)
+	}
+	if len(result.Processed) > 0 || len(result.Failed) > 0 {
+		t.Errorf("run() result = %+v, want no files handled", result)
+	}
+	// The check happens before processFile, so the comment is still there
+	if data, _ := os.ReadFile(file); string(data) != content {
+		t.Errorf("file = %q, want it untouched", data)
+	}
+
+	// Dry runs never start Claude, so they don't need it
+	config.DryRun = true
+	if _, err := run(config, newReporter(config, io.Discard, io.Discard)); err != nil {
+		t.Errorf("run() with -dry-run error = %v", err)
+	}
+}
+
+func TestRunSafeRestoresUncommentedFiles(t *testing.T) {
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatalf("filepath.EvalSymlinks() error = %v", err)
+	}
+	t.Chdir(dir)
+
+	const content = "package main // comment\n"
+	// Files are commented in sorted order, one at a time
+	good := filepath.Join(dir, "a_good.go")
+	bad := filepath.Join(dir, "b_bad.go")
+	later := filepath.Join(dir, "c_later.go")
+
+	// The fake Claude edits the bad file partway and then fails, so with -fail-fast the
+	// later file never runs
+	bin := writeFakeClaude(t, dir, `case "$*" in *b_bad.go*) echo "// half done" >> "`+bad+`"; echo "invalid file" >&2; exit 1;; esac
+`)
+
+	for _, safe := range []bool{false, true} {
+		for _, file := range []string{good, bad, later} {
+			if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+				t.Fatalf("os.WriteFile() error = %v", err)
+			}
+		}
+		config := Config{
+			Files:        []string{good, bad, later},
+			BatchSize:    2,
+			Concurrency:  1,
+			FailFast:     true,
+			Prompt:       "{basename}",
+			ClaudeBin:    bin,
+			CacheFile:    filepath.Join(dir, "cache.json"),
+			ForceProcess: true,
+			NoFormat:     true,
+			Safe:         safe,
+		}
+		if _, err := run(config, newReporter(config, io.Discard, io.Discard)); err == nil {
+			t.Fatalf("run() error = nil, want failure")
+		}
+
+		want := map[string]string{good: "package main\n", bad: content, later: content}
+		if !safe {
+			// Without -safe, failed and skipped files are left with their comments removed
+			want = map[string]string{good: "package main\n", bad: "package main\n// half done\n", later: "package main\n"}
+		}
+		for file, content := range want {
+			if data, _ := os.ReadFile(file); string(data) != content {
+				t.Errorf("safe=%v: %s = %q, want %q", safe, filepath.Base(file), data, content)
+			}
+		}
+	}
+}
+
+func TestRunResult(t *testing.T) {
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatalf("filepath.EvalSymlinks() error = %v", err)
+	}
+	t.Chdir(dir)
+
+	write := func(name string) string {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("os.MkdirAll() error = %v", err)
+		}
+		if err := os.WriteFile(path, []byte("package main // comment\n"), 0o644); err != nil {
+			t.Fatalf("os.WriteFile() error = %v", err)
+		}
+		return path
+	}
+	good := write("good.go")
+	bad := write("bad.go")
+	notes := write("notes.txt")
+	backup := write(filepath.Join(backupDirName, "old.go"))
+	missing := filepath.Join(dir, "missing.go")
+	image := filepath.Join(dir, "image.go")
+	if err := os.WriteFile(image, []byte("\x89PNG\r\n\x1a\n\x00"), 0o644); err != nil {
+		t.Fatalf("os.WriteFile() error = %v", err)
+	}
+
+	// The fake Claude fails for bad.go only; its prompt is the file name
+	config := Config{
+		Files:     []string{good, bad, notes, backup, missing, image},
+		BatchSize: 10,
+		Prompt:    "{basename}",
+		ClaudeBin: writeFakeClaude(t, dir, `case "$*" in *bad.go*) echo "invalid file" >&2; exit 1;; esac
+`),
+		CacheFile: filepath.Join(dir, "cache.json"),
+	}
+	result, err := run(config, newReporter(config, os.Stdout, os.Stderr))
+	if err == nil {
+		t.Fatalf("run() error = nil, want batch failure")
+	}
+
+	if !slices.Equal(result.Processed, []string{good}) {
+		t.Errorf("Processed = %v, want [%s]", result.Processed, good)
+	}
+	wantSkipped := []SkippedFile{{notes, skipUnsupported}, {backup, skipGitIgnored}, {image, skipBinary}}
+	if !slices.Equal(result.Skipped, wantSkipped) {
+		t.Errorf("Skipped = %v, want %v", result.Skipped, wantSkipped)
+	}
+	// Removal failures are recorded as they happen, before Claude runs
+	if len(result.Failed) != 2 || result.Failed[0].File != missing || result.Failed[1].File != bad {
+		t.Fatalf("Failed = %v, want %s then %s", result.Failed, missing, bad)
+	}
+	for _, failed := range result.Failed {
+		if failed.Err == nil {
+			t.Errorf("Failed entry for %s has no error", failed.File)
+		}
+	}
+
+	stats := result.Stats()
+	if stats.Processed != 1 || stats.Skipped != 3 || stats.Failed != 2 {
+		t.Errorf("Stats() = %d processed, %d skipped, %d failed, want 1, 3, 2", stats.Processed, stats.Skipped, stats.Failed)
+	}
+	if _, ok := result.Usage[good]; !ok {
+		t.Errorf("Usage = %v, want an entry for %s", result.Usage, good)
+	}
+}
+
+func TestCommentFilesQuietShowsBackendErrors(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "notes.txt")
+	if err := os.WriteFile(file, nil, 0o644); err != nil {
+		t.Fatalf("os.WriteFile() error = %v", err)
+	}
+
+	for _, stream := range []bool{false, true} {
+		t.Run(fmt.Sprintf("stream=%v", stream), func(t *testing.T) {
+			config := Config{
+				Prompt:    "{filename}",
+				LogLevel:  levelQuiet,
+				Stream:    stream,
+				ClaudeBin: writeFakeClaude(t, dir, "echo progress\necho 'API Error: 401' >&2\nexit 1\n"),
+			}
+
+			var stdout, stderr bytes.Buffer
+			if _, err := commentFiles([]string{file}, config, claudeCommenters(config), newReporter(config, &stdout, &stderr), nil); err == nil {
+				t.Fatal("commentFiles() error = nil, want the backend failure")
+			}
+			if !strings.Contains(stderr.String(), "API Error: 401") {
+				t.Errorf("stderr = %q, want the backend error", stderr.String())
+			}
+			if stdout.Len() != 0 {
+				t.Errorf("stdout = %q, want nothing with -quiet", stdout.String())
+			}
+		})
+	}
+}
+
+func TestCommentFilesBufferedOutput(t *testing.T) {
+	dir := t.TempDir()
+	var files []string
+	for _, name := range []string{"a.txt", "b.txt"} {
+		file := filepath.Join(dir, name)
+		if err := os.WriteFile(file, nil, 0o644); err != nil {
+			t.Fatalf("os.WriteFile() error = %v", err)
+		}
+		files = append(files, file)
+	}
+
+	// Both fake runs print, pause, then print again, so live output would interleave
+	config := Config{
+		Prompt: "{filename}",
+		ClaudeBin: writeFakeClaude(t, dir, `for arg; do file=$arg; done
+echo "start $file"
+sleep 0.2
+echo "end $file" >&2
+`),
+	}
+
+	stdout, err := os.CreateTemp(dir, "stdout")
+	if err != nil {
+		t.Fatalf("os.CreateTemp() error = %v", err)
+	}
+	defer stdout.Close()
+	_, err = commentFiles(files, config, claudeCommenters(config), newReporter(config, stdout, os.Stderr), nil)
+	if err != nil {
+		t.Fatalf("commentFiles() error = %v", err)
+	}
+
+	data, err := os.ReadFile(stdout.Name())
+	if err != nil {
+		t.Fatalf("os.ReadFile() error = %v", err)
+	}
+
+	// Each file's lines must form a single contiguous block
+	var order []string
+	for line := range strings.Lines(string(data)) {
+		for _, name := range []string{"a.txt", "b.txt"} {
+			if strings.Contains(line, name) && (len(order) == 0 || order[len(order)-1] != name) {
+				order = append(order, name)
+			}
+		}
+	}
+	if len(order) != 2 {
+		t.Errorf("output is interleaved:\n%s", data)
+	}
+	for _, want := range []string{"start " + files[0], "start " + files[1]} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("output is missing %q:\n%s", want, data)
+		}
+	}
+	// The backend's stderr is flushed to stderr rather than mixed into stdout
+	if strings.Contains(string(data), "end ") {
+		t.Errorf("stdout holds stderr output:\n%s", data)
+	}
+}
+
+// recordingCommenter is a Commenter that records the files and prompts it receives.
+type recordingCommenter struct {
+	mu      sync.Mutex
+	prompts map[string][]string
+}
+
+func (r *recordingCommenter) Comment(ctx context.Context, file, prompt string) (Usage, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.prompts[file] = append(r.prompts[file], prompt)
+	return Usage{}, nil
+}
+
+func TestProcessBatchesCallsCommenterOncePerFile(t *testing.T) {
+	dir := t.TempDir()
+
+	var files []string
+	for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+		file := filepath.Join(dir, name)
+		if err := os.WriteFile(file, nil, 0o644); err != nil {
+			t.Fatalf("os.WriteFile() error = %v", err)
+		}
+		files = append(files, file)
+	}
+
+	cache, err := loadCache(filepath.Join(dir, "cache.json"))
+	if err != nil {
+		t.Fatalf("loadCache() error = %v", err)
+	}
+
+	commenter := &recordingCommenter{prompts: make(map[string][]string)}
+	config := Config{BatchSize: 2, Prompt: "Comment {filename}"}
+	_, err = processBatches(files, config, cache, func(stdout, stderr io.Writer) Commenter {
+		return commenter
+	}, newReporter(config, os.Stdout, os.Stderr))
+	if err != nil {
+		t.Fatalf("processBatches() error = %v", err)
+	}
+
+	if len(commenter.prompts) != len(files) {
+		t.Errorf("Comment() called for %d files, want %d", len(commenter.prompts), len(files))
+	}
+	for _, file := range files {
+		want := []string{"Comment " + file}
+		if got := commenter.prompts[file]; len(got) != 1 || got[0] != want[0] {
+			t.Errorf("Comment() prompts for %s = %q, want %q", file, got, want)
+		}
+	}
+}
+
+func TestProcessBatchesStats(t *testing.T) {
+	dir := t.TempDir()
+
+	var files []string
+	for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+		file := filepath.Join(dir, name)
+		if err := os.WriteFile(file, nil, 0o644); err != nil {
+			t.Fatalf("os.WriteFile() error = %v", err)
+		}
+		files = append(files, file)
+	}
+
+	cache, err := loadCache(filepath.Join(dir, "cache.json"))
+	if err != nil {
+		t.Fatalf("loadCache() error = %v", err)
+	}
+
+	// Each run reports usage like claude --output-format json does
+	config := Config{
+		BatchSize: 2,
+		ClaudeBin: writeFakeClaude(t, dir, `echo '{"type":"result","result":"done","total_cost_usd":0.25,"usage":{"input_tokens":100,"cache_read_input_tokens":20,"output_tokens":30}}'
+`),
+	}
+	stats, err := processBatches(files, config, cache, claudeCommenters(config), newReporter(config, os.Stdout, os.Stderr))
+	if err != nil {
+		t.Fatalf("processBatches() error = %v", err)
+	}
+
+	if stats.Processed != 3 || stats.Failed != 0 {
+		t.Errorf("stats = %d processed, %d failed, want 3 processed, 0 failed", stats.Processed, stats.Failed)
+	}
+	want := Usage{InputTokens: 360, OutputTokens: 90, CostUSD: 0.75}
+	if stats.Total != want {
+		t.Errorf("stats.Total = %+v, want %+v", stats.Total, want)
+	}
+	if got := stats.Usage[files[1]]; got.InputTokens != 120 || got.OutputTokens != 30 {
+		t.Errorf("stats.Usage[%s] = %+v, want 120 input and 30 output tokens", files[1], got)
+	}
+
+	// A failing file is counted and the processed ones are still reported
+	config.ClaudeBin = writeFakeClaude(t, dir, "exit 1\n")
+	stats, err = processBatches(files[:1], config, cache, claudeCommenters(config), newReporter(config, os.Stdout, os.Stderr))
+	if err == nil {
+		t.Fatalf("processBatches() error = nil, want error")
+	}
+	if stats.Processed != 0 || stats.Failed != 1 {
+		t.Errorf("stats = %d processed, %d failed, want 0 processed, 1 failed", stats.Processed, stats.Failed)
+	}
+}
+
+func TestBackupAndRestore(t *testing.T) {
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatalf("filepath.EvalSymlinks() error = %v", err)
+	}
+	t.Chdir(dir)
+
+	if _, err := findGitRoot(); err == nil {
+		t.Skip("temp directory is inside a git repository")
+	}
+
+	original := "package main // comment\n"
+	file := filepath.Join(dir, "cmd", "main.go")
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		t.Fatalf("os.MkdirAll() error = %v", err)
+	}
+	if err := os.WriteFile(file, []byte(original), 0o644); err != nil {
+		t.Fatalf("os.WriteFile() error = %v", err)
+	}
+
+	if err := processFile(file, Config{Backup: true}); err != nil {
+		t.Fatalf("processFile() error = %v", err)
+	}
+
+	backupPath := filepath.Join(dir, backupDirName, "cmd", "main.go")
+	data, err := os.ReadFile(backupPath)
+	if err != nil {
+		t.Fatalf("backup was not created: %v", err)
+	}
+	if string(data) != original {
+		t.Errorf("backup content = %q, want %q", string(data), original)
+	}
+
+	// Backups must never be picked up as inputs
+	if !isGitIgnored(backupPath) {
+		t.Errorf("isGitIgnored(%s) = false, want true", backupPath)
+	}
+	files, err := expandDirectories([]string{dir}, Config{})
+	if err != nil {
+		t.Fatalf("expandDirectories() error = %v", err)
+	}
+	if len(files) != 1 || files[0] != file {
+		t.Errorf("expandDirectories() = %v, want only %s", files, file)
+	}
+
+	restored, err := restoreBackups()
+	if err != nil {
+		t.Fatalf("restoreBackups() error = %v", err)
+	}
+	if restored != 1 {
+		t.Errorf("restoreBackups() restored %d files, want 1", restored)
+	}
+	if data, _ := os.ReadFile(file); string(data) != original {
+		t.Errorf("restored content = %q, want %q", string(data), original)
+	}
+	if _, err := os.Stat(filepath.Join(dir, backupDirName)); !os.IsNotExist(err) {
+		t.Errorf("backup directory still exists after restore")
+	}
+
+	// Restoring again with no backups is a no-op
+	if restored, err := restoreBackups(); err != nil || restored != 0 {
+		t.Errorf("restoreBackups() without backups = %d, %v, want 0, nil", restored, err)
+	}
+}
+
+func TestCommentFilesConcurrency(t *testing.T) {
+	dir := t.TempDir()
+	running := filepath.Join(dir, "running")
+	if err := os.Mkdir(running, 0o755); err != nil {
+		t.Fatalf("os.Mkdir() error = %v", err)
+	}
+	counts := filepath.Join(dir, "counts")
+
+	var files []string
+	for i := range 6 {
+		file := filepath.Join(dir, fmt.Sprintf("file%d.txt", i))
+		if err := os.WriteFile(file, nil, 0o644); err != nil {
+			t.Fatalf("os.WriteFile() error = %v", err)
+		}
+		files = append(files, file)
+	}
+
+	// Each run registers itself, records how many runs are active, and lingers so
+	// that runs overlap whenever the pool allows it
+	config := Config{
+		Concurrency: 2,
+		ClaudeBin: writeFakeClaude(t, dir, `touch "`+running+`/$$"
+ls "`+running+`" | wc -l >> "`+counts+`"
+sleep 0.1
+rm "`+running+`/$$"
+`),
+	}
+	if _, err := commentFiles(files, config, claudeCommenters(config), newReporter(config, os.Stdout, os.Stderr), nil); err != nil {
+		t.Fatalf("commentFiles() error = %v", err)
+	}
+
+	data, err := os.ReadFile(counts)
+	if err != nil {
+		t.Fatalf("os.ReadFile() error = %v", err)
+	}
+	lines := strings.Fields(string(data))
+	if len(lines) != len(files) {
+		t.Fatalf("fake claude ran %d times, want %d", len(lines), len(files))
+	}
+	for _, line := range lines {
+		if n, err := strconv.Atoi(line); err != nil || n > config.Concurrency {
+			t.Errorf("%s runs were active at once, want at most %d", line, config.Concurrency)
+		}
+	}
+}
+
+func TestCommentFilesFailFast(t *testing.T) {
+	dir := t.TempDir()
+	var files []string
+	for _, name := range []string{"slow1.txt", "bad.txt", "slow2.txt", "slow3.txt"} {
+		file := filepath.Join(dir, name)
+		if err := os.WriteFile(file, nil, 0o644); err != nil {
+			t.Fatalf("os.WriteFile() error = %v", err)
+		}
+		files = append(files, file)
+	}
+
+	// The bad file fails once the others are underway, which would otherwise run for 30s
+	config := Config{
+		Prompt:   "{filename}",
+		FailFast: true,
+		ClaudeBin: writeFakeClaude(t, dir, `case "$*" in *bad.txt*) sleep 0.2; echo "invalid file" >&2; exit 1;; esac
+sleep 30
+`),
+	}
+
+	start := time.Now()
+	stats, err := commentFiles(files, config, claudeCommenters(config), newReporter(config, io.Discard, io.Discard), nil)
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("commentFiles() returned after %s, want prompt return after the first failure", elapsed)
+	}
+	if err == nil || !strings.Contains(err.Error(), files[1]+": ") {
+		t.Fatalf("commentFiles() error = %v, want failure for %s", err, files[1])
+	}
+
+	if len(stats.Usage) > 0 {
+		t.Errorf("Usage = %v, want no file completed", stats.Usage)
+	}
+	for _, file := range []string{files[0], files[2], files[3]} {
+		if fileErr, ok := stats.Errors[file]; ok && !strings.Contains(fileErr.Error(), "canceled") {
+			t.Errorf("Errors[%s] = %v, want a cancellation", file, fileErr)
+		}
+	}
+}
+
+func TestProcessBatchesDelay(t *testing.T) {
+	dir := t.TempDir()
+
+	var files []string
+	for i := range 5 {
+		file := filepath.Join(dir, fmt.Sprintf("file%d.txt", i))
+		if err := os.WriteFile(file, nil, 0o644); err != nil {
+			t.Fatalf("os.WriteFile() error = %v", err)
+		}
+		files = append(files, file)
+	}
+
+	cache, err := loadCache(filepath.Join(dir, "cache.json"))
+	if err != nil {
+		t.Fatalf("loadCache() error = %v", err)
+	}
+
+	var delays []time.Duration
+	t.Cleanup(func() { sleep = time.Sleep })
+	sleep = func(d time.Duration) { delays = append(delays, d) }
+
+	commenter := &recordingCommenter{prompts: make(map[string][]string)}
+	config := Config{BatchSize: 2, BatchDelay: time.Minute}
+	if _, err := processBatches(files, config, cache, func(stdout, stderr io.Writer) Commenter {
+		return commenter
+	}, newReporter(config, os.Stdout, os.Stderr)); err != nil {
+		t.Fatalf("processBatches() error = %v", err)
+	}
+
+	// Three batches need a pause before the second and third, but none after the last
+	if len(delays) != 2 || delays[0] != time.Minute || delays[1] != time.Minute {
+		t.Errorf("processBatches() slept %v, want [1m0s 1m0s]", delays)
+	}
+}
+
+// commenterFunc adapts a function to the Commenter interface.
+type commenterFunc func(ctx context.Context, file, prompt string) (Usage, error)
+
+func (f commenterFunc) Comment(ctx context.Context, file, prompt string) (Usage, error) {
+	return f(ctx, file, prompt)
+}
+
+func TestProcessBatchesDecouplesCacheSavesFromConcurrency(t *testing.T) {
+	dir := t.TempDir()
+	var files []string
+	for i := range 5 {
+		file := filepath.Join(dir, fmt.Sprintf("file%d.txt", i))
+		if err := os.WriteFile(file, nil, 0o644); err != nil {
+			t.Fatalf("os.WriteFile() error = %v", err)
+		}
+		files = append(files, file)
+	}
+	cachePath := filepath.Join(dir, "cache.json")
+	cache, err := loadCache(cachePath)
+	if err != nil {
+		t.Fatalf("loadCache() error = %v", err)
+	}
+
+	// One file at a time, each run records how many files the saved cache holds
+	var saved []int
+	config := Config{BatchSize: 2, Concurrency: 1}
+	_, err = processBatches(files, config, cache, func(stdout, stderr io.Writer) Commenter {
+		return commenterFunc(func(ctx context.Context, file, prompt string) (Usage, error) {
+			onDisk, err := loadCache(cachePath)
+			if err != nil {
+				return Usage{}, err
+			}
+			saved = append(saved, len(onDisk.ProcessedFiles))
+			return Usage{}, nil
+		})
+	}, newReporter(config, io.Discard, io.Discard))
+	if err != nil {
+		t.Fatalf("processBatches() error = %v", err)
+	}
+
+	// The cache is saved after every second file, and once more for the last one
+	if want := []int{0, 0, 2, 2, 4}; !slices.Equal(saved, want) {
+		t.Errorf("saved cache sizes seen by each file = %v, want %v", saved, want)
+	}
+	if onDisk, err := loadCache(cachePath); err != nil || len(onDisk.ProcessedFiles) != len(files) {
+		t.Errorf("final cache holds %d files (error %v), want %d", len(onDisk.ProcessedFiles), err, len(files))
+	}
+
+	// Concurrency isn't bounded by the batch size, and files from later batches start as
+	// soon as a slot frees up
+	var active, peak atomic.Int32
+	config = Config{BatchSize: 2, Concurrency: 3}
+	_, err = processBatches(files, config, cache, func(stdout, stderr io.Writer) Commenter {
+		return commenterFunc(func(ctx context.Context, file, prompt string) (Usage, error) {
+			n := active.Add(1)
+			defer active.Add(-1)
+			for {
+				old := peak.Load()
+				if n <= old || peak.CompareAndSwap(old, n) {
+					break
+				}
+			}
+			time.Sleep(50 * time.Millisecond)
+			return Usage{}, nil
+		})
+	}, newReporter(config, io.Discard, io.Discard))
+	if err != nil {
+		t.Fatalf("processBatches() error = %v", err)
+	}
+	if got := peak.Load(); got != 3 {
+		t.Errorf("at most %d files ran at once, want 3", got)
+	}
+}
+
+func TestLoadPrompt(t *testing.T) {
+	dir := t.TempDir()
+	promptFile := filepath.Join(dir, "prompt.txt")
+	if err := os.WriteFile(promptFile, []byte("Comment {filename} from a file\n"), 0o644); err != nil {
+		t.Fatalf("os.WriteFile() error = %v", err)
+	}
+	emptyFile := filepath.Join(dir, "empty.txt")
+	if err := os.WriteFile(emptyFile, []byte("\n"), 0o644); err != nil {
+		t.Fatalf("os.WriteFile() error = %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		prompt     string
+		promptSet  bool
+		promptFile string
+		stdin      string
+		want       string
+		wantErr    bool
+	}{
+		{name: "default", prompt: "default", want: "default"},
+		{name: "file replaces default", prompt: "default", promptFile: promptFile, want: "Comment {filename} from a file\n"},
+		{name: "explicit prompt wins over file", prompt: "explicit", promptSet: true, promptFile: promptFile, want: "explicit"},
+		{name: "stdin", prompt: "-", promptSet: true, stdin: "Comment {filename} from stdin\n", want: "Comment {filename} from stdin\n"},
+		{name: "empty stdin", prompt: "-", promptSet: true, stdin: "  \n", wantErr: true},
+		{name: "empty file", prompt: "default", promptFile: emptyFile, wantErr: true},
+		{name: "missing file", prompt: "default", promptFile: filepath.Join(dir, "missing.txt"), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := loadPrompt(tt.prompt, tt.promptSet, tt.promptFile, strings.NewReader(tt.stdin))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadPrompt() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("loadPrompt() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatCommand(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     string
+		config   Config
+		expected []string
+	}{
+		{
+			name:     "default",
+			file:     "main.go",
+			expected: []string{"gofmt", "-w", "main.go"},
+		},
+		{
+			name:     "default with arguments before the file",
+			file:     "src/Main.jl",
+			expected: []string{"julia", "-e", "using JuliaFormatter; format_file(ARGS[1])", "src/Main.jl"},
+		},
+		{
+			name:     "override",
+			file:     "main.go",
+			config:   Config{Formatters: map[string][]string{"go": {"gofumpt", "-w"}}},
+			expected: []string{"gofumpt", "-w", "main.go"},
+		},
+		{
+			name:     "override leaves other languages alone",
+			file:     "app.py",
+			config:   Config{Formatters: map[string][]string{"go": {"gofumpt", "-w"}}},
+			expected: []string{"ruff", "format", "app.py"},
+		},
+		{
+			name:   "disabled language",
+			file:   "app.py",
+			config: Config{Formatters: map[string][]string{"python": nil}},
+		},
+		{
+			name:   "no-format",
+			file:   "main.go",
+			config: Config{NoFormat: true},
+		},
+		{
+			name: "no formatter",
+			file: "Dockerfile",
+		},
+		{
+			name: "unsupported",
+			file: "README.txt",
+		},
+	}
+
+	// Every formatter is installed
+	original := lookPath
+	lookPath = func(file string) (string, error) { return "/usr/bin/" + file, nil }
+	defer func() { lookPath = original }()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := formatCommand(tt.file, tt.config)
+			if tt.expected == nil {
+				if cmd != nil {
+					t.Errorf("formatCommand() = %v, want nil", cmd.Args)
+				}
+				return
+			}
+			if cmd == nil {
+				t.Fatalf("formatCommand() = nil, want %v", tt.expected)
+			}
+			if !slices.Equal(cmd.Args, tt.expected) {
+				t.Errorf("formatCommand() = %v, want %v", cmd.Args, tt.expected)
+			}
+		})
+	}
+}
+
+func TestFormatCommandFallback(t *testing.T) {
+	original := lookPath
+	lookPath = func(file string) (string, error) { return "", exec.ErrNotFound }
+	defer func() { lookPath = original }()
+
+	cmd := formatCommand("main.go", Config{})
+	if want := []string{"go", "fmt", "main.go"}; cmd == nil || !slices.Equal(cmd.Args, want) {
+		t.Errorf("formatCommand() without gofmt = %v, want %v", cmd, want)
+	}
+	cmd = formatCommand("api.proto", Config{})
+	if want := []string{"clang-format", "-i", "api.proto"}; cmd == nil || !slices.Equal(cmd.Args, want) {
+		t.Errorf("formatCommand() without buf = %v, want %v", cmd, want)
+	}
+	cmd = formatCommand("default.nix", Config{})
+	if want := []string{"alejandra", "default.nix"}; cmd == nil || !slices.Equal(cmd.Args, want) {
+		t.Errorf("formatCommand() without nixpkgs-fmt = %v, want %v", cmd, want)
+	}
+
+	lookPath = func(file string) (string, error) { return "/usr/bin/" + file, nil }
+	cmd = formatCommand("api.proto", Config{})
+	if want := []string{"buf", "format", "-w", "api.proto"}; cmd == nil || !slices.Equal(cmd.Args, want) {
+		t.Errorf("formatCommand() with buf = %v, want %v", cmd, want)
+	}
+	lookPath = func(file string) (string, error) { return "", exec.ErrNotFound }
+
+	// An explicit formatter is used as given
+	cmd = formatCommand("main.go", Config{Formatters: map[string][]string{"go": {"gofmt", "-s", "-w"}}})
+	if want := []string{"gofmt", "-s", "-w", "main.go"}; cmd == nil || !slices.Equal(cmd.Args, want) {
+		t.Errorf("formatCommand() with override = %v, want %v", cmd, want)
+	}
+}
+
+func TestListLanguages(t *testing.T) {
+	var out bytes.Buffer
+	config := Config{Formatters: map[string][]string{stripper.Go: {"gofumpt", "-w"}}}
+	if err := listLanguages(&out, config); err != nil {
+		t.Fatalf("listLanguages() error = %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	rows := make(map[string][]string)
+	var order []string
+	for _, line := range lines[1:] {
+		fields := strings.Fields(line)
+		rows[fields[0]] = fields
+		order = append(order, fields[0])
+	}
+	if !slices.IsSorted(order) {
+		t.Errorf("extensions are not sorted: %v", order)
+	}
+
+	for _, lang := range stripper.Languages {
+		for _, ext := range stripper.Extensions(lang) {
+			if row, ok := rows[ext]; !ok || row[1] != stripper.Name(lang) {
+				t.Errorf("listLanguages() row for %s = %v, want language %s", ext, row, stripper.Name(lang))
+			}
+		}
+	}
+	if _, ok := rows["Dockerfile"]; !ok {
+		t.Errorf("listLanguages() is missing Dockerfile:\n%s", out.String())
+	}
+	if _, ok := rows[".json"]; ok {
+		t.Errorf("listLanguages() lists .json without -jsonc")
+	}
+	if want := []string{".go", "Go", "gofumpt", "-w"}; !slices.Equal(rows[".go"], want) {
+		t.Errorf("listLanguages() row for .go = %v, want %v", rows[".go"], want)
+	}
+	if want := []string{".dockerfile", "Dockerfile", "none"}; !slices.Equal(rows[".dockerfile"], want) {
+		t.Errorf("listLanguages() row for .dockerfile = %v, want %v", rows[".dockerfile"], want)
+	}
+}
+
+func TestRenderPrompt(t *testing.T) {
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatalf("filepath.EvalSymlinks() error = %v", err)
+	}
+	t.Chdir(dir)
+
+	if _, err := findGitRoot(); err == nil {
+		t.Skip("temp directory is inside a git repository")
+	}
+
+	file := filepath.Join(dir, "services", "api", "handler.ts")
+
+	tests := []struct {
+		prompt string
+		want   string
+	}{
+		{"{filename}", file},
+		{"{basename}", "handler.ts"},
+		{"{ext}", "ts"},
+		{"{reldir}", filepath.Join("services", "api")},
+		{"{language}", "TypeScript"},
+		{"Comment the {language} in {basename}, then re-read {basename}", "Comment the TypeScript in handler.ts, then re-read handler.ts"},
+		{"{unknown} stays", "{unknown} stays"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.prompt, func(t *testing.T) {
+			if got := renderPrompt(file, Config{Prompt: tt.prompt}); got != tt.want {
+				t.Errorf("renderPrompt() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// Files at the root have no directory of their own
+	if got := renderPrompt(filepath.Join(dir, "Dockerfile"), Config{Prompt: "{reldir} {language}"}); got != ". Dockerfile" {
+		t.Errorf("renderPrompt() = %q, want %q", got, ". Dockerfile")
+	}
+}
+
+func TestCheckFiles(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"commented.go": "package main\n\n// main does nothing\nfunc main() {}\n",
+		"clean.go":     "package main\n\n\n\nfunc main() {}   \n",
+		"clean.py":     "s = \"# not a comment\"\n",
+		"commented.py": "x = 1  # one\n",
+		"notes.txt":    "# unsupported\n",
+	}
+	var paths []string
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("os.WriteFile() error = %v", err)
+		}
+		paths = append(paths, path)
+	}
+	slices.Sort(paths)
+
+	got, err := checkFiles(paths, Config{})
+	if err != nil {
+		t.Fatalf("checkFiles() error = %v", err)
+	}
+	want := []string{filepath.Join(dir, "commented.go"), filepath.Join(dir, "commented.py")}
+	if !slices.Equal(got, want) {
+		t.Errorf("checkFiles() = %v, want %v", got, want)
+	}
+
+	// Checking never modifies the files
+	for name, content := range files {
+		if data, _ := os.ReadFile(filepath.Join(dir, name)); string(data) != content {
+			t.Errorf("%s was modified to %q", name, string(data))
+		}
+	}
+}
+
+func TestCheckFilesSelectsLikeRun(t *testing.T) {
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatalf("filepath.EvalSymlinks() error = %v", err)
+	}
+	if err := os.Mkdir(filepath.Join(dir, ".git"), 0o755); err != nil {
+		t.Fatalf("os.Mkdir() error = %v", err)
+	}
+	t.Chdir(dir)
+
+	for _, name := range []string{"main.go", filepath.Join("vendor", "lib.go"), "api_test.go", "tool.py", "exclude.txt"} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("os.MkdirAll() error = %v", err)
+		}
+		content := "package main // comment\n"
+		if name == "exclude.txt" {
+			content = "vendor/\n"
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("os.WriteFile() error = %v", err)
+		}
+	}
+
+	config := Config{
+		Exclude:     []string{"*_test.go"},
+		ExcludeFrom: filepath.Join(dir, "exclude.txt"),
+		Include:     []string{".go"},
+	}
+	files, err := expandDirectories([]string{dir}, config)
+	if err != nil {
+		t.Fatalf("expandDirectories() error = %v", err)
+	}
+	got, err := checkFiles(files, config)
+	if err != nil {
+		t.Fatalf("checkFiles() error = %v", err)
+	}
+	if want := []string{filepath.Join(dir, "main.go")}; !slices.Equal(got, want) {
+		t.Errorf("checkFiles() = %v, want %v", got, want)
+	}
+}
+
+func TestProcessFileKeepHeader(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     string
+		input    string
+		expected string
+	}{
+		{
+			name: "go",
+			file: "main.go",
+			input: `// SPDX-License-Identifier: Apache-2.0
+// Copyright 2024 The Authors
+
+// Package main does things.
+package main
+
+func main() {} // entry point
+`,
+			expected: `// SPDX-License-Identifier: Apache-2.0
+// Copyright 2024 The Authors
+
+package main
+
+func main() {}
+`,
+		},
+		{
+			name: "python",
+			file: "tool.py",
+			input: `#!/usr/bin/env python3
+# SPDX-License-Identifier: MIT
+# Copyright 2024 The Authors
+
+import os  # for paths
+
+# entry point
+def main():
+    pass
+`,
+			expected: `#!/usr/bin/env python3
+# SPDX-License-Identifier: MIT
+# Copyright 2024 The Authors
+
+import os
+
+def main():
+    pass
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			if err := os.WriteFile(path, []byte(tt.input), 0o644); err != nil {
+				t.Fatalf("os.WriteFile() error = %v", err)
+			}
+
+			if err := processFile(path, Config{KeepHeader: true, CollapseNewlines: true}); err != nil {
+				t.Fatalf("processFile() error = %v", err)
+			}
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("os.ReadFile() error = %v", err)
+			}
+			if string(data) != tt.expected {
+				t.Errorf("processFile() wrote:\n%s\nwant:\n%s", string(data), tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+//go:build !unix
+
+package main
+
+import (
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// killProcessGroupOnCancel leaves the default cancellation in place, which kills
+// only the direct child, as process groups are a Unix concept.
+func killProcessGroupOnCancel(cmd *exec.Cmd) {}
+
+// lockFile treats f as held while it names a running process, since flock is a Unix
+// concept. A lockfile naming a process that has exited is taken over.
+func lockFile(f *os.File) error {
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return err
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil
+	}
+	if p, err := os.FindProcess(pid); err == nil {
+		p.Release()
+		return errLocked
+	}
+	return nil
+}
@@ -0,0 +1,345 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+const (
+	outputText = "text"
+	outputJSON = "json"
+)
+
+// logLevel is how much progress a run prints, chosen with -quiet and -verbose. The zero
+// value is the normal level.
+type logLevel int
+
+const (
+	// levelQuiet prints only problems and the final summary
+	levelQuiet logLevel = iota - 1
+	levelNormal
+	// levelVerbose also prints each file's formatter and backend steps
+	levelVerbose
+)
+
+// logger writes progress lines up to its level and drops the rest.
+type logger struct {
+	w     io.Writer
+	level logLevel
+}
+
+// printf writes a line if level is enabled.
+func (l logger) printf(level logLevel, format string, args ...any) {
+	if level <= l.level {
+		fmt.Fprintf(l.w, format+"\n", args...)
+	}
+}
+
+// writer returns w if level is enabled, or a writer that drops everything.
+func (l logger) writer(level logLevel) io.Writer {
+	if level <= l.level {
+		return l.w
+	}
+	return io.Discard
+}
+
+// Reporter receives the progress events of a run and presents them. Events may arrive
+// from several goroutines at once.
+type Reporter interface {
+	// Progressf reports a progress message that isn't about a single file's outcome
+	Progressf(format string, args ...any)
+	// Warnf reports a problem that doesn't change any file's outcome
+	Warnf(format string, args ...any)
+	// Removed reports that comments were removed from file
+	Removed(file string)
+	// Cached reports that file was marked as processed without processing it
+	Cached(file string)
+	// Skipped reports that file was left alone; reason is "gitignored", "unchanged",
+	// "unsupported", "binary", or "uncommented"
+	Skipped(file, reason string)
+	// Failed reports that file could not be processed
+	Failed(file string, err error)
+	// Commented reports that the backend commented file
+	Commented(file string, usage Usage, elapsed time.Duration)
+	// Log is where the per-file backend output goes; it drops everything in quiet mode
+	Log() io.Writer
+	// ErrLog is where the per-file backend errors, retry notices, and formatter warnings
+	// go; like Warnf, it is written at every log level
+	ErrLog() io.Writer
+	// Finish reports the outcome of the whole run
+	Finish(result RunResult, err error)
+}
+
+// newReporter returns the reporter for the -output format.
+func newReporter(config Config, stdout, stderr io.Writer) Reporter {
+	if config.Output == outputJSON {
+		return newJSONReporter(stdout, stderr, config.DryRun, config.LogLevel)
+	}
+	// Cache-only and dry runs never reach the backend, so they have no summary to show
+	return &textReporter{
+		stdout:   stdout,
+		stderr:   stderr,
+		progress: logger{stdout, config.LogLevel},
+		summary:  !config.CacheOnly && !config.DryRun,
+	}
+}
+
+// textReporter prints human-readable lines: progress to stdout, problems to stderr.
+// Problems and the summary are printed at every log level.
+type textReporter struct {
+	mu       sync.Mutex
+	stdout   io.Writer
+	stderr   io.Writer
+	progress logger
+	summary  bool
+}
+
+func (r *textReporter) Progressf(format string, args ...any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.progress.printf(levelNormal, format, args...)
+}
+
+func (r *textReporter) Warnf(format string, args ...any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	fmt.Fprintf(r.stderr, "Warning: "+format+"\n", args...)
+}
+
+func (r *textReporter) Removed(file string) {
+	r.Progressf("Removed comments from: %s", file)
+}
+
+func (r *textReporter) Cached(file string) {
+	r.Progressf("Cached: %s", file)
+}
+
+func (r *textReporter) Skipped(file, reason string) {
+	if reason == skipUncommented {
+		reason = "no comments to regenerate"
+	}
+	r.Progressf("Skipping (%s): %s", reason, file)
+}
+
+func (r *textReporter) Failed(file string, err error) {
+	r.Warnf("failed to process %s: %v", file, err)
+}
+
+// Commented prints nothing since the backend output already ends with the file's usage.
+func (r *textReporter) Commented(file string, usage Usage, elapsed time.Duration) {}
+
+func (r *textReporter) Log() io.Writer {
+	return r.progress.writer(levelNormal)
+}
+
+func (r *textReporter) ErrLog() io.Writer {
+	return r.stderr
+}
+
+func (r *textReporter) Finish(result RunResult, err error) {
+	if !r.summary || len(result.Processed)+len(result.Failed) == 0 {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	result.Stats().print(r.stdout)
+}
+
+// Statuses of a file in the JSON report.
+const (
+	statusProcessed = "processed"
+	statusSkipped   = "skipped"
+	statusFailed    = "failed"
+)
+
+// jsonReport is the document printed by -output json.
+type jsonReport struct {
+	Files  []*jsonFile `json:"files"`
+	Totals jsonTotals  `json:"totals"`
+	// Error is the error that ended the run, if any
+	Error string `json:"error,omitempty"`
+}
+
+// jsonFile is the outcome of one file. Status is "processed", "skipped" for unchanged
+// files, "unsupported", "binary", "uncommented" for files without comments to
+// regenerate, "gitignored", or "failed".
+type jsonFile struct {
+	File         string  `json:"file"`
+	Status       string  `json:"status"`
+	Error        string  `json:"error,omitempty"`
+	DurationMS   int64   `json:"duration_ms,omitempty"`
+	InputTokens  int     `json:"input_tokens,omitempty"`
+	OutputTokens int     `json:"output_tokens,omitempty"`
+	CostUSD      float64 `json:"cost_usd,omitempty"`
+}
+
+type jsonTotals struct {
+	Processed    int     `json:"processed"`
+	Skipped      int     `json:"skipped"`
+	Unsupported  int     `json:"unsupported"`
+	Binary       int     `json:"binary"`
+	Uncommented  int     `json:"uncommented"`
+	GitIgnored   int     `json:"gitignored"`
+	Failed       int     `json:"failed"`
+	InputTokens  int     `json:"input_tokens"`
+	OutputTokens int     `json:"output_tokens"`
+	CostUSD      float64 `json:"cost_usd"`
+	// TokensEstimated is set when some token counts were estimated
+	TokensEstimated bool  `json:"tokens_estimated"`
+	DurationMS      int64 `json:"duration_ms"`
+}
+
+// jsonReporter collects each file's outcome and prints them as one JSON document to
+// stdout when the run finishes. Free-text progress goes to stderr so stdout stays
+// parseable.
+type jsonReporter struct {
+	mu       sync.Mutex
+	stdout   io.Writer
+	stderr   io.Writer
+	progress logger
+	dryRun   bool
+	start    time.Time
+	files    []*jsonFile
+	byName   map[string]*jsonFile
+}
+
+func newJSONReporter(stdout, stderr io.Writer, dryRun bool, level logLevel) *jsonReporter {
+	return &jsonReporter{
+		stdout:   stdout,
+		stderr:   stderr,
+		progress: logger{stderr, level},
+		dryRun:   dryRun,
+		start:    time.Now(),
+		byName:   make(map[string]*jsonFile),
+	}
+}
+
+// file returns the entry for name, adding it in first-seen order. The caller must hold mu.
+func (r *jsonReporter) file(name string) *jsonFile {
+	f, ok := r.byName[name]
+	if !ok {
+		f = &jsonFile{File: name}
+		r.byName[name] = f
+		r.files = append(r.files, f)
+	}
+	return f
+}
+
+func (r *jsonReporter) Progressf(format string, args ...any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.progress.printf(levelNormal, format, args...)
+}
+
+func (r *jsonReporter) Warnf(format string, args ...any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	fmt.Fprintf(r.stderr, "Warning: "+format+"\n", args...)
+}
+
+// Removed only settles the outcome of dry runs; otherwise the backend's outcome follows.
+func (r *jsonReporter) Removed(file string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	f := r.file(file)
+	if r.dryRun {
+		f.Status = statusProcessed
+	}
+}
+
+func (r *jsonReporter) Cached(file string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.file(file).Status = statusProcessed
+}
+
+func (r *jsonReporter) Skipped(file, reason string) {
+	status := reason
+	if reason == skipUnchanged {
+		status = statusSkipped
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.file(file).Status = status
+}
+
+func (r *jsonReporter) Failed(file string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	f := r.file(file)
+	f.Status = statusFailed
+	f.Error = err.Error()
+}
+
+func (r *jsonReporter) Commented(file string, usage Usage, elapsed time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	f := r.file(file)
+	f.Status = statusProcessed
+	f.DurationMS = elapsed.Milliseconds()
+	f.InputTokens = usage.InputTokens
+	f.OutputTokens = usage.OutputTokens
+	f.CostUSD = usage.CostUSD
+}
+
+func (r *jsonReporter) Log() io.Writer {
+	return r.progress.writer(levelNormal)
+}
+
+func (r *jsonReporter) ErrLog() io.Writer {
+	return r.stderr
+}
+
+func (r *jsonReporter) Finish(result RunResult, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	report := jsonReport{
+		// An empty list rather than null keeps consumers simple
+		Files: append([]*jsonFile{}, r.files...),
+		Totals: jsonTotals{
+			InputTokens:     result.Total.InputTokens,
+			OutputTokens:    result.Total.OutputTokens,
+			CostUSD:         result.Total.CostUSD,
+			TokensEstimated: result.Total.Estimated,
+			DurationMS:      time.Since(r.start).Milliseconds(),
+		},
+	}
+	for _, f := range r.files {
+		// Files that hadn't started when -fail-fast canceled the run never reach the backend
+		if f.Status == "" {
+			f.Status = statusFailed
+			f.Error = "not processed: run canceled by -fail-fast"
+		}
+
+		switch f.Status {
+		case statusProcessed:
+			report.Totals.Processed++
+		case statusSkipped:
+			report.Totals.Skipped++
+		case skipUnsupported:
+			report.Totals.Unsupported++
+		case skipBinary:
+			report.Totals.Binary++
+		case skipUncommented:
+			report.Totals.Uncommented++
+		case skipGitIgnored:
+			report.Totals.GitIgnored++
+		case statusFailed:
+			report.Totals.Failed++
+		}
+	}
+	if err != nil {
+		report.Error = err.Error()
+	}
+
+	encoder := json.NewEncoder(r.stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		fmt.Fprintf(r.stderr, "Warning: failed to write JSON report: %v\n", err)
+	}
+}
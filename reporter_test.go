@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestJSONReporter(t *testing.T) {
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatalf("filepath.EvalSymlinks() error = %v", err)
+	}
+	t.Chdir(dir)
+
+	write := func(name string) string {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("os.MkdirAll() error = %v", err)
+		}
+		if err := os.WriteFile(path, []byte("package main // comment\n"), 0o644); err != nil {
+			t.Fatalf("os.WriteFile() error = %v", err)
+		}
+		return path
+	}
+	good := write("good.go")
+	bad := write("bad.go")
+	notes := write("notes.txt")
+	backup := write(filepath.Join(backupDirName, "old.go"))
+
+	// The fake Claude fails for bad.go only; its prompt is the file name
+	config := Config{
+		Files:     []string{good, bad, notes, backup},
+		BatchSize: 10,
+		Prompt:    "{basename}",
+		ClaudeBin: writeFakeClaude(t, dir, `case "$*" in *bad.go*) echo "invalid file" >&2; exit 1;; esac
+`),
+		CacheFile: filepath.Join(dir, "cache.json"),
+		Output:    outputJSON,
+	}
+
+	var stdout bytes.Buffer
+	reporter := newReporter(config, &stdout, io.Discard)
+	result, err := run(config, reporter)
+	reporter.Finish(result, err)
+
+	var report struct {
+		Files []struct {
+			File        string `json:"file"`
+			Status      string `json:"status"`
+			Error       string `json:"error"`
+			InputTokens int    `json:"input_tokens"`
+		} `json:"files"`
+		Totals struct {
+			Processed   int `json:"processed"`
+			Skipped     int `json:"skipped"`
+			Unsupported int `json:"unsupported"`
+			GitIgnored  int `json:"gitignored"`
+			Failed      int `json:"failed"`
+			InputTokens int `json:"input_tokens"`
+		} `json:"totals"`
+		Error string `json:"error"`
+	}
+	// Progress goes elsewhere, so stdout holds nothing but the report
+	if err := json.Unmarshal(stdout.Bytes(), &report); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v\n%s", err, stdout.String())
+	}
+
+	statuses := make(map[string]string)
+	for _, f := range report.Files {
+		statuses[f.File] = f.Status
+		switch f.File {
+		case good:
+			if f.InputTokens == 0 {
+				t.Errorf("%s has no input tokens", f.File)
+			}
+		case bad:
+			if !strings.Contains(f.Error, "exit status 1") {
+				t.Errorf("%s error = %q, want the Claude failure", f.File, f.Error)
+			}
+		}
+	}
+	want := map[string]string{good: "processed", bad: "failed", notes: "unsupported", backup: "gitignored"}
+	for file, status := range want {
+		if statuses[file] != status {
+			t.Errorf("status of %s = %q, want %q", file, statuses[file], status)
+		}
+	}
+
+	totals := report.Totals
+	if totals.Processed != 1 || totals.Skipped != 0 || totals.Unsupported != 1 || totals.GitIgnored != 1 || totals.Failed != 1 {
+		t.Errorf("totals = %+v, want 1 processed, 1 unsupported, 1 gitignored, 1 failed", totals)
+	}
+	if totals.InputTokens == 0 {
+		t.Errorf("totals have no input tokens")
+	}
+	if !strings.Contains(report.Error, "bad.go") {
+		t.Errorf("error = %q, want the batch failure", report.Error)
+	}
+}
+
+func TestTextReporterLogLevels(t *testing.T) {
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatalf("filepath.EvalSymlinks() error = %v", err)
+	}
+	t.Chdir(dir)
+
+	runAt := func(level logLevel) string {
+		file := filepath.Join(dir, "main.go")
+		if err := os.WriteFile(file, []byte("package main // comment\n"), 0o644); err != nil {
+			t.Fatalf("os.WriteFile() error = %v", err)
+		}
+		config := Config{
+			Files:     []string{file},
+			BatchSize: 1,
+			Prompt:    "{basename}",
+			ClaudeBin: writeFakeClaude(t, dir, "echo claude output\n"),
+			CacheFile: filepath.Join(dir, "cache.json"),
+			// The cache would skip the file on the second run
+			ForceProcess: true,
+			NoFormat:     true,
+			LogLevel:     level,
+		}
+
+		var stdout bytes.Buffer
+		reporter := newReporter(config, &stdout, io.Discard)
+		result, err := run(config, reporter)
+		reporter.Finish(result, err)
+		if err != nil {
+			t.Fatalf("run() error = %v", err)
+		}
+		return stdout.String()
+	}
+
+	tests := []struct {
+		level  logLevel
+		want   []string
+		absent []string
+	}{
+		{levelQuiet, []string{"Summary: 1 processed"}, []string{"Removed comments from", "Processing batch", "claude output", "Completed"}},
+		{levelNormal, []string{"Removed comments from", "claude output", "Completed", "Summary: 1 processed"}, []string{"Requesting comments"}},
+		{levelVerbose, []string{"Removed comments from", "Adding comments", "Requesting comments (attempt 1/1)", "Completed", "Summary: 1 processed"}, nil},
+	}
+	for _, tt := range tests {
+		output := runAt(tt.level)
+		for _, want := range tt.want {
+			if !strings.Contains(output, want) {
+				t.Errorf("level %d output is missing %q:\n%s", tt.level, want, output)
+			}
+		}
+		for _, absent := range tt.absent {
+			if strings.Contains(output, absent) {
+				t.Errorf("level %d output contains %q:\n%s", tt.level, absent, output)
+			}
+		}
+	}
+}
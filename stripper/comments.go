@@ -0,0 +1,254 @@
+package stripper
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// excessiveNewlines matches runs of two or more blank lines.
+var excessiveNewlines = regexp.MustCompile(`\n{3,}`)
+
+// splitShebang separates a leading "#!" interpreter line (including its newline) from
+// the rest of content. Languages that use # for comments would otherwise strip the
+// shebang, leaving scripts that can no longer be executed directly.
+func splitShebang(content string) (shebang, rest string) {
+	if !strings.HasPrefix(content, "#!") {
+		return "", content
+	}
+
+	if idx := strings.IndexByte(content, '\n'); idx != -1 {
+		return content[:idx+1], content[idx+1:]
+	}
+	return content, ""
+}
+
+// commentSyntax lists the comment markers of a language: the line comment markers and,
+// for languages that have them, the block comment delimiters.
+type commentSyntax struct {
+	line       []string
+	blockOpen  string
+	blockClose string
+}
+
+// splitHeader separates the comment block at the top of content, such as a license
+// header, from the rest. The header is the run of comment lines right at the start,
+// after an optional shebang, up to the first line that is blank or holds code. A block
+// comment only counts when nothing but whitespace follows it on its closing line.
+func splitHeader(content string, syntax commentSyntax) (header, rest string) {
+	shebang, body := splitShebang(content)
+
+	pos := 0
+	for pos < len(body) {
+		next := len(body)
+		if idx := strings.IndexByte(body[pos:], '\n'); idx != -1 {
+			next = pos + idx + 1
+		}
+		line := strings.TrimLeft(body[pos:next], " \t")
+
+		if syntax.blockOpen != "" && strings.HasPrefix(line, syntax.blockOpen) {
+			open := next - len(line)
+			closeIdx := strings.Index(body[open+len(syntax.blockOpen):], syntax.blockClose)
+			if closeIdx == -1 {
+				break
+			}
+			end := open + len(syntax.blockOpen) + closeIdx + len(syntax.blockClose)
+
+			afterEnd := len(body)
+			if idx := strings.IndexByte(body[end:], '\n'); idx != -1 {
+				afterEnd = end + idx + 1
+			}
+			if strings.TrimSpace(body[end:afterEnd]) != "" {
+				break
+			}
+			pos = afterEnd
+			continue
+		}
+
+		if !slices.ContainsFunc(syntax.line, func(marker string) bool {
+			return strings.HasPrefix(line, marker)
+		}) {
+			break
+		}
+		pos = next
+	}
+
+	return shebang + body[:pos], body[pos:]
+}
+
+// markerIndex returns the index in line of marker when line starts with a comment, in
+// syntax, whose text starts with marker after the comment opener and any spaces, or -1.
+func markerIndex(line string, syntax commentSyntax, marker string) int {
+	openers := syntax.line
+	if syntax.blockOpen != "" {
+		openers = append(slices.Clip(openers), syntax.blockOpen)
+	}
+
+	indent := len(line) - len(strings.TrimLeft(line, " \t"))
+	for _, opener := range openers {
+		text, ok := strings.CutPrefix(line[indent:], opener)
+		if !ok {
+			continue
+		}
+		trimmed := strings.TrimLeft(text, " \t")
+		if strings.HasPrefix(trimmed, marker) {
+			return len(line) - len(trimmed)
+		}
+	}
+	return -1
+}
+
+// directiveKeeper returns a keep function for the removers that preserve selected
+// comments. It keeps comments whose text, after one of markers and any spaces, starts
+// with one of directives, such as "go:" for //go:generate or "eslint-" for
+// /* eslint-disable */.
+func directiveKeeper(directives []string, markers ...string) func(comment string) bool {
+	return func(comment string) bool {
+		for _, marker := range markers {
+			text, found := strings.CutPrefix(comment, marker)
+			if !found {
+				continue
+			}
+
+			text = strings.TrimLeft(text, " \t")
+			for _, directive := range directives {
+				if strings.HasPrefix(text, directive) {
+					return true
+				}
+			}
+			return false
+		}
+		return false
+	}
+}
+
+// keepAny returns a keep function that keeps a comment when any of keeps does.
+func keepAny(keeps ...func(comment string) bool) func(comment string) bool {
+	return func(comment string) bool {
+		return slices.ContainsFunc(keeps, func(keep func(string) bool) bool {
+			return keep(comment)
+		})
+	}
+}
+
+// trimsEveryLine holds the languages whose removers trim the trailing whitespace of every
+// line, comment or not. They all keep the line structure, which lets
+// restoreTrailingWhitespace undo the trimming for Options.KeepTrailingWhitespace.
+var trimsEveryLine = map[string]bool{
+	Go: true, Java: true, JavaScript: true, TypeScript: true, Python: true, Rust: true, YAML: true,
+}
+
+// restoreTrailingWhitespace puts back the trailing whitespace that was trimmed from the
+// lines of src that lost nothing else in cleaned, line i of cleaned being what remains
+// of line i of src.
+func restoreTrailingWhitespace(src, cleaned string) string {
+	srcLines := strings.Split(src, "\n")
+	lines := strings.Split(cleaned, "\n")
+	if len(lines) != len(srcLines) {
+		return cleaned
+	}
+
+	for i, line := range lines {
+		if line != srcLines[i] && line == strings.TrimRight(srcLines[i], " \t") {
+			lines[i] = srcLines[i]
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// CollapseExcessiveNewlines removes the blank lines left at the top of a file when a leading
+// comment block is stripped and collapses runs of more than one blank line to exactly one.
+// It works on the text as a whole, so blank lines inside multi-line strings are collapsed too;
+// Options.CollapseNewlines only collapses the blank lines that removed comments left.
+func CollapseExcessiveNewlines(content string) string {
+	content = strings.TrimLeft(content, "\n")
+	return excessiveNewlines.ReplaceAllString(content, "\n\n")
+}
+
+// collapseRemovedLines collapses the blank lines of cleaned, which is src with its comments
+// removed, like CollapseExcessiveNewlines, but only in runs holding a line that a removed
+// comment left blank. Runs of blank lines that src already had, such as those in a Go raw
+// string, a Python docstring, or a YAML block scalar, are kept as they are.
+func collapseRemovedLines(src, cleaned string) string {
+	lines := strings.Split(cleaned, "\n")
+	removed := removedLines(src, lines)
+	if removed == nil {
+		return cleaned
+	}
+
+	// The empty string after a final newline ends the text rather than being a blank line
+	n := len(lines)
+	if strings.HasSuffix(cleaned, "\n") {
+		n--
+	}
+
+	kept := make([]string, 0, len(lines))
+	for i := 0; i < n; {
+		if lines[i] != "" {
+			kept = append(kept, lines[i])
+			i++
+			continue
+		}
+
+		end, fromComment := i, false
+		for end < n && lines[end] == "" {
+			fromComment = fromComment || removed[end]
+			end++
+		}
+		switch {
+		case !fromComment:
+			kept = append(kept, lines[i:end]...)
+		case i > 0:
+			// Blank lines left at the top of the file are dropped altogether
+			kept = append(kept, "")
+		}
+		i = end
+	}
+	return strings.Join(append(kept, lines[n:]...), "\n")
+}
+
+// removedLines reports for each of lines, the lines of src with its comments removed,
+// whether it is blank because a comment was removed from it. Most removers keep the line
+// structure, so line i of the result comes from line i of src. The others join the lines
+// a block comment spans, so their lines are matched to src by finding their bytes in src
+// in order. It returns nil if lines can't be matched to src that way.
+func removedLines(src string, lines []string) []bool {
+	removed := make([]bool, len(lines))
+	srcLines := strings.Split(src, "\n")
+	if len(srcLines) == len(lines) {
+		for i, line := range lines {
+			removed[i] = line == "" && srcLines[i] != ""
+		}
+		return removed
+	}
+
+	pos := 0
+	for i, line := range lines {
+		start := pos
+		for j := range len(line) {
+			k := strings.IndexByte(src[pos:], line[j])
+			if k == -1 {
+				return nil
+			}
+			pos += k + 1
+		}
+		if i == len(lines)-1 {
+			break
+		}
+		k := strings.IndexByte(src[pos:], '\n')
+		if k == -1 {
+			return nil
+		}
+		removed[i] = line == "" && pos+k > start
+		pos += k + 1
+	}
+	return removed
+}
+
+// usesCRLF reports whether most line breaks in text are Windows-style "\r\n" rather
+// than bare "\n". The removers only understand "\n", so text that uses CRLF is
+// normalized before they run and converted back afterwards.
+func usesCRLF(text string) bool {
+	crlf := strings.Count(text, "\r\n")
+	return crlf > strings.Count(text, "\n")-crlf
+}
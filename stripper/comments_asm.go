@@ -0,0 +1,124 @@
+package stripper
+
+import (
+	"unicode"
+)
+
+// The assembly dialects accepted by Options.AsmDialect.
+const (
+	// AsmGAS is the x86 syntax of the GNU assembler, including .S files run through the
+	// C preprocessor
+	AsmGAS = "gas"
+	// AsmNASM is the syntax of the Netwide Assembler
+	AsmNASM = "nasm"
+)
+
+// AsmDialects lists the supported assembly dialects.
+var AsmDialects = []string{AsmGAS, AsmNASM}
+
+// asmPreprocessorDirectives are the C preprocessor directives of .S files, which start
+// with '#' like GAS comments but must be kept.
+var asmPreprocessorDirectives = map[string]bool{
+	"include": true, "define": true, "undef": true, "if": true, "ifdef": true, "ifndef": true,
+	"elif": true, "else": true, "endif": true, "error": true, "warning": true, "pragma": true,
+	"line": true,
+}
+
+// RemoveAsmComments removes comments from assembly code written in dialect. In AsmGAS,
+// the default, these are # line comments and the // and /* */ comments of preprocessed
+// .S files, while preprocessor directives such as #include and #define are kept. In
+// AsmNASM they are ; line comments, since ';' separates statements in GAS. String
+// literals and character constants are preserved in both dialects.
+func RemoveAsmComments(code, dialect string) string {
+	nasm := dialect == AsmNASM
+	runes := []rune(code)
+	result := make([]rune, 0, len(runes))
+	// Whether only whitespace precedes runes[i] on its line, as for preprocessor directives
+	lineStart := true
+	i := 0
+
+	for i < len(runes) {
+		ch := runes[i]
+
+		switch {
+		case ch == '"' || nasm && (ch == '\'' || ch == '`'):
+			// GAS strings and NASM backquoted strings have backslash escapes; NASM's
+			// single- and double-quoted strings don't
+			end := asmStringEnd(runes, i, !nasm || ch == '`')
+			result = append(result, runes[i:end]...)
+			i = end
+			lineStart = false
+			continue
+		case !nasm && ch == '\'':
+			// GAS character constants have no closing quote: 'a, or '\n for escapes
+			end := min(i+2, len(runes))
+			if end < len(runes) && runes[i+1] == '\\' {
+				end++
+			}
+			if end > i+1 && runes[i+1] == '\n' {
+				end = i + 1
+			}
+			result = append(result, runes[i:end]...)
+			i = end
+			lineStart = false
+			continue
+		case nasm && ch == ';',
+			!nasm && ch == '#' && !(lineStart && isAsmPreprocessorDirective(runes, i)),
+			!nasm && ch == '/' && i+1 < len(runes) && runes[i+1] == '/':
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+			result = trimTrailingBlanks(result)
+			continue
+		case !nasm && ch == '/' && i+1 < len(runes) && runes[i+1] == '*':
+			i += 2
+			for i < len(runes) && (runes[i] != '*' || i+1 >= len(runes) || runes[i+1] != '/') {
+				i++
+			}
+			i = min(i+2, len(runes))
+			if i >= len(runes) || runes[i] == '\n' {
+				result = trimTrailingBlanks(result)
+			}
+			continue
+		}
+
+		if ch == '\n' {
+			lineStart = true
+		} else if !unicode.IsSpace(ch) {
+			lineStart = false
+		}
+		result = append(result, ch)
+		i++
+	}
+
+	return string(result)
+}
+
+// asmStringEnd returns the index just past the string literal starting at runes[i],
+// which ends at its closing quote, or unterminated, at the end of its line.
+func asmStringEnd(runes []rune, i int, escapes bool) int {
+	quote := runes[i]
+	for i++; i < len(runes) && runes[i] != '\n'; i++ {
+		switch {
+		case escapes && runes[i] == '\\' && i+1 < len(runes) && runes[i+1] != '\n':
+			i++
+		case runes[i] == quote:
+			return i + 1
+		}
+	}
+	return i
+}
+
+// isAsmPreprocessorDirective reports whether the '#' at runes[i] starts a C
+// preprocessor directive, such as "#include" or "# define".
+func isAsmPreprocessorDirective(runes []rune, i int) bool {
+	j := i + 1
+	for j < len(runes) && (runes[j] == ' ' || runes[j] == '\t') {
+		j++
+	}
+	start := j
+	for j < len(runes) && isAlphanumeric(runes[j]) {
+		j++
+	}
+	return asmPreprocessorDirectives[string(runes[start:j])]
+}
@@ -0,0 +1,100 @@
+package stripper
+
+import (
+	"testing"
+)
+
+func TestRemoveAsmComments(t *testing.T) {
+	tests := []struct {
+		name     string
+		dialect  string
+		input    string
+		expected string
+	}{
+		{
+			name:    "GAS line and block comments",
+			dialect: AsmGAS,
+			input: `# entry point
+	.globl _start   # exported
+_start:
+	movl $1, %eax // exit
+	/* status
+	   code */
+	xorl %ebx, %ebx /* inline */ ; int $0x80`,
+			expected: `
+	.globl _start
+_start:
+	movl $1, %eax
+
+	xorl %ebx, %ebx  ; int $0x80`,
+		},
+		{
+			name:    "GAS preprocessor directives",
+			dialect: AsmGAS,
+			input: `#include <asm/unistd.h>
+# define EXIT 60 /* syscall */
+#ifdef DEBUG # debug build
+	nop
+#endif
+	#notadirective`,
+			expected: `#include <asm/unistd.h>
+# define EXIT 60
+#ifdef DEBUG
+	nop
+#endif
+`,
+		},
+		{
+			name:     "GAS strings and character constants",
+			dialect:  AsmGAS,
+			input:    `msg: .asciz "a # b // c \" /* d" # comment` + "\n" + `	movb $'#, %al # hash` + "\n" + `	movb $'\n, %bl`,
+			expected: `msg: .asciz "a # b // c \" /* d"` + "\n" + `	movb $'#, %al` + "\n" + `	movb $'\n, %bl`,
+		},
+		{
+			name:     "GAS is the default dialect",
+			dialect:  "",
+			input:    "\tret # done\n",
+			expected: "\tret\n",
+		},
+		{
+			name:    "NASM line comments",
+			dialect: AsmNASM,
+			input: `; hello world
+section .text   ; code
+global _start
+_start:
+	mov eax, 1 ; exit
+	int 0x80`,
+			expected: `
+section .text
+global _start
+_start:
+	mov eax, 1
+	int 0x80`,
+		},
+		{
+			name:    "NASM strings with comment markers",
+			dialect: AsmNASM,
+			input: `msg db "a ; b", 'c;d', ` + "`e\\`;f`" + ` ; comment
+semi db ';' ; semicolon`,
+			expected: `msg db "a ; b", 'c;d', ` + "`e\\`;f`" + `
+semi db ';'`,
+		},
+		{
+			name:     "NASM leaves GAS comment markers alone",
+			dialect:  AsmNASM,
+			input:    `%define SIZE 16 # 2 ; size` + "\n" + `	mov eax, [ebx+SIZE/2] ; half`,
+			expected: `%define SIZE 16 # 2` + "\n" + `	mov eax, [ebx+SIZE/2]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := RemoveAsmComments(tt.input, tt.dialect)
+
+			if result != tt.expected {
+				t.Errorf("RemoveAsmComments() failed\nInput:\n%s\n\nExpected:\n%s\n\nGot:\n%s", tt.input, tt.expected, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package stripper
+
+import (
+	"regexp"
+	"strings"
+)
+
+// dockerParserDirective matches BuildKit parser directives such as "# syntax=docker/dockerfile:1"
+// and "# escape=`", which look like comments but change how the file is parsed.
+var dockerParserDirective = regexp.MustCompile(`(?i)^\s*#\s*(syntax|escape|check)\s*=`)
+
+// dockerEscapeDirective captures the line-continuation character set by "# escape=".
+var dockerEscapeDirective = regexp.MustCompile(`(?i)^\s*#\s*escape\s*=\s*(\S)`)
+
+// RemoveDockerfileComments removes # comments from a Dockerfile. Parser directives at the
+// top of the file and heredoc bodies are kept, and inline shell comments in RUN
+// instructions are removed only when the # is outside quotes.
+func RemoveDockerfileComments(content string) string {
+	var result strings.Builder
+	lines := strings.Split(content, "\n")
+
+	// Directives are only recognized before the first comment, blank line, or instruction
+	inDirectives := true
+	escapeChar := "\\"
+	// Set while a RUN instruction continues onto the next line
+	inRun := false
+	inContinuation := false
+	var pendingHeredocs []shellHeredoc
+
+	for i, line := range lines {
+		if len(pendingHeredocs) > 0 {
+			// Heredoc bodies are scripts or file contents, so # lines inside them are data
+			result.WriteString(line)
+			body := line
+			if pendingHeredocs[0].stripTabs {
+				body = strings.TrimLeft(body, "\t")
+			}
+			if body == pendingHeredocs[0].delimiter {
+				pendingHeredocs = pendingHeredocs[1:]
+			}
+			if i < len(lines)-1 {
+				result.WriteString("\n")
+			}
+			continue
+		}
+
+		trimmed := strings.TrimSpace(line)
+
+		if inDirectives {
+			if dockerParserDirective.MatchString(line) {
+				if m := dockerEscapeDirective.FindStringSubmatch(line); m != nil {
+					escapeChar = m[1]
+				}
+				result.WriteString(line)
+				if i < len(lines)-1 {
+					result.WriteString("\n")
+				}
+				continue
+			}
+			inDirectives = false
+		}
+
+		// Docker only treats # as a comment at the start of a line; comment lines are also
+		// allowed (and dropped by Docker) in the middle of a continued instruction, where
+		// they are dropped entirely since BuildKit warns about empty continuation lines
+		if strings.HasPrefix(trimmed, "#") {
+			if !inContinuation && i < len(lines)-1 {
+				result.WriteString("\n")
+			}
+			continue
+		}
+
+		if !inContinuation {
+			fields := strings.Fields(trimmed)
+			inRun = len(fields) > 0 && strings.EqualFold(fields[0], "RUN")
+		}
+
+		cleaned := line
+		if inRun {
+			cleaned = removeDockerRunComment(line, escapeChar)
+		}
+
+		pendingHeredocs = append(pendingHeredocs, findDockerHeredocs(cleaned)...)
+		inContinuation = strings.HasSuffix(strings.TrimRight(cleaned, " \t"), escapeChar)
+
+		result.WriteString(cleaned)
+		if i < len(lines)-1 {
+			result.WriteString("\n")
+		}
+	}
+
+	return result.String()
+}
+
+// removeDockerRunComment strips a trailing shell comment from one physical line of a RUN
+// instruction. If the comment ends with the line-continuation character the line is left
+// untouched, because the shell would see the next line as part of the same comment.
+func removeDockerRunComment(line, escapeChar string) string {
+	runes := []rune(line)
+	var quote rune
+
+	for j := 0; j < len(runes); j++ {
+		ch := runes[j]
+
+		if quote != 0 {
+			if ch == '\\' && quote == '"' {
+				j++
+				continue
+			}
+			if ch == quote {
+				quote = 0
+			}
+			continue
+		}
+
+		switch {
+		case ch == '\\':
+			j++
+		case ch == '"' || ch == '\'':
+			quote = ch
+		case ch == '#' && j > 0 && isShellWordBoundary(runes[j-1]):
+			if strings.HasSuffix(strings.TrimRight(string(runes[j:]), " \t"), escapeChar) {
+				return line
+			}
+			return strings.TrimRight(string(runes[:j]), " \t")
+		}
+	}
+
+	return line
+}
+
+// findDockerHeredocs returns the heredocs opened on a Dockerfile instruction line, in order.
+func findDockerHeredocs(line string) []shellHeredoc {
+	var heredocs []shellHeredoc
+	runes := []rune(line)
+
+	for j := 0; j+1 < len(runes); j++ {
+		if runes[j] != '<' || runes[j+1] != '<' {
+			continue
+		}
+		// Skip here-strings (<<<), which have no body
+		if j+2 < len(runes) && runes[j+2] == '<' {
+			j += 2
+			continue
+		}
+		if heredoc, end, ok := parseShellHeredoc(runes, j+2); ok {
+			heredocs = append(heredocs, heredoc)
+			j = end - 1
+		}
+	}
+
+	return heredocs
+}
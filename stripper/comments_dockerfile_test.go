@@ -0,0 +1,122 @@
+package stripper
+
+import (
+	"testing"
+)
+
+func TestRemoveDockerfileComments(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name: "parser directives preserved",
+			input: `# syntax=docker/dockerfile:1
+# escape=\
+# regular comment
+FROM alpine`,
+			expected: `# syntax=docker/dockerfile:1
+# escape=\
+
+FROM alpine`,
+		},
+		{
+			// Directives are only honored at the very top of the file
+			name: "directive after instruction is a comment",
+			input: `FROM alpine
+# syntax=docker/dockerfile:1`,
+			expected: `FROM alpine
+`,
+		},
+		{
+			name: "hash inside quoted RUN argument",
+			input: `RUN echo "#keep" && echo '#also' # strip me
+ENV COLOR=#fff`,
+			expected: `RUN echo "#keep" && echo '#also'
+ENV COLOR=#fff`,
+		},
+		{
+			// Only RUN is run by a shell, so # in other instructions is a literal argument
+			name:     "hash in non-RUN instruction",
+			input:    `LABEL description="x" # not stripped`,
+			expected: `LABEL description="x" # not stripped`,
+		},
+		{
+			name: "comment lines inside continuation",
+			input: `RUN apt-get update \
+    # install curl
+    && apt-get install -y curl # inline`,
+			expected: `RUN apt-get update \
+    && apt-get install -y curl`,
+		},
+		{
+			name: "comment lines between continued RUN lines",
+			input: `# build
+RUN set -e; \
+    # fetch sources
+    # and unpack them
+    curl -o src.tgz "$URL"; \
+    tar xzf src.tgz
+# done
+CMD ["app"]`,
+			expected: `
+RUN set -e; \
+    curl -o src.tgz "$URL"; \
+    tar xzf src.tgz
+
+CMD ["app"]`,
+		},
+		{
+			name: "heredoc body preserved",
+			input: `RUN <<EOF
+# part of the script
+echo hi
+EOF
+# removed`,
+			expected: `RUN <<EOF
+# part of the script
+echo hi
+EOF
+`,
+		},
+		{
+			// With a backtick escape, a trailing backtick continues the RUN line
+			name:     "custom escape character",
+			input:    "# escape=`\nRUN echo a `\n    && echo b # c",
+			expected: "# escape=`\nRUN echo a `\n    && echo b",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := RemoveDockerfileComments(tt.input)
+
+			if result != tt.expected {
+				t.Errorf("RemoveDockerfileComments() failed\nInput:\n%s\n\nExpected:\n%s\n\nGot:\n%s", tt.input, tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestIsDockerfile(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected bool
+	}{
+		{"Dockerfile", true},
+		{"build/Dockerfile", true},
+		{"Dockerfile.dev", true},
+		{"api.dockerfile", true},
+		{"Dockerfile_notes.md", false},
+		{"main.go", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := isDockerfile(tt.path); got != tt.expected {
+				t.Errorf("isDockerfile(%q) = %v, want %v", tt.path, got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package stripper
+
+import (
+	"unicode"
+)
+
+// elixirSigilClosers maps the bracket-style sigil delimiters to their closing character;
+// every other delimiter closes with itself.
+var elixirSigilClosers = map[rune]rune{'(': ')', '[': ']', '{': '}', '<': '>'}
+
+// RemoveElixirComments removes # comments from Elixir code while preserving strings,
+// charlists, heredocs, sigils, ?# character literals, and #{...} interpolation.
+func RemoveElixirComments(code string) string {
+	shebang, code := splitShebang(code)
+	runes := []rune(code)
+	result, _ := stripElixirCode(runes, 0, []rune(shebang), false)
+	return string(result)
+}
+
+// stripElixirCode copies code from runes[i:] to result with comments removed. When
+// inInterpolation is set it stops at the '}' that closes the enclosing #{...} and returns
+// its index, so that strings nested inside interpolations are parsed as code.
+func stripElixirCode(runes []rune, i int, result []rune, inInterpolation bool) ([]rune, int) {
+	braceDepth := 0
+
+	for i < len(runes) {
+		ch := runes[i]
+
+		switch {
+		case ch == '}' && inInterpolation && braceDepth == 0:
+			return result, i
+		case ch == '{':
+			braceDepth++
+		case ch == '}':
+			braceDepth--
+		case ch == '"' || ch == '\'':
+			delimiter := string(ch)
+			if i+2 < len(runes) && runes[i+1] == ch && runes[i+2] == ch {
+				delimiter = string([]rune{ch, ch, ch})
+			}
+			result, i = copyElixirString(runes, i+len(delimiter), append(result, []rune(delimiter)...), delimiter, true)
+			continue
+		case ch == '~' && i+2 < len(runes) && unicode.IsLetter(runes[i+1]):
+			if next, end, ok := copyElixirSigil(runes, i, result); ok {
+				result, i = next, end
+				continue
+			}
+		case ch == '?' && i+1 < len(runes) && (i == 0 || !isElixirIdentChar(runes[i-1])):
+			// ?# is the codepoint of '#', and ?\# its escaped form; neither starts a comment
+			end := i + 2
+			if runes[i+1] == '\\' && end < len(runes) {
+				end++
+			}
+			result = append(result, runes[i:end]...)
+			i = end
+			continue
+		case ch == '#':
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+			result = trimTrailingBlanks(result)
+			continue
+		}
+
+		result = append(result, ch)
+		i++
+	}
+
+	return result, i
+}
+
+// copyElixirSigil copies the sigil starting at runes[i] (e.g. ~r/a#b/i or ~s"""...""")
+// and returns the new result and the index just past it, including trailing modifiers.
+func copyElixirSigil(runes []rune, i int, result []rune) ([]rune, int, bool) {
+	start := i
+	j := i + 1
+
+	// Sigil names are a single lowercase letter or one or more uppercase letters
+	for j < len(runes) && unicode.IsLetter(runes[j]) {
+		j++
+	}
+	if j >= len(runes) {
+		return result, start, false
+	}
+
+	name := runes[i+1 : j]
+	// Only lowercase sigils interpolate; uppercase sigils such as ~S and ~R are verbatim
+	interpolate := unicode.IsLower(name[0])
+
+	open := runes[j]
+	delimiter := string(open)
+	if closer, ok := elixirSigilClosers[open]; ok {
+		delimiter = string(closer)
+	} else if open == '"' || open == '\'' {
+		if j+2 < len(runes) && runes[j+1] == open && runes[j+2] == open {
+			delimiter = string([]rune{open, open, open})
+		}
+	} else if open != '/' && open != '|' {
+		return result, start, false
+	}
+
+	// Bracket closers are a single character, so the opener is always as long as the closer
+	openLen := len([]rune(delimiter))
+
+	result = append(result, runes[start:j+openLen]...)
+	result, j = copyElixirString(runes, j+openLen, result, delimiter, interpolate)
+
+	// Modifiers such as the i in ~r/foo/i follow the closing delimiter
+	for j < len(runes) && unicode.IsLetter(runes[j]) {
+		result = append(result, runes[j])
+		j++
+	}
+
+	return result, j, true
+}
+
+// copyElixirString copies string content from runes[i:] up to and including the closing
+// delimiter. A backslash always protects the next character so an escaped delimiter never
+// closes the string, and #{...} interpolations are parsed as code when interpolate is set.
+func copyElixirString(runes []rune, i int, result []rune, delimiter string, interpolate bool) ([]rune, int) {
+	closing := []rune(delimiter)
+
+	for i < len(runes) {
+		ch := runes[i]
+
+		if ch == '\\' && i+1 < len(runes) {
+			result = append(result, ch, runes[i+1])
+			i += 2
+			continue
+		}
+
+		if interpolate && ch == '#' && i+1 < len(runes) && runes[i+1] == '{' {
+			result = append(result, '#', '{')
+			result, i = stripElixirCode(runes, i+2, result, true)
+			if i < len(runes) {
+				result = append(result, runes[i])
+				i++
+			}
+			continue
+		}
+
+		if i+len(closing) <= len(runes) && string(runes[i:i+len(closing)]) == delimiter {
+			result = append(result, closing...)
+			return result, i + len(closing)
+		}
+
+		result = append(result, ch)
+		i++
+	}
+
+	return result, i
+}
+
+func isElixirIdentChar(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '?' || r == '!'
+}
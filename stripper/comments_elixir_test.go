@@ -0,0 +1,90 @@
+package stripper
+
+import (
+	"testing"
+)
+
+func TestRemoveElixirComments(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name: "line comments",
+			input: `# module comment
+defmodule A do # trailing
+end`,
+			expected: `
+defmodule A do
+end`,
+		},
+		{
+			name: "shebang preserved",
+			input: `#!/usr/bin/env elixir
+IO.puts("hi") # greet`,
+			expected: `#!/usr/bin/env elixir
+IO.puts("hi")`,
+		},
+		{
+			name:     "interpolation is not a comment",
+			input:    `"Hello #{name} # still string" # comment`,
+			expected: `"Hello #{name} # still string"`,
+		},
+		{
+			name:     "nested string inside interpolation",
+			input:    `"a #{Map.get(m, "#key")} b" # comment`,
+			expected: `"a #{Map.get(m, "#key")} b"`,
+		},
+		{
+			name: "heredoc with hash",
+			input: `@doc """
+Returns the # of items.
+"""
+def count, do: 0 # comment`,
+			expected: `@doc """
+Returns the # of items.
+"""
+def count, do: 0`,
+		},
+		{
+			name:     "charlist",
+			input:    `'# not a comment' # comment`,
+			expected: `'# not a comment'`,
+		},
+		{
+			name:     "regex sigil with hash",
+			input:    `Regex.match?(~r/#\d+/i, s) # comment`,
+			expected: `Regex.match?(~r/#\d+/i, s)`,
+		},
+		{
+			name: "heredoc sigil",
+			input: `~s"""
+# kept
+""" # comment`,
+			expected: `~s"""
+# kept
+"""`,
+		},
+		{
+			name:     "bracket sigils",
+			input:    `~w(a #b c) ++ ~S{#{not_interpolated}} # comment`,
+			expected: `~w(a #b c) ++ ~S{#{not_interpolated}}`,
+		},
+		{
+			name:     "character literal",
+			input:    `c = ?# # comment`,
+			expected: `c = ?#`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := RemoveElixirComments(tt.input)
+
+			if result != tt.expected {
+				t.Errorf("RemoveElixirComments() failed\nInput:\n%s\n\nExpected:\n%s\n\nGot:\n%s", tt.input, tt.expected, result)
+			}
+		})
+	}
+}
@@ -1,18 +1,57 @@
-package main
+package stripper
 
 import (
+	"io"
+	"iter"
+	"slices"
 	"strings"
 )
 
-func removeGoComments(content string) string {
+// goDirectives are the default -keep-directives prefixes for Go: compiler directives
+// such as //go:build and //go:generate, legacy // +build constraints, and linter
+// suppressions.
+var goDirectives = []string{"go:", "+build", "nolint", "lint:ignore"}
+
+// RemoveGoComments removes line and block comments from Go source, preserving string,
+// rune, and raw string literals.
+func RemoveGoComments(content string) string {
+	return removeGoCommentsKeeping(content, nil, false)
+}
+
+// removeGoCommentsKeeping removes comments like RemoveGoComments, except the // comments
+// for which keep returns true. keep receives the comment from its // opener to the end
+// of the line; Go directives are always line comments, so block comments are never kept.
+// A nil keep removes every comment. With keepDocs, doc comments of top-level declarations
+// are kept too (see goDocCommentLines).
+func removeGoCommentsKeeping(content string, keep func(comment string) bool, keepDocs bool) string {
+	var docLines map[int]bool
+	if keepDocs {
+		docLines = goDocCommentLines(strings.Split(content, "\n"))
+	}
+
 	var result strings.Builder
-	lines := strings.Split(content, "\n")
+	removeGoCommentLines(&result, splitLines(content), keep, docLines)
+	return result.String()
+}
 
+// removeGoCommentLines writes lines to result with comments removed like
+// removeGoCommentsKeeping, keeping the lines whose indexes are in docLines as is.
+func removeGoCommentLines(result io.StringWriter, lines iter.Seq2[string, bool], keep func(comment string) bool, docLines map[int]bool) {
 	// Track state across lines since Go supports multi-line raw strings and block comments
 	inBlockComment := false
 	inRawStringMultiline := false
 
-	for i, line := range lines {
+	i := -1
+	for line, newline := range lines {
+		i++
+		if docLines[i] && !inRawStringMultiline && !inBlockComment {
+			result.WriteString(line)
+			if newline {
+				result.WriteString("\n")
+			}
+			continue
+		}
+
 		// Handle continuation of multi-line raw string from previous line
 		if inRawStringMultiline {
 			if idx := strings.Index(line, "`"); idx != -1 {
@@ -24,7 +63,7 @@ func removeGoComments(content string) string {
 			} else {
 				// Still inside raw string - preserve entire line as-is
 				result.WriteString(line)
-				if i < len(lines)-1 {
+				if newline {
 					result.WriteString("\n")
 				}
 				continue
@@ -145,8 +184,11 @@ func removeGoComments(content string) string {
 				break
 			}
 
-			// Detect line comments - everything after '//' is ignored
+			// Detect line comments - everything after '//' is ignored unless it is kept
 			if j+1 < len(runes) && runes[j] == '/' && runes[j+1] == '/' {
+				if rest := string(runes[j:]); keep != nil && keep(rest) {
+					cleaned.WriteString(rest)
+				}
 				break
 			}
 
@@ -154,15 +196,41 @@ func removeGoComments(content string) string {
 			j++
 		}
 
-		// Remove trailing whitespace but preserve the line structure
-		trimmed := strings.TrimRight(cleaned.String(), " \t")
-		result.WriteString(trimmed)
+		if inRawStringMultiline {
+			// The line ends inside a raw string, so trailing whitespace is string content
+			result.WriteString(cleaned.String())
+		} else {
+			// Remove trailing whitespace but preserve the line structure
+			trimmed := strings.TrimRight(cleaned.String(), " \t")
+			result.WriteString(trimmed)
+		}
 
 		// Preserve newlines except after the last line
-		if i < len(lines)-1 {
+		if newline {
 			result.WriteString("\n")
 		}
 	}
+}
 
-	return result.String()
+// goDeclKeywords start the top-level declarations that godoc documents.
+var goDeclKeywords = []string{"package ", "func ", "func(", "type ", "var ", "const ", "import "}
+
+// goDocCommentLines returns the indexes of the lines holding doc comments: runs of //
+// comment lines starting in the first column that directly precede a top-level
+// declaration, with no blank line in between.
+func goDocCommentLines(lines []string) map[int]bool {
+	docLines := make(map[int]bool)
+	for i, line := range lines {
+		isDecl := slices.ContainsFunc(goDeclKeywords, func(keyword string) bool {
+			return strings.HasPrefix(line, keyword)
+		})
+		if !isDecl {
+			continue
+		}
+
+		for j := i - 1; j >= 0 && strings.HasPrefix(lines[j], "//"); j-- {
+			docLines[j] = true
+		}
+	}
+	return docLines
 }
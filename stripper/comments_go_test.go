@@ -0,0 +1,265 @@
+package stripper
+
+import (
+	"testing"
+)
+
+func TestRemoveGoComments(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name: "single line comment",
+			input: `package main
+func main() {
+	x := 5 // this is a comment
+	y := 10
+}`,
+			expected: `package main
+func main() {
+	x := 5
+	y := 10
+}`,
+		},
+		{
+			name: "block comment",
+			input: `package main
+/* This is a
+   block comment */
+func main() {}`,
+			expected: `package main
+
+
+func main() {}`,
+		},
+		{
+			name:     "inline block comment",
+			input:    `x := /* comment */ 5`,
+			expected: `x :=  5`,
+		},
+		{
+			// Critical test: ensures the parser distinguishes between comment syntax
+			// inside string literals (which should be preserved) vs actual comments
+			name: "string with comment-like content",
+			input: `s := "// not a comment"
+s2 := "/* also not */"`,
+			expected: `s := "// not a comment"
+s2 := "/* also not */"`,
+		},
+		{
+			// Tests rune literal handling - single quotes use different parsing rules
+			// than double quotes, and comment characters inside runes must be preserved
+			name: "rune literals",
+			input: `r := '/' // comment
+r2 := '*'
+r3 := '\''`,
+			expected: `r := '/'
+r2 := '*'
+r3 := '\''`,
+		},
+		{
+			// Ensures escaped quotes don't prematurely terminate string parsing,
+			// which would incorrectly treat the remainder as code/comments
+			name: "escaped quotes in string",
+			input: `s := "He said \"hello\" // comment"
+// another comment`,
+			expected: `s := "He said \"hello\" // comment"
+`,
+		},
+		{
+			name: "mixed comments and code",
+			input: `// Package comment
+package main
+/*
+Multi-line
+comment
+*/
+func main() {
+	x := 5 // inline
+	/* block */ y := 10
+}`,
+			expected: `
+package main
+
+
+
+
+func main() {
+	x := 5
+	 y := 10
+}`,
+		},
+		{
+			// Edge case: verifies trailing comments don't cause issues with EOF handling
+			name: "comment at end of file",
+			input: `package main
+// final comment`,
+			expected: `package main
+`,
+		},
+		{
+			// Tests escape sequences within runes - the parser must not confuse
+			// the escaped character with comment syntax (e.g., '\n' contains 'n', not newline)
+			name: "empty rune",
+			input: `r := '\n' // newline rune
+x := 5`,
+			expected: `r := '\n'
+x := 5`,
+		},
+		{
+			name:     "multiline raw string followed by block comment",
+			input:    "x := `multi\nline /* kept */` /* c */ + y",
+			expected: "x := `multi\nline /* kept */`  + y",
+		},
+		{
+			name:     "multiline raw string followed by unterminated block comment",
+			input:    "x := `multi\nline`/* c\nstill c */ + y // done\nz := 1",
+			expected: "x := `multi\nline`\n + y\nz := 1",
+		},
+		{
+			name:     "trailing whitespace inside multiline raw string",
+			input:    "x := `a  \nb` /* c */ + `c\t\nd` // e",
+			expected: "x := `a  \nb`  + `c\t\nd`",
+		},
+		{
+			name:     "multiline raw string followed by another raw string",
+			input:    "x := `a\nb` + `// kept` // c",
+			expected: "x := `a\nb` + `// kept`",
+		},
+	}
+
+	// Range over slice creates a copy of the struct on each iteration
+	for _, tt := range tests {
+		// Parallel test execution requires capturing tt in closure scope
+		// to avoid race conditions from loop variable reuse
+		t.Run(tt.name, func(t *testing.T) {
+			result := RemoveGoComments(tt.input)
+
+			if result != tt.expected {
+				t.Errorf("RemoveGoComments() failed\nInput:\n%s\n\nExpected:\n%s\n\nGot:\n%s", tt.input, tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestRemoveGoCommentsKeepingDirectives(t *testing.T) {
+	keep := directiveKeeper(goDirectives, "//")
+
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "go:generate kept, ordinary comment removed",
+			input:    "//go:generate mockgen -source=store.go -destination=mock_store.go\n// ordinary\npackage store\n",
+			expected: "//go:generate mockgen -source=store.go -destination=mock_store.go\n\npackage store\n",
+		},
+		{
+			name:     "build constraints kept",
+			input:    "//go:build linux && amd64\n// +build linux,amd64\n\npackage main\n",
+			expected: "//go:build linux && amd64\n// +build linux,amd64\n\npackage main\n",
+		},
+		{
+			name:     "go:embed kept",
+			input:    "//go:embed templates/*\nvar templates embed.FS // embedded templates\n",
+			expected: "//go:embed templates/*\nvar templates embed.FS\n",
+		},
+		{
+			name:     "trailing nolint kept",
+			input:    "x := compute() //nolint:errcheck\ny := 2 // two\n",
+			expected: "x := compute() //nolint:errcheck\ny := 2\n",
+		},
+		{
+			name:     "block comment removed even when it looks like a directive",
+			input:    "x := 1 /*go:noinline*/\n",
+			expected: "x := 1\n",
+		},
+		{
+			name:     "directive text inside a string is unaffected",
+			input:    "s := \"//go:generate\" // note\n",
+			expected: "s := \"//go:generate\"\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := removeGoCommentsKeeping(tt.input, keep, false)
+
+			if result != tt.expected {
+				t.Errorf("removeGoCommentsKeeping() failed\nInput:\n%s\n\nExpected:\n%s\n\nGot:\n%s", tt.input, tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestRemoveGoCommentsKeepingDocs(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name: "doc comments on top-level declarations kept",
+			input: `// Package store persists records.
+package store
+
+// Store holds records.
+// It is safe for concurrent use.
+type Store struct {
+	// mu guards records
+	mu sync.Mutex
+}
+
+// Get returns the record for key.
+func (s *Store) Get(key string) Record {
+	// fast path
+	return s.records[key] // may be zero
+}
+`,
+			expected: `// Package store persists records.
+package store
+
+// Store holds records.
+// It is safe for concurrent use.
+type Store struct {
+
+	mu sync.Mutex
+}
+
+// Get returns the record for key.
+func (s *Store) Get(key string) Record {
+
+	return s.records[key]
+}
+`,
+		},
+		{
+			name:     "comment separated by a blank line is not a doc comment",
+			input:    "// stray note\n\nfunc f() {}\n",
+			expected: "\n\nfunc f() {}\n",
+		},
+		{
+			name:     "doc comment of a grouped declaration kept",
+			input:    "// Limits for requests.\nconst (\n\tmaxSize = 10 // bytes\n)\n",
+			expected: "// Limits for requests.\nconst (\n\tmaxSize = 10\n)\n",
+		},
+		{
+			name:     "comment-like lines in a raw string untouched",
+			input:    "var s = `\n// not a comment\nfunc f()`\n",
+			expected: "var s = `\n// not a comment\nfunc f()`\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := removeGoCommentsKeeping(tt.input, nil, true)
+
+			if result != tt.expected {
+				t.Errorf("removeGoCommentsKeeping() failed\nInput:\n%s\n\nExpected:\n%s\n\nGot:\n%s", tt.input, tt.expected, result)
+			}
+		})
+	}
+}
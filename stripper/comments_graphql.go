@@ -0,0 +1,71 @@
+package stripper
+
+// RemoveGraphQLComments removes # comments from GraphQL schemas and queries while
+// preserving string values and block strings, which hold type and field descriptions.
+func RemoveGraphQLComments(code string) string {
+	runes := []rune(code)
+	result := make([]rune, 0, len(runes))
+	i := 0
+
+	for i < len(runes) {
+		ch := runes[i]
+
+		// Block strings must be checked before regular strings so """ isn't read as an
+		// empty string followed by the start of another one
+		if ch == '"' && i+2 < len(runes) && runes[i+1] == '"' && runes[i+2] == '"' {
+			result = append(result, '"', '"', '"')
+			i += 3
+			for i < len(runes) {
+				// \""" is the only escape sequence inside a block string
+				if runes[i] == '\\' && i+3 < len(runes) && runes[i+1] == '"' && runes[i+2] == '"' && runes[i+3] == '"' {
+					result = append(result, runes[i:i+4]...)
+					i += 4
+					continue
+				}
+				if runes[i] == '"' && i+2 < len(runes) && runes[i+1] == '"' && runes[i+2] == '"' {
+					result = append(result, '"', '"', '"')
+					i += 3
+					break
+				}
+				result = append(result, runes[i])
+				i++
+			}
+			continue
+		}
+
+		if ch == '"' {
+			result = append(result, ch)
+			i++
+			for i < len(runes) {
+				result = append(result, runes[i])
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+					result = append(result, runes[i])
+					i++
+					continue
+				}
+				// Regular strings cannot span lines, so stop at a newline to avoid
+				// swallowing the rest of the document on an unterminated string
+				if runes[i] == '"' || runes[i] == '\n' {
+					i++
+					break
+				}
+				i++
+			}
+			continue
+		}
+
+		if ch == '#' {
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+			result = trimTrailingBlanks(result)
+			continue
+		}
+
+		result = append(result, ch)
+		i++
+	}
+
+	return string(result)
+}
@@ -0,0 +1,79 @@
+package stripper
+
+import (
+	"testing"
+)
+
+func TestRemoveGraphQLComments(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name: "line comments",
+			input: `# schema comment
+type Query {
+  user(id: ID!): User # inline
+}`,
+			expected: `
+type Query {
+  user(id: ID!): User
+}`,
+		},
+		{
+			name: "block string description with hash",
+			input: `"""
+A user. See issue #42 for details.
+# not a comment
+"""
+type User {
+  id: ID! # comment
+}`,
+			expected: `"""
+A user. See issue #42 for details.
+# not a comment
+"""
+type User {
+  id: ID!
+}`,
+		},
+		{
+			name: "escaped triple quote in block string",
+			input: `"""
+Contains \""" # still text
+"""
+scalar Date # comment`,
+			expected: `"""
+Contains \""" # still text
+"""
+scalar Date`,
+		},
+		{
+			name: "string description with hash",
+			input: `type T {
+  "Color as #rrggbb"
+  color: String # comment
+}`,
+			expected: `type T {
+  "Color as #rrggbb"
+  color: String
+}`,
+		},
+		{
+			name:     "hash inside query argument",
+			input:    `{ search(term: "a # b", tag: "\"#x\"") { id } } # comment`,
+			expected: `{ search(term: "a # b", tag: "\"#x\"") { id } }`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := RemoveGraphQLComments(tt.input)
+
+			if result != tt.expected {
+				t.Errorf("RemoveGraphQLComments() failed\nInput:\n%s\n\nExpected:\n%s\n\nGot:\n%s", tt.input, tt.expected, result)
+			}
+		})
+	}
+}
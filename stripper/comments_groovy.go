@@ -0,0 +1,212 @@
+package stripper
+
+import (
+	"unicode"
+)
+
+// RemoveGroovyComments removes // line comments and /* */ block comments from Groovy code,
+// including Gradle build scripts, while preserving single-, double-, and triple-quoted
+// strings, slashy (/.../) and dollar-slashy ($/.../$) strings, and the code inside
+// ${...} GString interpolations.
+func RemoveGroovyComments(code string) string {
+	runes := []rune(code)
+	result, _ := stripGroovyCode(runes, 0, make([]rune, 0, len(runes)), false)
+	return string(result)
+}
+
+// groovySlashyKeywords are keywords after which a '/' begins a slashy string rather than
+// a division.
+var groovySlashyKeywords = map[string]bool{
+	"return": true, "in": true, "instanceof": true, "case": true, "else": true,
+	"assert": true, "throw": true, "new": true,
+}
+
+// stripGroovyCode copies code from runes[i:] to result with comments removed. When
+// inInterpolation is set it stops at the '}' that closes the enclosing ${...} and
+// returns its index, so that strings nested inside interpolations are parsed as code.
+func stripGroovyCode(runes []rune, i int, result []rune, inInterpolation bool) ([]rune, int) {
+	braceDepth := 0
+	// As with JavaScript regex literals, a '/' after an operand is a division and
+	// anywhere else starts a slashy string, so the last token is tracked
+	var prevSignificant rune
+	prevWord := ""
+
+	for i < len(runes) {
+		ch := runes[i]
+
+		switch {
+		case ch == '}' && inInterpolation && braceDepth == 0:
+			return result, i
+		case ch == '{':
+			braceDepth++
+		case ch == '}':
+			braceDepth--
+		case ch == '"' || ch == '\'':
+			triple := i+2 < len(runes) && runes[i+1] == ch && runes[i+2] == ch
+			result, i = copyGroovyString(runes, i, result, triple)
+			prevSignificant, prevWord = ch, ""
+			continue
+		case ch == '/' && i+1 < len(runes) && runes[i+1] == '*':
+			// Unlike Kotlin and Scala, Groovy block comments don't nest
+			i += 2
+			for i < len(runes) && (runes[i] != '*' || i+1 >= len(runes) || runes[i+1] != '/') {
+				i++
+			}
+			i = min(i+2, len(runes))
+			if i >= len(runes) || runes[i] == '\n' {
+				result = trimTrailingBlanks(result)
+			}
+			continue
+		case ch == '/' && i+1 < len(runes) && runes[i+1] == '/':
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+			result = trimTrailingBlanks(result)
+			continue
+		case ch == '/' && groovySlashyAllowed(prevSignificant, prevWord):
+			// Without a closing '/' it can only be a division
+			if copied, end, ok := copyGroovySlashy(runes, i, result); ok {
+				result, i = copied, end
+				prevSignificant, prevWord = ch, ""
+				continue
+			}
+		case ch == '$' && i+1 < len(runes) && runes[i+1] == '/' && (i == 0 || !isJSIdentChar(runes[i-1])):
+			result, i = copyGroovyDollarSlashy(runes, i, result)
+			prevSignificant, prevWord = ch, ""
+			continue
+		}
+
+		if isJSIdentChar(ch) {
+			if i == 0 || !isJSIdentChar(runes[i-1]) {
+				prevWord = ""
+			}
+			prevWord += string(ch)
+			prevSignificant = ch
+		} else if !unicode.IsSpace(ch) {
+			prevSignificant, prevWord = ch, ""
+		}
+
+		result = append(result, ch)
+		i++
+	}
+
+	return result, i
+}
+
+// groovySlashyAllowed reports whether a '/' following the given token starts a slashy
+// string, applying the JavaScript regex literal rule with Groovy's keywords.
+func groovySlashyAllowed(prevSignificant rune, prevWord string) bool {
+	if prevWord != "" {
+		return groovySlashyKeywords[prevWord]
+	}
+	return jsRegexAllowed(prevSignificant, "")
+}
+
+// copyGroovyString copies the quoted string starting at runes[i] to result and returns
+// the index just past it. Only double-quoted strings are GStrings with ${...}
+// interpolations; only triple-quoted strings can span lines.
+func copyGroovyString(runes []rune, i int, result []rune, triple bool) ([]rune, int) {
+	quote := runes[i]
+	width := 1
+	if triple {
+		width = 3
+	}
+	result = append(result, runes[i:i+width]...)
+	i += width
+
+	for i < len(runes) {
+		ch := runes[i]
+
+		if ch == '\\' && i+1 < len(runes) {
+			result = append(result, ch, runes[i+1])
+			i += 2
+			continue
+		}
+
+		if quote == '"' && ch == '$' && i+1 < len(runes) && runes[i+1] == '{' {
+			result, i = copyGroovyInterpolation(runes, i, result)
+			continue
+		}
+
+		if ch == quote && (!triple || i+2 < len(runes) && runes[i+1] == quote && runes[i+2] == quote) {
+			result = append(result, runes[i:i+width]...)
+			return result, i + width
+		}
+
+		// Single-line strings stop at the newline so a malformed string doesn't
+		// swallow the rest of the file
+		if !triple && ch == '\n' {
+			return result, i
+		}
+
+		result = append(result, ch)
+		i++
+	}
+
+	return result, i
+}
+
+// copyGroovySlashy copies the slashy string starting at runes[i] to result and returns
+// the index just past it, or false if the string is never closed. Slashy strings can
+// span lines and interpolate, and only escape the slash itself.
+func copyGroovySlashy(runes []rune, i int, result []rune) ([]rune, int, bool) {
+	result = append(result, '/')
+	i++
+
+	for i < len(runes) {
+		ch := runes[i]
+
+		switch {
+		case ch == '\\' && i+1 < len(runes) && runes[i+1] == '/':
+			result = append(result, ch, '/')
+			i += 2
+		case ch == '$' && i+1 < len(runes) && runes[i+1] == '{':
+			result, i = copyGroovyInterpolation(runes, i, result)
+		case ch == '/':
+			return append(result, ch), i + 1, true
+		default:
+			result = append(result, ch)
+			i++
+		}
+	}
+
+	return result, i, false
+}
+
+// copyGroovyDollarSlashy copies the dollar-slashy string starting at runes[i] to result
+// and returns the index just past it. Inside, $$ and $/ escape a dollar and a slash.
+func copyGroovyDollarSlashy(runes []rune, i int, result []rune) ([]rune, int) {
+	result = append(result, '$', '/')
+	i += 2
+
+	for i < len(runes) {
+		ch := runes[i]
+
+		switch {
+		case ch == '$' && i+1 < len(runes) && (runes[i+1] == '$' || runes[i+1] == '/'):
+			result = append(result, ch, runes[i+1])
+			i += 2
+		case ch == '$' && i+1 < len(runes) && runes[i+1] == '{':
+			result, i = copyGroovyInterpolation(runes, i, result)
+		case ch == '/' && i+1 < len(runes) && runes[i+1] == '$':
+			return append(result, '/', '$'), i + 2
+		default:
+			result = append(result, ch)
+			i++
+		}
+	}
+
+	return result, i
+}
+
+// copyGroovyInterpolation copies the ${...} interpolation starting at runes[i] to result,
+// removing comments from the code inside, and returns the index just past its '}'.
+func copyGroovyInterpolation(runes []rune, i int, result []rune) ([]rune, int) {
+	result = append(result, '$', '{')
+	result, i = stripGroovyCode(runes, i+2, result, true)
+	if i < len(runes) {
+		result = append(result, runes[i])
+		i++
+	}
+	return result, i
+}
@@ -0,0 +1,100 @@
+package stripper
+
+import (
+	"testing"
+)
+
+func TestRemoveGroovyComments(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name: "line and block comments",
+			input: `// build script
+def x = 5 // trailing
+/* block
+   comment */
+def y = 10 /* inline */ + 1`,
+			expected: `
+def x = 5
+
+def y = 10  + 1`,
+		},
+		{
+			name: "triple-quoted strings with comment markers",
+			input: `def sql = """
+    SELECT 1 -- // kept
+    /* also kept */
+""" // removed
+def raw = '''// kept ''' // removed`,
+			expected: `def sql = """
+    SELECT 1 -- // kept
+    /* also kept */
+"""
+def raw = '''// kept '''`,
+		},
+		{
+			name:     "quoted strings with comment markers",
+			input:    `def url = "https://example.com"; def s = 'a \' // b' // comment`,
+			expected: `def url = "https://example.com"; def s = 'a \' // b'`,
+		},
+		{
+			name:     "GString interpolation with nested quotes",
+			input:    `println "value: ${map["//key"] /* note */} // text" // comment`,
+			expected: `println "value: ${map["//key"] } // text"`,
+		},
+		{
+			name:     "slashy string",
+			input:    `def pattern = ~/https?:\/\/[^ ]*/ // url pattern`,
+			expected: `def pattern = ~/https?:\/\/[^ ]*/`,
+		},
+		{
+			name:     "division is not a slashy string",
+			input:    `def half = total / 2 // half of it` + "\n" + `def rate = (a + b) / count / 2`,
+			expected: `def half = total / 2` + "\n" + `def rate = (a + b) / count / 2`,
+		},
+		{
+			name:     "slashy string after a keyword",
+			input:    `if (s ==~ /a\/\/b/) return /\/\/ ${s}/ // comment`,
+			expected: `if (s ==~ /a\/\/b/) return /\/\/ ${s}/`,
+		},
+		{
+			name: "dollar-slashy string",
+			input: `def path = $/C:\dir\// $$ $/ /* kept */ ${name}/$ // comment
+def next = 1`,
+			expected: `def path = $/C:\dir\// $$ $/ /* kept */ ${name}/$
+def next = 1`,
+		},
+		{
+			name: "gradle build file",
+			input: `plugins {
+    id 'java' // apply the Java plugin
+}
+
+dependencies {
+    /* test dependencies */
+    testImplementation "junit:junit:${junitVersion}"
+}`,
+			expected: `plugins {
+    id 'java'
+}
+
+dependencies {
+
+    testImplementation "junit:junit:${junitVersion}"
+}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := RemoveGroovyComments(tt.input)
+
+			if result != tt.expected {
+				t.Errorf("RemoveGroovyComments() failed\nInput:\n%s\n\nExpected:\n%s\n\nGot:\n%s", tt.input, tt.expected, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package stripper
+
+import (
+	"strings"
+)
+
+// RemoveHaskellComments removes -- line comments and nested {- -} block comments from
+// Haskell code. Operators made of dashes and symbols (-->, <--, --|) are left alone,
+// as are {-# ... #-} pragmas since they change how the module compiles.
+func RemoveHaskellComments(code string) string {
+	runes := []rune(code)
+	result := make([]rune, 0, len(runes))
+	i := 0
+
+	for i < len(runes) {
+		ch := runes[i]
+
+		if ch == '"' {
+			result = append(result, ch)
+			i++
+			for i < len(runes) {
+				result = append(result, runes[i])
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+					result = append(result, runes[i])
+					i++
+					continue
+				}
+				if runes[i] == '"' || runes[i] == '\n' {
+					i++
+					break
+				}
+				i++
+			}
+			continue
+		}
+
+		// A quote after an identifier character is a prime (foldl', x'), not a char literal
+		if ch == '\'' && (i == 0 || !isHaskellIdentChar(runes[i-1])) {
+			if end := haskellCharLiteralEnd(runes, i); end != -1 {
+				result = append(result, runes[i:end]...)
+				i = end
+				continue
+			}
+		}
+
+		if ch == '{' && i+1 < len(runes) && runes[i+1] == '-' {
+			if i+2 < len(runes) && runes[i+2] == '#' {
+				end := len(runes)
+				if idx := strings.Index(string(runes[i:]), "#-}"); idx != -1 {
+					end = i + len([]rune(string(runes[i:])[:idx])) + 3
+				}
+				result = append(result, runes[i:end]...)
+				i = end
+				continue
+			}
+
+			// Haskell block comments nest, so track depth like Rust's block comments
+			depth := 1
+			i += 2
+			for i < len(runes) && depth > 0 {
+				if i+1 < len(runes) && runes[i] == '{' && runes[i+1] == '-' {
+					depth++
+					i += 2
+					continue
+				}
+				if i+1 < len(runes) && runes[i] == '-' && runes[i+1] == '}' {
+					depth--
+					i += 2
+					continue
+				}
+				i++
+			}
+			continue
+		}
+
+		if ch == '-' && i+1 < len(runes) && runes[i+1] == '-' && (i == 0 || !isHaskellSymbol(runes[i-1])) {
+			end := i
+			for end < len(runes) && runes[end] == '-' {
+				end++
+			}
+
+			// A run of dashes followed by another symbol is an operator such as --> or --|
+			if end < len(runes) && isHaskellSymbol(runes[end]) {
+				result = append(result, runes[i:end]...)
+				i = end
+				continue
+			}
+
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+			result = trimTrailingBlanks(result)
+			continue
+		}
+
+		result = append(result, ch)
+		i++
+	}
+
+	return string(result)
+}
+
+// haskellCharLiteralEnd returns the index just past the char literal starting at
+// runes[start], or -1 if the quote does not open a char literal (e.g. a promoted
+// constructor like '[] in type-level code).
+func haskellCharLiteralEnd(runes []rune, start int) int {
+	i := start + 1
+	if i < len(runes) && runes[i] == '\\' {
+		// Escapes can be longer than one character ('\n', '\x41', '\DEL'), so scan
+		// forward to the closing quote on the same line
+		for i++; i < len(runes) && runes[i] != '\n'; i++ {
+			if runes[i] == '\'' {
+				return i + 1
+			}
+		}
+		return -1
+	}
+
+	if i+1 < len(runes) && runes[i] != '\n' && runes[i+1] == '\'' {
+		return i + 2
+	}
+	return -1
+}
+
+func isHaskellSymbol(r rune) bool {
+	return strings.ContainsRune("!#$%&*+./<=>?@\\^|-~:", r)
+}
+
+func isHaskellIdentChar(r rune) bool {
+	return isAlphanumeric(r) || r == '_' || r == '\''
+}
@@ -0,0 +1,72 @@
+package stripper
+
+import (
+	"testing"
+)
+
+func TestRemoveHaskellComments(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name: "line comments",
+			input: `-- module header
+main :: IO ()
+main = print 1 -- trailing`,
+			expected: `
+main :: IO ()
+main = print 1`,
+		},
+		{
+			name:     "longer dash run is still a comment",
+			input:    `x = 1 ----- divider`,
+			expected: `x = 1`,
+		},
+		{
+			name: "nested block comments",
+			input: `{- outer {- inner -} still outer -}
+f x = x {- inline -} + 1`,
+			expected: `
+f x = x  + 1`,
+		},
+		{
+			// A dash run followed by another symbol forms an operator, not a comment
+			name: "operators made of dashes",
+			input: `a --> b
+c <-- d
+e --| f -- real comment`,
+			expected: `a --> b
+c <-- d
+e --| f`,
+		},
+		{
+			name:     "comment markers inside strings",
+			input:    `s = "-- not {- a -} comment" -- comment`,
+			expected: `s = "-- not {- a -} comment"`,
+		},
+		{
+			name:     "char literals and primes",
+			input:    `f x' = foldl' g '-' x' -- comment`,
+			expected: `f x' = foldl' g '-' x'`,
+		},
+		{
+			name: "pragmas preserved",
+			input: `{-# LANGUAGE OverloadedStrings #-}
+module Main where -- comment`,
+			expected: `{-# LANGUAGE OverloadedStrings #-}
+module Main where`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := RemoveHaskellComments(tt.input)
+
+			if result != tt.expected {
+				t.Errorf("RemoveHaskellComments() failed\nInput:\n%s\n\nExpected:\n%s\n\nGot:\n%s", tt.input, tt.expected, result)
+			}
+		})
+	}
+}
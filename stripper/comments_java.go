@@ -0,0 +1,162 @@
+package stripper
+
+import (
+	"strings"
+)
+
+// RemoveJavaComments removes line, block, and Javadoc comments from Java source,
+// preserving string and character literals and text blocks.
+func RemoveJavaComments(content string) string {
+	var result strings.Builder
+	lines := strings.Split(content, "\n")
+
+	// Track state across lines since Java supports multi-line text blocks and block comments.
+	// Javadoc (/** */) is just a block comment as far as removal is concerned.
+	inBlockComment := false
+	inTextBlock := false
+
+	for i, line := range lines {
+		// Text blocks ("""...""") are preserved verbatim, including any // or /* inside them,
+		// which commonly appear in embedded URLs, JSON, and SQL
+		if inTextBlock {
+			idx := findTextBlockEnd(line)
+			if idx == -1 {
+				result.WriteString(line)
+				if i < len(lines)-1 {
+					result.WriteString("\n")
+				}
+				continue
+			}
+
+			result.WriteString(line[:idx+3])
+			inTextBlock = false
+			// Continue processing remainder of line in case there's code after the text block
+			line = line[idx+3:]
+		}
+
+		// Handle continuation of block comment from previous line
+		if inBlockComment {
+			if idx := strings.Index(line, "*/"); idx != -1 {
+				inBlockComment = false
+				line = line[idx+2:]
+			} else {
+				// Still inside block comment - preserve line structure but not content
+				result.WriteString("\n")
+				continue
+			}
+		}
+
+		var cleaned strings.Builder
+		inString := false
+		inChar := false
+		escaped := false
+		j := 0
+		runes := []rune(line)
+
+		for j < len(runes) {
+			ch := runes[j]
+
+			if escaped {
+				cleaned.WriteRune(ch)
+				escaped = false
+				j++
+				continue
+			}
+
+			if ch == '\\' && (inString || inChar) {
+				cleaned.WriteRune(ch)
+				escaped = true
+				j++
+				continue
+			}
+
+			// Text blocks must be checked before regular strings so """ isn't read as
+			// an empty string followed by the start of another one
+			if ch == '"' && !inString && !inChar && j+2 < len(runes) && runes[j+1] == '"' && runes[j+2] == '"' {
+				cleaned.WriteString(`"""`)
+				rest := string(runes[j+3:])
+				if endIdx := findTextBlockEnd(rest); endIdx != -1 {
+					cleaned.WriteString(rest[:endIdx+3])
+					j += 3 + len([]rune(rest[:endIdx+3]))
+					continue
+				}
+
+				// Text block spans multiple lines - preserve rest of line as-is
+				inTextBlock = true
+				cleaned.WriteString(rest)
+				break
+			}
+
+			if ch == '\'' && !inString {
+				inChar = !inChar
+				cleaned.WriteRune(ch)
+				j++
+				continue
+			}
+
+			if ch == '"' && !inChar {
+				inString = !inString
+				cleaned.WriteRune(ch)
+				j++
+				continue
+			}
+
+			// Preserve all content inside strings and char literals
+			if inString || inChar {
+				cleaned.WriteRune(ch)
+				j++
+				continue
+			}
+
+			// Java block comments do not nest, so the first */ always closes the comment
+			if j+1 < len(runes) && runes[j] == '/' && runes[j+1] == '*' {
+				inBlockComment = true
+
+				if endIdx := strings.Index(string(runes[j+2:]), "*/"); endIdx != -1 {
+					inBlockComment = false
+					j += len([]rune(string(runes[j+2:])[:endIdx])) + 4
+					continue
+				}
+
+				break
+			}
+
+			if j+1 < len(runes) && runes[j] == '/' && runes[j+1] == '/' {
+				break
+			}
+
+			cleaned.WriteRune(ch)
+			j++
+		}
+
+		if inTextBlock {
+			// Text block content must keep its trailing whitespace untouched
+			result.WriteString(cleaned.String())
+		} else {
+			trimmed := strings.TrimRight(cleaned.String(), " \t")
+			result.WriteString(trimmed)
+		}
+
+		if i < len(lines)-1 {
+			result.WriteString("\n")
+		}
+	}
+
+	return result.String()
+}
+
+// findTextBlockEnd returns the byte index of the closing """ of a Java text block in s,
+// or -1 if the block does not close. Escaped quotes (\") never close a text block.
+func findTextBlockEnd(s string) int {
+	for idx := 0; idx+2 < len(s); idx++ {
+		if s[idx] == '\\' {
+			// Skip the escaped character entirely
+			idx++
+			continue
+		}
+		if s[idx] == '"' && s[idx+1] == '"' && s[idx+2] == '"' {
+			return idx
+		}
+	}
+	return -1
+}
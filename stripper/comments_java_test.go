@@ -0,0 +1,109 @@
+package stripper
+
+import (
+	"testing"
+)
+
+func TestRemoveJavaComments(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name: "single line comment",
+			input: `int x = 5; // this is a comment
+int y = 10;`,
+			expected: `int x = 5;
+int y = 10;`,
+		},
+		{
+			name:     "inline block comment",
+			input:    `int x = /* comment */ 5;`,
+			expected: `int x =  5;`,
+		},
+		{
+			name: "javadoc comment",
+			input: `/**
+ * Adds two numbers.
+ * @param a first
+ */
+int add(int a, int b) { return a + b; }`,
+			expected: `
+
+
+
+int add(int a, int b) { return a + b; }`,
+		},
+		{
+			name: "string with comment-like content",
+			input: `String url = "https://example.com"; // link
+String s = "/* not a comment */";`,
+			expected: `String url = "https://example.com";
+String s = "/* not a comment */";`,
+		},
+		{
+			// Char literals use single quotes and can hold the '/' character on its own
+			name: "char literals",
+			input: `char c = '/'; // slash
+char q = '\'';
+char d = '"'; // quote`,
+			expected: `char c = '/';
+char q = '\'';
+char d = '"';`,
+		},
+		{
+			name:     "escaped quotes in string",
+			input:    `String s = "He said \"hi\" // not a comment"; // comment`,
+			expected: `String s = "He said \"hi\" // not a comment";`,
+		},
+		{
+			// Text blocks frequently embed URLs, so // inside them must survive
+			name: "text block with url",
+			input: `String json = """
+    {"url": "https://example.com/path"} // kept
+    /* also kept */
+    """; // removed
+int x = 1;`,
+			expected: `String json = """
+    {"url": "https://example.com/path"} // kept
+    /* also kept */
+    """;
+int x = 1;`,
+		},
+		{
+			name:     "text block closing on same line",
+			input:    `String s = """ // inside """; // outside`,
+			expected: `String s = """ // inside """;`,
+		},
+		{
+			// Escaped quotes inside a text block must not close it early
+			name: "text block with escaped quote",
+			input: `String s = """
+    a \""" // still text
+    """;`,
+			expected: `String s = """
+    a \""" // still text
+    """;`,
+		},
+		{
+			name: "multi-line block comment",
+			input: `int x = 1; /* start
+   middle
+   end */ int y = 2;`,
+			expected: `int x = 1;
+
+ int y = 2;`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := RemoveJavaComments(tt.input)
+
+			if result != tt.expected {
+				t.Errorf("RemoveJavaComments() failed\nInput:\n%s\n\nExpected:\n%s\n\nGot:\n%s", tt.input, tt.expected, result)
+			}
+		})
+	}
+}
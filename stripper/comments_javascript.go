@@ -0,0 +1,465 @@
+package stripper
+
+import (
+	"io"
+	"iter"
+	"strings"
+	"unicode"
+)
+
+// jsDirectives are the default -keep-directives prefixes for JavaScript and TypeScript:
+// type checker and linter suppressions, formatter and coverage pragmas, source map
+// references, and bundler annotations such as /*#__PURE__*/ and webpack magic comments.
+var jsDirectives = []string{
+	"@ts-", "eslint-", "eslint ", "prettier-ignore", "biome-ignore", "istanbul ignore",
+	"c8 ignore", "# sourceMappingURL=", "@ sourceMappingURL=", "#__PURE__", "@__PURE__",
+	"webpack", "@vite-ignore", "@jsx", "@flow",
+}
+
+// RemoveJSComments removes line and block comments from JavaScript and TypeScript
+// source, preserving string, template, and regular expression literals.
+func RemoveJSComments(content string) string {
+	return removeJSCommentsKeeping(content, nil, false)
+}
+
+// RemoveJSXComments removes comments like RemoveJSComments from JavaScript or TypeScript
+// source that contains JSX elements, preserving their text and attributes. Expression
+// containers that held only comments, such as {/* note */}, are removed whole.
+func RemoveJSXComments(content string) string {
+	return removeJSCommentsKeeping(content, nil, true)
+}
+
+// removeJSCommentsKeeping removes comments like RemoveJSComments, or RemoveJSXComments if
+// jsx is set, except those for which keep returns true. keep receives the comment text
+// from its // or /* opener up to the end of the comment or the end of its first line,
+// whichever comes first. A nil keep removes every comment. A leading #! hashbang, as used
+// by Node CLI scripts, is kept as is.
+func removeJSCommentsKeeping(content string, keep func(comment string) bool, jsx bool) string {
+	var result strings.Builder
+	removeJSCommentLines(&result, splitLines(content), keep, jsx)
+	return result.String()
+}
+
+// removeJSCommentLines writes lines to result with comments removed like
+// removeJSCommentsKeeping.
+func removeJSCommentLines(result io.StringWriter, lines iter.Seq2[string, bool], keep func(comment string) bool, jsx bool) {
+	// Track state across lines since comments and template literals can span multiple lines
+	inBlockComment := false
+	// Set when the block comment being continued was kept and must be copied through
+	keepingBlockComment := false
+
+	// A '/' can start a comment, a regex literal, or a division; which one depends on the
+	// previous significant token, so track it across lines
+	prevSignificant := rune(0)
+	prevWord := ""
+	prevWasIdent := false
+	// Set when the previous token ends an operand although jsRegexAllowed would expect
+	// one: a postfix ++ or --, or a keyword used as a property name as in a.return
+	prevOperand := false
+	// Set when the previous token is the ) that ends the condition of an if, while, or
+	// for, after which a '/' starts a regex in the statement body, as in if (x) /re/.test(s).
+	// headerParens holds whether each open parenthesis starts such a condition.
+	prevHeaderEnd := false
+	var headerParens []bool
+	// The template literals and JSX elements open at this point, innermost last. They nest
+	// through their ${...} and {...} expressions, which are code that can hold strings,
+	// braces, and further templates and elements, and like block comments they can span
+	// lines.
+	var nesting []jsNesting
+
+	first := true
+	for line, newline := range lines {
+		if first {
+			first = false
+			if strings.HasPrefix(line, "#!") {
+				result.WriteString(line)
+				if newline {
+					result.WriteString("\n")
+				}
+				continue
+			}
+		}
+
+		// Handle continuation of block comments from previous lines
+		if inBlockComment {
+			if idx := strings.Index(line, "*/"); idx != -1 {
+				inBlockComment = false
+				if keepingBlockComment {
+					result.WriteString(line[:idx+2])
+					keepingBlockComment = false
+				}
+				// Process remainder of line after comment closes
+				line = line[idx+2:]
+			} else if keepingBlockComment {
+				result.WriteString(line)
+				if newline {
+					result.WriteString("\n")
+				}
+				continue
+			} else {
+				// Entire line is still inside block comment, preserve newline structure
+				result.WriteString("\n")
+				continue
+			}
+		}
+		// Character-by-character parsing state for this line
+		var cleaned strings.Builder
+		inString := false
+		stringChar := rune(0)  // Track which quote type started the string (' or ")
+		escaped := false
+
+		j := 0
+		runes := []rune(line)
+
+		for j < len(runes) {
+			ch := runes[j]
+			var top *jsNesting
+			if len(nesting) > 0 {
+				top = &nesting[len(nesting)-1]
+			}
+			// Outside expressions, templates and JSX have text and tags rather than code
+			inText := top != nil && !top.inExpression
+			inTemplateText := inText && top.kind == jsTemplateLiteral
+
+			// Escaped characters are always literal, never syntax
+			if escaped {
+				cleaned.WriteRune(ch)
+				escaped = false
+				j++
+				continue
+			}
+
+			// Backslash starts escape sequence within strings/templates
+			if ch == '\\' && (inString || inTemplateText) {
+				cleaned.WriteRune(ch)
+				escaped = true
+				j++
+				continue
+			}
+
+			// Template text, comment-like syntax included, is copied up to the closing
+			// backtick or the ${ that opens an expression
+			if inTemplateText {
+				if ch == '`' {
+					nesting = nesting[:len(nesting)-1]
+					prevSignificant, prevWord, prevWasIdent, prevOperand, prevHeaderEnd = ch, "", false, false, false
+				} else if ch == '$' && j+1 < len(runes) && runes[j+1] == '{' {
+					top.inExpression = true
+					prevSignificant, prevWord, prevWasIdent, prevOperand, prevHeaderEnd = '{', "", false, false, false
+					cleaned.WriteString("${")
+					j += 2
+					continue
+				}
+				cleaned.WriteRune(ch)
+				j++
+				continue
+			}
+
+			// JSX text, quotes and comment-like syntax included, is copied up to the { that
+			// opens an expression or the < of a child element or closing tag
+			if inText && top.kind == jsJSXChildren {
+				switch ch {
+				case '{':
+					top.inExpression = true
+					top.expressionStart, top.commentRemoved = cleaned.Len(), false
+					prevSignificant, prevWord, prevWasIdent, prevOperand, prevHeaderEnd = '{', "", false, false, false
+				case '<':
+					nesting = append(nesting, jsNesting{kind: jsJSXTag, closing: j+1 < len(runes) && runes[j+1] == '/'})
+				}
+				cleaned.WriteRune(ch)
+				j++
+				continue
+			}
+
+			// Inside a tag, attribute strings and comments are handled as in code, while
+			// everything else is copied up to the tag's end or an attribute expression
+			startsCode := ch == '"' || ch == '\'' || ch == '/' && j+1 < len(runes) && (runes[j+1] == '/' || runes[j+1] == '*')
+			if inText && top.kind == jsJSXTag && !inString && !startsCode {
+				switch {
+				case ch == '{':
+					top.inExpression = true
+					top.expressionStart = -1
+					prevSignificant, prevWord, prevWasIdent, prevOperand, prevHeaderEnd = '{', "", false, false, false
+				case ch == '/' && j+1 < len(runes) && runes[j+1] == '>' && !top.closing:
+					nesting = nesting[:len(nesting)-1]
+					prevSignificant, prevWord, prevWasIdent, prevOperand, prevHeaderEnd = ')', "", false, false, false
+					cleaned.WriteString("/>")
+					j += 2
+					continue
+				case ch == '>' && top.closing:
+					// A closing tag ends the element whose children it follows
+					nesting = nesting[:max(len(nesting)-2, 0)]
+					prevSignificant, prevWord, prevWasIdent, prevOperand, prevHeaderEnd = ')', "", false, false, false
+				case ch == '>':
+					*top = jsNesting{kind: jsJSXChildren}
+				}
+				cleaned.WriteRune(ch)
+				j++
+				continue
+			}
+
+			// Handle string literals (' and ")
+			if ch == '"' || ch == '\'' {
+				if !inString {
+					inString = true
+					stringChar = ch
+				} else if ch == stringChar {
+					inString = false
+					stringChar = 0
+					prevSignificant, prevWord, prevWasIdent, prevOperand, prevHeaderEnd = ch, "", false, false, false
+				}
+				cleaned.WriteRune(ch)
+				j++
+				continue
+			}
+
+			// Inside strings, preserve everything (including comment syntax)
+			if inString {
+				cleaned.WriteRune(ch)
+				j++
+				continue
+			}
+
+			if ch == '`' {
+				nesting = append(nesting, jsNesting{kind: jsTemplateLiteral})
+				cleaned.WriteRune(ch)
+				j++
+				continue
+			}
+
+			// Only the '}' matching the opening of an expression returns to the text or tag
+			// of its template or element
+			if top != nil && top.inExpression && (ch == '{' || ch == '}') {
+				if ch == '{' {
+					top.braces++
+				} else if top.braces > 0 {
+					top.braces--
+				} else {
+					top.inExpression = false
+					if top.kind == jsJSXChildren && top.commentRemoved && top.expressionStart >= 0 &&
+						strings.TrimSpace(cleaned.String()[top.expressionStart+1:]) == "" {
+						// Drop the {} left behind by removing the only thing in it
+						kept := cleaned.String()[:top.expressionStart]
+						cleaned.Reset()
+						cleaned.WriteString(kept)
+					} else {
+						cleaned.WriteRune(ch)
+					}
+					j++
+					continue
+				}
+			}
+
+			// Block comment start - check if it closes on same line
+			if j+1 < len(runes) && runes[j] == '/' && runes[j+1] == '*' {
+				inBlockComment = true
+				rest := string(runes[j:])
+
+				// Optimize single-line block comments by skipping over them immediately
+				if endIdx := strings.Index(rest[2:], "*/"); endIdx != -1 {
+					inBlockComment = false
+					comment := rest[:endIdx+4]
+					if keep != nil && keep(comment) {
+						cleaned.WriteString(comment)
+					} else if top != nil && top.inExpression {
+						top.commentRemoved = true
+					}
+					j += len([]rune(comment))  // Skip past the entire comment including */
+					continue
+				}
+
+				// Comment extends beyond this line
+				if keep != nil && keep(rest) {
+					keepingBlockComment = true
+					cleaned.WriteString(rest)
+				}
+				break
+			}
+
+			// Line comment - rest of line is a comment
+			if j+1 < len(runes) && runes[j] == '/' && runes[j+1] == '/' {
+				if rest := string(runes[j:]); keep != nil && keep(rest) {
+					cleaned.WriteString(rest)
+				}
+				break
+			}
+
+			// Regex literals can contain // and /* (e.g. /https?:\/\//), so copy them whole
+			if ch == '/' && (prevHeaderEnd || !prevOperand && jsRegexAllowed(prevSignificant, prevWord)) {
+				if end := jsRegexEnd(runes, j); end != -1 {
+					cleaned.WriteString(string(runes[j:end]))
+					j = end
+					prevSignificant, prevWord, prevWasIdent, prevOperand, prevHeaderEnd = '/', "", false, false, false
+					continue
+				}
+			}
+
+			// Where an operand is expected, a '<' opens a JSX element rather than comparing
+			if jsx && ch == '<' && prevSignificant != '<' && (prevHeaderEnd || !prevOperand && jsRegexAllowed(prevSignificant, prevWord)) && isJSXTagStart(runes, j) {
+				nesting = append(nesting, jsNesting{kind: jsJSXTag})
+				cleaned.WriteRune(ch)
+				j++
+				continue
+			}
+
+			isIdent := isJSIdentChar(ch)
+			if isIdent {
+				if prevWasIdent {
+					prevWord += string(ch)
+				} else {
+					prevWord = string(ch)
+					prevOperand = prevSignificant == '.'
+				}
+				prevHeaderEnd = false
+			} else if ch != ' ' && ch != '\t' {
+				prevHeaderEnd = false
+				switch ch {
+				case '(':
+					headerParens = append(headerParens, jsControlKeywords[prevWord] && !prevOperand)
+				case ')':
+					if len(headerParens) > 0 {
+						prevHeaderEnd = headerParens[len(headerParens)-1]
+						headerParens = headerParens[:len(headerParens)-1]
+					}
+				}
+				prevWord = ""
+				// The second of two adjacent + or - makes a ++ or --, which is postfix
+				// where jsRegexAllowed would not expect an operand after it; a prefix
+				// ++/re/ is not valid JavaScript
+				prevOperand = (ch == '+' || ch == '-') && j > 0 && runes[j-1] == ch && !prevOperand
+			}
+			if ch != ' ' && ch != '\t' {
+				prevSignificant = ch
+			}
+			prevWasIdent = isIdent
+
+			cleaned.WriteRune(ch)
+			j++
+		}
+		prevWasIdent = false
+		// The line being cleaned no longer holds the start of any open expression
+		for i := range nesting {
+			nesting[i].expressionStart = -1
+		}
+
+		if len(nesting) > 0 && nesting[len(nesting)-1].kind == jsTemplateLiteral && !nesting[len(nesting)-1].inExpression {
+			// Trailing whitespace is part of the template literal the line ends in
+			result.WriteString(cleaned.String())
+		} else {
+			// Remove trailing whitespace but preserve line structure
+			trimmed := strings.TrimRight(cleaned.String(), " \t")
+			result.WriteString(trimmed)
+		}
+
+		if newline {
+			result.WriteString("\n")
+		}
+	}
+}
+
+// jsNestingKind is the kind of construct a jsNesting holds open.
+type jsNestingKind int
+
+const (
+	// jsTemplateLiteral is a `...` template literal
+	jsTemplateLiteral jsNestingKind = iota
+	// jsJSXTag is a JSX tag, from its < to its >
+	jsJSXTag
+	// jsJSXChildren is the content of a JSX element, between its opening and closing tags
+	jsJSXChildren
+)
+
+// jsNesting is the state of an open template literal or JSX element: whether a ${...} or
+// {...} expression is open in it, and how many braces that expression has opened and not
+// yet closed.
+type jsNesting struct {
+	kind         jsNestingKind
+	inExpression bool
+	braces       int
+	// closing is set for closing tags such as </div>
+	closing bool
+	// expressionStart is where the open expression of JSX children starts in the line
+	// being cleaned, or -1 if it started on an earlier line, and commentRemoved is set
+	// once a comment is removed from it
+	expressionStart int
+	commentRemoved  bool
+}
+
+// jsRegexKeywords are keywords after which a '/' begins a regex literal rather than a division.
+var jsRegexKeywords = map[string]bool{
+	"return": true, "typeof": true, "instanceof": true, "in": true, "of": true, "new": true,
+	"delete": true, "void": true, "throw": true, "case": true, "do": true, "else": true,
+	"yield": true, "await": true,
+}
+
+// jsControlKeywords are the keywords whose parenthesized header is followed by a
+// statement, so that a '/' after the closing parenthesis begins a regex literal.
+var jsControlKeywords = map[string]bool{"if": true, "while": true, "for": true, "with": true}
+
+// jsRegexAllowed reports whether a '/' following the given token starts a regex literal.
+// After an identifier, number, or closing bracket a '/' is a division operator; after an
+// operator, an opening bracket, or a keyword like return, it starts a regex.
+func jsRegexAllowed(prevSignificant rune, prevWord string) bool {
+	if prevWord != "" {
+		return jsRegexKeywords[prevWord]
+	}
+	if prevSignificant == 0 {
+		return true
+	}
+	return strings.ContainsRune("(,=:[!&|?{};+-*%<>~^", prevSignificant)
+}
+
+// jsRegexEnd returns the index just past the flags of the regex literal starting at
+// runes[start], or -1 if no closing '/' is found on the line. A '/' inside a character
+// class ([/]) or after a backslash does not end the regex.
+func jsRegexEnd(runes []rune, start int) int {
+	inClass := false
+	for j := start + 1; j < len(runes); j++ {
+		switch runes[j] {
+		case '\\':
+			j++
+		case '[':
+			inClass = true
+		case ']':
+			inClass = false
+		case '/':
+			if inClass {
+				continue
+			}
+			j++
+			for j < len(runes) && isJSIdentChar(runes[j]) {
+				j++
+			}
+			return j
+		}
+	}
+	return -1
+}
+
+// isJSXTagStart reports whether the '<' at runes[i] opens a JSX element or fragment. In
+// .tsx files, type parameter lists such as <T,> and <T extends U> are written that way to
+// tell them apart from elements.
+func isJSXTagStart(runes []rune, i int) bool {
+	j := i + 1
+	if j < len(runes) && runes[j] == '>' {
+		return true
+	}
+	if j >= len(runes) || !isJSIdentChar(runes[j]) || unicode.IsDigit(runes[j]) {
+		return false
+	}
+	for j < len(runes) && (isJSIdentChar(runes[j]) || runes[j] == '.' || runes[j] == '-' || runes[j] == ':') {
+		j++
+	}
+	rest := strings.TrimLeft(string(runes[j:]), " \t")
+	return !strings.HasPrefix(rest, ",") && !strings.HasPrefix(rest, "extends ")
+}
+
+func isJSIdentChar(r rune) bool {
+	return isAlphanumeric(r) || r == '_' || r == '$'
+}
+
+// isJSDocComment reports whether comment is a JSDoc (or TSDoc) block comment, which opens
+// with /** but is not the empty /**/ comment.
+func isJSDocComment(comment string) bool {
+	return strings.HasPrefix(comment, "/**") && !strings.HasPrefix(comment, "/**/")
+}
@@ -0,0 +1,441 @@
+package stripper
+
+import (
+	"testing"
+)
+
+func TestRemoveJSComments(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name: "single line comment",
+			input: `const x = 5; // this is a comment
+const y = 10;`,
+			expected: `const x = 5;
+const y = 10;`,
+		},
+		{
+			name: "block comment",
+			input: `const x = 5;
+/* this is a
+   block comment */
+const y = 10;`,
+			expected: `const x = 5;
+
+
+const y = 10;`,
+		},
+		{
+			name:     "inline block comment",
+			input:    `const x = /* comment */ 5;`,
+			expected: `const x =  5;`,
+		},
+
+		// String literal edge cases - critical to test because comment markers inside strings must be preserved
+		{
+			name: "string with comment-like content",
+			input: `const str = "// not a comment";
+const str2 = '/* also not */';`,
+			expected: `const str = "// not a comment";
+const str2 = '/* also not */';`,
+		},
+		{
+			name:     "template literal with comment-like content",
+			input:    "const str = `// not a comment\n/* still not */`;",
+			expected: "const str = `// not a comment\n/* still not */`;",
+		},
+		{
+			// Escaped quotes don't terminate the string, so comment markers after them are still inside the string
+			name: "escaped quotes in string",
+			input: `const str = "He said \"hello\" // comment";
+// another comment`,
+			expected: `const str = "He said \"hello\" // comment";
+`,
+		},
+
+		{
+			name: "mixed comments and code",
+			input: `// header comment
+const x = 5; // inline comment
+/* block
+   comment */
+const y = 10; /* inline block */ const z = 15;`,
+			expected: `
+const x = 5;
+
+
+const y = 10;  const z = 15;`,
+		},
+		{
+			name: "empty lines",
+			input: `const x = 5;
+// comment
+const y = 10;`,
+			expected: `const x = 5;
+
+const y = 10;`,
+		},
+		{
+			name: "comment at end of file",
+			input: `const x = 5;
+// final comment`,
+			expected: `const x = 5;
+`,
+		},
+		{
+			name: "only comments",
+			input: `// comment 1
+/* comment 2 */
+// comment 3`,
+			expected: `
+
+`,
+		},
+
+		{
+			// Backslashes in template literals don't escape quotes (unlike regular strings), so they need special handling
+			name:     "backslash in template literal",
+			input:    "const str = `path\\\\to\\\\file`; // comment",
+			expected: "const str = `path\\\\to\\\\file`;",
+		},
+
+		{
+			// JavaScript doesn't support nested block comments - the first */ closes the outermost /*
+			// This test verifies the parser correctly handles this edge case
+			name: "nested block comments not supported",
+			input: `/* outer /* inner */ still in comment */
+const x = 5;`,
+			expected: ` still in comment */
+const x = 5;`,
+		},
+		{
+			// Regex literals may contain // which must not be mistaken for a line comment
+			name:     "regex literal with escaped slashes",
+			input:    `const re = /https?:\/\//g; // url prefix`,
+			expected: `const re = /https?:\/\//g;`,
+		},
+		{
+			name:     "regex literal with slashes in escapes",
+			input:    `const re = /a\/\/b/; // comment`,
+			expected: `const re = /a\/\/b/;`,
+		},
+		{
+			// Inside a character class, / does not terminate the regex
+			name:     "regex literal with character class",
+			input:    `if (/[/]/.test(s)) return /[/*]+/; // comment`,
+			expected: `if (/[/]/.test(s)) return /[/*]+/;`,
+		},
+		{
+			// After an identifier, / is division, so the following // is a real comment
+			name:     "division followed by comment",
+			input:    `const x = a / b // c`,
+			expected: `const x = a / b`,
+		},
+		{
+			name:     "division after closing paren",
+			input:    `const y = (a + b) / 2 / c; // half`,
+			expected: `const y = (a + b) / 2 / c;`,
+		},
+		{
+			name:     "division after postfix increment",
+			input:    "y = x++ / 2 // e\nw = x-- / 2 / 3 // g",
+			expected: "y = x++ / 2\nw = x-- / 2 / 3",
+		},
+		{
+			name:     "division after keyword as property name",
+			input:    "z = a.return / 2 // f\nv = a?.in / 2 / 3 // h",
+			expected: "z = a.return / 2\nv = a?.in / 2 / 3",
+		},
+		{
+			name:     "regex after a control statement header",
+			input:    "if (x) /a\\/\\//.test(s); // c\nwhile (f(i--)) /b\\/\\//.exec(s) // d\nz = g(x) / 2 // e",
+			expected: "if (x) /a\\/\\//.test(s);\nwhile (f(i--)) /b\\/\\//.exec(s)\nz = g(x) / 2",
+		},
+		{
+			name:     "regex after unary plus",
+			input:    "n = a + +/x\\/\\//.test(s) // i",
+			expected: "n = a + +/x\\/\\//.test(s)",
+		},
+		{
+			// The backtick inside ${} opens a nested template rather than closing the outer one
+			name:     "nested template literal",
+			input:    "const s = `a${`b`}c // text`; // comment",
+			expected: "const s = `a${`b`}c // text`;",
+		},
+		{
+			name:     "template expression with strings and braces",
+			input:    "const s = `${fn({ k: '`' }) /* arg */ + \"}\"} // kept`; // comment",
+			expected: "const s = `${fn({ k: '`' })  + \"}\"} // kept`;",
+		},
+		{
+			name:     "multi-line template with nested template",
+			input:    "const html = `<ul>\n  ${items.map(i => `<li>${i}</li> // kept`) // comment\n  .join('')}\n  // kept\n</ul>`; // comment\nconst x = 1; // one",
+			expected: "const html = `<ul>\n  ${items.map(i => `<li>${i}</li> // kept`)\n  .join('')}\n  // kept\n</ul>`;\nconst x = 1;",
+		},
+		{
+			// Trailing spaces at the end of a line inside a template are part of its value
+			name:     "trailing spaces in template text",
+			input:    "const s = `a  \n${b}  \nc`;  // comment",
+			expected: "const s = `a  \n${b}  \nc`;",
+		},
+		{
+			name: "hashbang preserved",
+			input: `#!/usr/bin/env node // not a comment
+// entry point
+main();`,
+			expected: `#!/usr/bin/env node // not a comment
+
+main();`,
+		},
+		{
+			name:     "private field",
+			input:    `class A { #x = 1; // c }`,
+			expected: `class A { #x = 1;`,
+		},
+		{
+			name: "private field access",
+			input: `class Counter {
+  #count = 0; /* private */
+  half() { return this.#count / 2; } // halve
+}`,
+			expected: `class Counter {
+  #count = 0;
+  half() { return this.#count / 2; }
+}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := RemoveJSComments(tt.input)
+			if result != tt.expected {
+				t.Errorf("RemoveJSComments() failed\nInput:\n%s\n\nExpected:\n%s\n\nGot:\n%s", tt.input, tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestRemoveJSCommentsTypeScript(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name: "typescript interface",
+			input: `// Interface definition
+interface User {
+  name: string; // user's name
+  age: number; /* user's age */
+}`,
+			expected: `
+interface User {
+  name: string;
+  age: number;
+}`,
+		},
+		{
+			// TypeScript generics use < and > which could be confused with comparison operators
+			// This ensures the comment removal doesn't break on angle bracket syntax
+			name: "typescript generics",
+			input: `function map<T, U>(arr: T[]): U[] {
+  // implementation
+  return arr.map(/* ... */);
+}`,
+			expected: `function map<T, U>(arr: T[]): U[] {
+
+  return arr.map();
+}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := RemoveJSComments(tt.input)
+			if result != tt.expected {
+				t.Errorf("RemoveJSComments() failed for TypeScript\nInput:\n%s\n\nExpected:\n%s\n\nGot:\n%s", tt.input, tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestRemoveJSCommentsKeepingDirectives(t *testing.T) {
+	keep := directiveKeeper(jsDirectives, "//", "/*")
+
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name: "ts-expect-error kept",
+			input: `// helper for legacy callers
+// @ts-expect-error legacy signature
+legacy(1, 2);`,
+			expected: `
+// @ts-expect-error legacy signature
+legacy(1, 2);`,
+		},
+		{
+			name: "eslint-disable-next-line kept",
+			input: `// eslint-disable-next-line no-console
+console.log(value); // debug output`,
+			expected: `// eslint-disable-next-line no-console
+console.log(value);`,
+		},
+		{
+			name:     "eslint-disable block kept",
+			input:    "/* eslint-disable */\n/* generated file */\nexport const x = 1;",
+			expected: "/* eslint-disable */\n\nexport const x = 1;",
+		},
+		{
+			name:     "prettier-ignore kept",
+			input:    "// prettier-ignore\nconst matrix = [1,0, 0,1];",
+			expected: "// prettier-ignore\nconst matrix = [1,0, 0,1];",
+		},
+		{
+			name:     "source map reference kept",
+			input:    "export {};\n//# sourceMappingURL=index.js.map",
+			expected: "export {};\n//# sourceMappingURL=index.js.map",
+		},
+		{
+			name:     "pure annotation kept",
+			input:    "export const store = /*#__PURE__*/ createStore(); // singleton",
+			expected: "export const store = /*#__PURE__*/ createStore();",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := removeJSCommentsKeeping(tt.input, keep, false)
+			if result != tt.expected {
+				t.Errorf("removeJSCommentsKeeping() failed\nInput:\n%s\n\nExpected:\n%s\n\nGot:\n%s", tt.input, tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestRemoveJSCommentsKeepingDocs(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name: "jsdoc kept",
+			input: `/**
+ * Adds two numbers.
+ * @param {number} a
+ */
+function add(a, b) {
+  /* fast path */
+  return a + b; // sum
+}`,
+			expected: `/**
+ * Adds two numbers.
+ * @param {number} a
+ */
+function add(a, b) {
+
+  return a + b;
+}`,
+		},
+		{
+			name:     "single-line jsdoc kept, empty block removed",
+			input:    "/** The answer. */\nconst answer = 42; /**/",
+			expected: "/** The answer. */\nconst answer = 42;",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := removeJSCommentsKeeping(tt.input, isJSDocComment, false)
+			if result != tt.expected {
+				t.Errorf("removeJSCommentsKeeping() failed\nInput:\n%s\n\nExpected:\n%s\n\nGot:\n%s", tt.input, tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestRemoveJSXComments(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name: "comment-only expression containers",
+			input: `const App = () => (
+  <div>
+    {/* header */}
+    <h1>Title</h1> {/* inline */}
+    {
+      // multi-line
+    }
+  </div>
+);`,
+			expected: `const App = () => (
+  <div>
+
+    <h1>Title</h1>
+    {
+
+    }
+  </div>
+);`,
+		},
+		{
+			name:     "text with comment markers and quotes",
+			input:    `const link = <a href="/docs">https://example.com/* not */ // also not, don't</a>; // gone`,
+			expected: `const link = <a href="/docs">https://example.com/* not */ // also not, don't</a>;`,
+		},
+		{
+			name: "attributes",
+			input: `<Component
+  prop="//x" // remove
+  other={a /* c */ + 1}
+  /* between */ flag
+  render={() => <span>{'//'}</span>}
+/>;`,
+			expected: `<Component
+  prop="//x"
+  other={a  + 1}
+   flag
+  render={() => <span>{'//'}</span>}
+/>;`,
+		},
+		{
+			name:     "nested elements and fragments",
+			input:    `return <>{items.map(i => <li key={i}>{i} // item</li>)}</>; // list`,
+			expected: `return <>{items.map(i => <li key={i}>{i} // item</li>)}</>;`,
+		},
+		{
+			name:     "templates and regexes in expressions",
+			input:    "<p title={`a ${b} // c`} data-re={/\\/\\//g}>x // y</p>; // z",
+			expected: "<p title={`a ${b} // c`} data-re={/\\/\\//g}>x // y</p>;",
+		},
+		{
+			name:     "comparisons and type parameters are not elements",
+			input:    "const lt = a < b; // less\nconst id = <T,>(x: T) => x; // identity\nconst f = <U extends object>(u: U) => u; // bounded",
+			expected: "const lt = a < b;\nconst id = <T,>(x: T) => x;\nconst f = <U extends object>(u: U) => u;",
+		},
+		{
+			name:     "division after an element",
+			input:    "const half = <Box /> / 2; // odd but valid",
+			expected: "const half = <Box /> / 2;",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := RemoveJSXComments(tt.input)
+
+			if result != tt.expected {
+				t.Errorf("RemoveJSXComments() failed\nInput:\n%s\n\nExpected:\n%s\n\nGot:\n%s", tt.input, tt.expected, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package stripper
+
+// RemoveJSONCComments removes // and /* */ comments from JSON with comments (JSONC) and
+// JSON5. Single-quoted strings are only valid in JSON5, but a quote can never appear
+// outside a string in JSONC either, so both dialects share the same string handling.
+func RemoveJSONCComments(code string) string {
+	runes := []rune(code)
+	result := make([]rune, 0, len(runes))
+	i := 0
+
+	for i < len(runes) {
+		ch := runes[i]
+
+		if ch == '"' || ch == '\'' {
+			result = append(result, ch)
+			i++
+			for i < len(runes) {
+				result = append(result, runes[i])
+				if runes[i] == '\\' && i+1 < len(runes) {
+					// JSON5 allows escaped newlines for line continuation, which this also covers
+					i++
+					result = append(result, runes[i])
+					i++
+					continue
+				}
+				if runes[i] == ch {
+					i++
+					break
+				}
+				i++
+			}
+			continue
+		}
+
+		if ch == '/' && i+1 < len(runes) && runes[i+1] == '/' {
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+			result = trimTrailingBlanks(result)
+			continue
+		}
+
+		if ch == '/' && i+1 < len(runes) && runes[i+1] == '*' {
+			i += 2
+			for i < len(runes) {
+				if i+1 < len(runes) && runes[i] == '*' && runes[i+1] == '/' {
+					i += 2
+					break
+				}
+				i++
+			}
+
+			// Comments after the final value commonly end the line or the file; don't
+			// leave the whitespace that preceded them dangling
+			if i >= len(runes) || runes[i] == '\n' {
+				result = trimTrailingBlanks(result)
+			}
+			continue
+		}
+
+		result = append(result, ch)
+		i++
+	}
+
+	return string(result)
+}
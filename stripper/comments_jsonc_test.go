@@ -0,0 +1,70 @@
+package stripper
+
+import (
+	"testing"
+)
+
+func TestRemoveJSONCComments(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name: "line and block comments",
+			input: `{
+  // compiler options
+  "strict": true, /* always */
+  "target": "es2022"
+}`,
+			expected: `{
+
+  "strict": true,
+  "target": "es2022"
+}`,
+		},
+		{
+			name:     "comment markers inside strings",
+			input:    `{"url": "https://example.com", "glob": "src/**/*.ts"} // done`,
+			expected: `{"url": "https://example.com", "glob": "src/**/*.ts"}`,
+		},
+		{
+			name:     "escaped quote in string",
+			input:    `{"s": "a \" // b"} // comment`,
+			expected: `{"s": "a \" // b"}`,
+		},
+		{
+			// JSON5 allows single-quoted strings
+			name:     "json5 single-quoted string",
+			input:    `{key: 'it\'s // not a comment'} // comment`,
+			expected: `{key: 'it\'s // not a comment'}`,
+		},
+		{
+			name: "line comment after final value",
+			input: `{"a": 1}
+// trailing comment`,
+			expected: `{"a": 1}
+`,
+		},
+		{
+			name:     "block comment after final value",
+			input:    `{"a": 1} /* trailing */`,
+			expected: `{"a": 1}`,
+		},
+		{
+			name:     "unterminated block comment at end of file",
+			input:    `[1, 2] /* never closed`,
+			expected: `[1, 2]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := RemoveJSONCComments(tt.input)
+
+			if result != tt.expected {
+				t.Errorf("RemoveJSONCComments() failed\nInput:\n%s\n\nExpected:\n%s\n\nGot:\n%s", tt.input, tt.expected, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package stripper
+
+import (
+	"strings"
+)
+
+// RemoveJuliaComments removes # line comments and nested #= =# block comments from Julia
+// code while preserving strings, triple-quoted strings, command literals, char literals,
+// and the code inside $(...) interpolations.
+func RemoveJuliaComments(code string) string {
+	shebang, code := splitShebang(code)
+	runes := []rune(code)
+	result, _ := stripJuliaCode(runes, 0, []rune(shebang), false)
+	return string(result)
+}
+
+// stripJuliaCode copies code from runes[i:] to result with comments removed. When
+// inInterpolation is set it stops at the ')' that closes the enclosing $(...) and
+// returns its index, so that strings nested inside interpolations are parsed as code.
+func stripJuliaCode(runes []rune, i int, result []rune, inInterpolation bool) ([]rune, int) {
+	parenDepth := 0
+
+	for i < len(runes) {
+		ch := runes[i]
+
+		switch {
+		case ch == ')' && inInterpolation && parenDepth == 0:
+			return result, i
+		case ch == '(':
+			parenDepth++
+		case ch == ')':
+			parenDepth--
+		case ch == '"' || ch == '`':
+			// Prefixed literals like r"..." and raw"..." are non-standard string literals,
+			// which never interpolate
+			prefixed := i > 0 && (isAlphanumeric(runes[i-1]) || runes[i-1] == '_')
+			delimiter := string(ch)
+			if i+2 < len(runes) && runes[i+1] == ch && runes[i+2] == ch {
+				delimiter = strings.Repeat(string(ch), 3)
+			}
+			result, i = copyJuliaString(runes, i+len(delimiter), append(result, []rune(delimiter)...), delimiter, !prefixed)
+			continue
+		case ch == '\'' && !isJuliaTransposeContext(runes, i):
+			end := i + 1
+			for end < len(runes) && runes[end] != '\'' && runes[end] != '\n' {
+				if runes[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			end = min(end+1, len(runes))
+			result = append(result, runes[i:end]...)
+			i = end
+			continue
+		case ch == '#' && i+1 < len(runes) && runes[i+1] == '=':
+			i = skipNestedBlockComment(runes, i, "#=", "=#")
+			if i >= len(runes) || runes[i] == '\n' {
+				result = trimTrailingBlanks(result)
+			}
+			continue
+		case ch == '#':
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+			result = trimTrailingBlanks(result)
+			continue
+		}
+
+		result = append(result, ch)
+		i++
+	}
+
+	return result, i
+}
+
+// copyJuliaString copies string content from runes[i:] up to and including the closing
+// delimiter, parsing $(...) interpolations as code when interpolate is set.
+func copyJuliaString(runes []rune, i int, result []rune, delimiter string, interpolate bool) ([]rune, int) {
+	closing := []rune(delimiter)
+
+	for i < len(runes) {
+		ch := runes[i]
+
+		if ch == '\\' && i+1 < len(runes) {
+			result = append(result, ch, runes[i+1])
+			i += 2
+			continue
+		}
+
+		if interpolate && ch == '$' && i+1 < len(runes) && runes[i+1] == '(' {
+			result = append(result, '$', '(')
+			result, i = stripJuliaCode(runes, i+2, result, true)
+			if i < len(runes) {
+				result = append(result, runes[i])
+				i++
+			}
+			continue
+		}
+
+		if i+len(closing) <= len(runes) && string(runes[i:i+len(closing)]) == delimiter {
+			result = append(result, closing...)
+			return result, i + len(closing)
+		}
+
+		result = append(result, ch)
+		i++
+	}
+
+	return result, i
+}
+
+// isJuliaTransposeContext reports whether the ' at runes[i] is the adjoint operator
+// (A', x[1]', f(x)') rather than the start of a char literal.
+func isJuliaTransposeContext(runes []rune, i int) bool {
+	if i == 0 {
+		return false
+	}
+	prev := runes[i-1]
+	return isAlphanumeric(prev) || strings.ContainsRune("_)]}.'", prev)
+}
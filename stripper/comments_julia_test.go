@@ -0,0 +1,77 @@
+package stripper
+
+import (
+	"testing"
+)
+
+func TestRemoveJuliaComments(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name: "line comments",
+			input: `# header
+x = 1 # trailing`,
+			expected: `
+x = 1`,
+		},
+		{
+			name: "shebang preserved",
+			input: `#!/usr/bin/env julia
+println(1) # print`,
+			expected: `#!/usr/bin/env julia
+println(1)`,
+		},
+		{
+			name: "nested block comments",
+			input: `#= outer #= inner =# still outer =#
+y = 2 #= a #= b =# c =# + 3`,
+			expected: `
+y = 2  + 3`,
+		},
+		{
+			name:     "hash in strings",
+			input:    `s = "issue #1"; c = '#' # comment`,
+			expected: `s = "issue #1"; c = '#'`,
+		},
+		{
+			name: "triple-quoted string",
+			input: `doc = """
+# not a comment
+#= nor this =#
+""" # comment`,
+			expected: `doc = """
+# not a comment
+#= nor this =#
+"""`,
+		},
+		{
+			name:     "interpolation with nested string",
+			input:    `msg = "value: $(get(d, "#k", 0)) #x" # comment`,
+			expected: `msg = "value: $(get(d, "#k", 0)) #x"`,
+		},
+		{
+			// A quote after an identifier or closing bracket is the adjoint operator
+			name:     "transpose is not a char literal",
+			input:    `B = A' * x[1]' # transpose`,
+			expected: `B = A' * x[1]'`,
+		},
+		{
+			name:     "regex literal",
+			input:    `r = r"#\d+" # comment`,
+			expected: `r = r"#\d+"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := RemoveJuliaComments(tt.input)
+
+			if result != tt.expected {
+				t.Errorf("RemoveJuliaComments() failed\nInput:\n%s\n\nExpected:\n%s\n\nGot:\n%s", tt.input, tt.expected, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package stripper
+
+import (
+	"strings"
+)
+
+// RemoveKotlinComments removes // line comments and nested /* */ block comments from
+// Kotlin code while preserving strings, raw strings ("""..."""), char literals, and
+// the code inside ${...} string templates.
+func RemoveKotlinComments(code string) string {
+	runes := []rune(code)
+	result, _ := stripKotlinCode(runes, 0, make([]rune, 0, len(runes)), false)
+	return string(result)
+}
+
+// stripKotlinCode copies code from runes[i:] to result with comments removed. When
+// inTemplate is set it stops at the '}' that closes the enclosing ${...} template and
+// returns its index, so that strings nested inside templates are parsed as code.
+func stripKotlinCode(runes []rune, i int, result []rune, inTemplate bool) ([]rune, int) {
+	braceDepth := 0
+
+	for i < len(runes) {
+		ch := runes[i]
+
+		switch {
+		case ch == '}' && inTemplate && braceDepth == 0:
+			return result, i
+		case ch == '{':
+			braceDepth++
+		case ch == '}':
+			braceDepth--
+		case ch == '"':
+			raw := i+2 < len(runes) && runes[i+1] == '"' && runes[i+2] == '"'
+			result, i = copyKotlinString(runes, i, result, raw)
+			continue
+		case ch == '\'':
+			end := i + 1
+			for end < len(runes) && runes[end] != '\'' && runes[end] != '\n' {
+				if runes[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			end = min(end+1, len(runes))
+			result = append(result, runes[i:end]...)
+			i = end
+			continue
+		case ch == '/' && i+1 < len(runes) && runes[i+1] == '*':
+			i = skipNestedBlockComment(runes, i, "/*", "*/")
+			if i >= len(runes) || runes[i] == '\n' {
+				result = trimTrailingBlanks(result)
+			}
+			continue
+		case ch == '/' && i+1 < len(runes) && runes[i+1] == '/':
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+			result = trimTrailingBlanks(result)
+			continue
+		}
+
+		result = append(result, ch)
+		i++
+	}
+
+	return result, i
+}
+
+// copyKotlinString copies the string literal starting at runes[i] to result and returns
+// the index just past it. Raw strings have no escapes but, like regular strings, can
+// contain ${...} templates whose code may itself contain quotes.
+func copyKotlinString(runes []rune, i int, result []rune, raw bool) ([]rune, int) {
+	delimiter := `"`
+	if raw {
+		delimiter = `"""`
+	}
+	result = append(result, []rune(delimiter)...)
+	i += len(delimiter)
+
+	for i < len(runes) {
+		ch := runes[i]
+
+		if !raw && ch == '\\' && i+1 < len(runes) {
+			result = append(result, ch, runes[i+1])
+			i += 2
+			continue
+		}
+
+		if ch == '$' && i+1 < len(runes) && runes[i+1] == '{' {
+			result = append(result, '$', '{')
+			result, i = stripKotlinCode(runes, i+2, result, true)
+			if i < len(runes) {
+				result = append(result, runes[i])
+				i++
+			}
+			continue
+		}
+
+		if strings.HasPrefix(string(runes[i:min(i+len(delimiter), len(runes))]), delimiter) {
+			// A raw string may end with extra quotes ("""a""""), where only the final
+			// three close it and the rest belong to the content
+			for raw && i+3 < len(runes) && runes[i+3] == '"' {
+				result = append(result, '"')
+				i++
+			}
+			result = append(result, []rune(delimiter)...)
+			return result, i + len(delimiter)
+		}
+
+		// Regular strings cannot span lines; stop so a malformed string doesn't
+		// swallow the rest of the file
+		if !raw && ch == '\n' {
+			return result, i
+		}
+
+		result = append(result, ch)
+		i++
+	}
+
+	return result, i
+}
+
+// skipNestedBlockComment returns the index just past the block comment starting at
+// runes[i], counting nested open/close pairs so the comment only ends once every nested
+// comment has been closed. Unterminated comments run to the end of the input. The
+// delimiters must be ASCII.
+func skipNestedBlockComment(runes []rune, i int, open, close string) int {
+	depth := 0
+	for i < len(runes) {
+		rest := string(runes[i:min(i+len(open), len(runes))])
+		if rest == open {
+			depth++
+			i += len(open)
+			continue
+		}
+
+		rest = string(runes[i:min(i+len(close), len(runes))])
+		if rest == close {
+			depth--
+			i += len(close)
+			if depth == 0 {
+				return i
+			}
+			continue
+		}
+		i++
+	}
+	return i
+}
@@ -0,0 +1,72 @@
+package stripper
+
+import (
+	"testing"
+)
+
+func TestRemoveKotlinComments(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name: "line comments",
+			input: `// file comment
+val x = 5 // trailing
+val y = 10`,
+			expected: `
+val x = 5
+val y = 10`,
+		},
+		{
+			// Kotlin block comments nest, unlike Java's
+			name: "nested block comments",
+			input: `/* outer /* inner */ still comment */
+fun f() = 1 /* a /* b */ c */ + 2`,
+			expected: `
+fun f() = 1  + 2`,
+		},
+		{
+			name: "raw string with comment markers",
+			input: `val url = """
+    https://example.com // kept
+    /* also kept */
+""" // removed`,
+			expected: `val url = """
+    https://example.com // kept
+    /* also kept */
+"""`,
+		},
+		{
+			name:     "string template with nested quotes",
+			input:    `val s = "value: ${map["//key"]} // text" // comment`,
+			expected: `val s = "value: ${map["//key"]} // text"`,
+		},
+		{
+			name:     "template in raw string",
+			input:    `val s = """${if (a) "}" else "//"} done""" // comment`,
+			expected: `val s = """${if (a) "}" else "//"} done"""`,
+		},
+		{
+			name:     "escaped quote and char literals",
+			input:    `val s = "a \" // b"; val c = '"'; val d = '/' // comment`,
+			expected: `val s = "a \" // b"; val c = '"'; val d = '/'`,
+		},
+		{
+			name:     "raw string ending with extra quotes",
+			input:    `val q = """say ""hi"""" // comment`,
+			expected: `val q = """say ""hi""""`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := RemoveKotlinComments(tt.input)
+
+			if result != tt.expected {
+				t.Errorf("RemoveKotlinComments() failed\nInput:\n%s\n\nExpected:\n%s\n\nGot:\n%s", tt.input, tt.expected, result)
+			}
+		})
+	}
+}
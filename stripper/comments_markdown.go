@@ -0,0 +1,195 @@
+package stripper
+
+import (
+	"strings"
+)
+
+// RemoveMarkdownComments removes HTML comments (<!-- -->) from Markdown prose. Fenced code
+// blocks (``` or ~~~), indented code blocks, and inline code spans are left untouched,
+// since comment syntax there is content being shown. Trailing spaces are kept apart from
+// those before a removed comment, as two trailing spaces make a hard line break, even
+// after a comment.
+// Indented lines after a blank line are taken to be code, as they are outside lists; in
+// a list they continue an item instead, whose comments are then kept too.
+func RemoveMarkdownComments(content string) string {
+	var result strings.Builder
+	inComment := false
+	// The opening fence of the code block being copied, if any
+	fence := ""
+	// An indented code block can't interrupt a paragraph, so it starts only at the
+	// beginning or after a blank line, and blank lines don't end it
+	inIndentedCode := false
+	afterBlank := true
+
+	for line, newline := range splitLines(content) {
+		blank := strings.TrimSpace(line) == ""
+		indentedCode := !inComment && fence == "" && !blank && isMarkdownIndented(line) && (inIndentedCode || afterBlank)
+		if !blank {
+			inIndentedCode = indentedCode
+		}
+		afterBlank = blank
+
+		switch {
+		case indentedCode:
+			result.WriteString(line)
+		case fence != "":
+			result.WriteString(line)
+			if isMarkdownClosingFence(line, fence) {
+				fence = ""
+			}
+		case !inComment && markdownFence(line) != "":
+			fence = markdownFence(line)
+			result.WriteString(line)
+		default:
+			var cleaned string
+			cleaned, inComment = removeMarkdownLineComments(line, inComment)
+			result.WriteString(cleaned)
+		}
+
+		if newline {
+			result.WriteString("\n")
+		}
+	}
+
+	return result.String()
+}
+
+// removeMarkdownLineComments removes the HTML comments from a line of prose, given
+// whether it starts inside a comment, and reports whether it ends inside one.
+func removeMarkdownLineComments(line string, inComment bool) (string, bool) {
+	var cleaned strings.Builder
+	removed := false
+	// Where the last removed comment was in cleaned, to trim the whitespace before it
+	// when nothing but whitespace follows
+	lastComment := 0
+
+	for i := 0; i < len(line); {
+		if inComment {
+			end := strings.Index(line[i:], "-->")
+			if end == -1 {
+				break
+			}
+			i += end + len("-->")
+			inComment = false
+			continue
+		}
+
+		switch {
+		case line[i] == '`':
+			end := markdownCodeSpanEnd(line, i)
+			if end == -1 {
+				// An unmatched run of backticks is literal text
+				end = i
+				for end < len(line) && line[end] == '`' {
+					end++
+				}
+			}
+			cleaned.WriteString(line[i:end])
+			i = end
+			continue
+		case strings.HasPrefix(line[i:], "<!--"):
+			inComment = true
+			removed = true
+			lastComment = cleaned.Len()
+			i += len("<!--")
+			continue
+		}
+
+		cleaned.WriteByte(line[i])
+		i++
+	}
+
+	// Only the gap before a trailing comment is trimmed: spaces after it can be a hard
+	// line break
+	s := cleaned.String()
+	if removed && strings.TrimSpace(s[lastComment:]) == "" {
+		s = strings.TrimRight(s[:lastComment], " \t") + s[lastComment:]
+	}
+	return s, inComment
+}
+
+// markdownCodeSpanEnd returns the index just past the code span opened by the run of
+// backticks at line[i], or -1 if no run of the same length closes it on the line.
+func markdownCodeSpanEnd(line string, i int) int {
+	n := 0
+	for i+n < len(line) && line[i+n] == '`' {
+		n++
+	}
+
+	for j := i + n; j < len(line); {
+		if line[j] != '`' {
+			j++
+			continue
+		}
+		m := 0
+		for j+m < len(line) && line[j+m] == '`' {
+			m++
+		}
+		if m == n {
+			return j + m
+		}
+		j += m
+	}
+	return -1
+}
+
+// markdownFence returns the fence, such as ``` or ~~~~, that opens a fenced code block
+// on line, or an empty string if line doesn't open one. Fences are indented by at most
+// three spaces, and the info string after a backtick fence can't contain backticks.
+func markdownFence(line string) string {
+	rest, ok := trimFenceIndent(line)
+	if !ok || len(rest) < 3 || (rest[0] != '`' && rest[0] != '~') {
+		return ""
+	}
+
+	n := 0
+	for n < len(rest) && rest[n] == rest[0] {
+		n++
+	}
+	if n < 3 || (rest[0] == '`' && strings.Contains(rest[n:], "`")) {
+		return ""
+	}
+	return rest[:n]
+}
+
+// isMarkdownClosingFence reports whether line closes the code block opened by fence: a
+// run of at least as many of the same character, with nothing after it but spaces.
+func isMarkdownClosingFence(line, fence string) bool {
+	rest, ok := trimFenceIndent(line)
+	if !ok {
+		return false
+	}
+
+	n := 0
+	for n < len(rest) && rest[n] == fence[0] {
+		n++
+	}
+	return n >= len(fence) && strings.TrimSpace(rest[n:]) == ""
+}
+
+// isMarkdownIndented reports whether line is indented by four columns or more, with
+// tabs advancing to the next multiple of four, as the lines of indented code blocks are.
+func isMarkdownIndented(line string) bool {
+	column := 0
+	for _, ch := range line {
+		switch ch {
+		case ' ':
+			column++
+		case '\t':
+			column += 4 - column%4
+		default:
+			return column >= 4
+		}
+		if column >= 4 {
+			return true
+		}
+	}
+	return false
+}
+
+// trimFenceIndent removes the up to three spaces that can indent a fence, reporting
+// false if line is indented further.
+func trimFenceIndent(line string) (string, bool) {
+	rest := strings.TrimLeft(line, " ")
+	return rest, len(line)-len(rest) <= 3
+}
@@ -0,0 +1,106 @@
+package stripper
+
+import (
+	"testing"
+)
+
+func TestRemoveMarkdownComments(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name: "comments in prose",
+			input: `<!-- TODO: rewrite -->
+# Title
+
+Some text <!-- note --> continues here. <!-- trailing -->
+More text.`,
+			expected: `
+# Title
+
+Some text  continues here.
+More text.`,
+		},
+		{
+			name:     "comment inside a code fence is kept",
+			input:    "<!-- outside -->\n```html\n<!-- inside -->\n<p>hi</p>\n```\nAfter <!-- removed -->",
+			expected: "\n```html\n<!-- inside -->\n<p>hi</p>\n```\nAfter",
+		},
+		{
+			// A shorter fence or one made of the other character doesn't close the block
+			name:     "tilde and longer fences",
+			input:    "~~~~\n```\n<!-- kept -->\n~~~\n~~~~\n<!-- removed -->\n",
+			expected: "~~~~\n```\n<!-- kept -->\n~~~\n~~~~\n\n",
+		},
+		{
+			name:     "inline code span",
+			input:    "Write `<!-- -->` or ``a `<!--` b`` for comments. <!-- gone -->",
+			expected: "Write `<!-- -->` or ``a `<!--` b`` for comments.",
+		},
+		{
+			name: "multi-line comment",
+			input: `Intro
+<!--
+` + "```" + `
+not a fence inside a comment
+-->
+Outro`,
+			expected: `Intro
+
+
+
+
+Outro`,
+		},
+		{
+			// Two trailing spaces are a hard line break
+			name:     "trailing spaces kept",
+			input:    "Line one  \nLine two <!-- c -->\n",
+			expected: "Line one  \nLine two\n",
+		},
+		{
+			name:     "hard line break after a comment",
+			input:    "Line one <!-- c -->  \nLine two\t<!-- c --><!-- d -->  \nLine three\n",
+			expected: "Line one  \nLine two  \nLine three\n",
+		},
+		{
+			name:     "indented code is not a fence",
+			input:    "    ```\n<!-- removed -->\n",
+			expected: "    ```\n\n",
+		},
+		{
+			name: "comment inside an indented code block is kept",
+			input: `Example: <!-- note -->
+
+    <!-- shown -->
+    <p>hi</p>
+
+	<!-- tab-indented -->
+Text <!-- removed -->`,
+			expected: `Example:
+
+    <!-- shown -->
+    <p>hi</p>
+
+	<!-- tab-indented -->
+Text`,
+		},
+		{
+			name:     "indented line continuing a paragraph is prose",
+			input:    "Intro\n    more <!-- removed -->\n",
+			expected: "Intro\n    more\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := RemoveMarkdownComments(tt.input)
+
+			if result != tt.expected {
+				t.Errorf("RemoveMarkdownComments() failed\nInput:\n%s\n\nExpected:\n%s\n\nGot:\n%s", tt.input, tt.expected, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package stripper
+
+// RemoveNixComments removes # line comments and /* */ block comments, which don't nest,
+// from Nix code while preserving double-quoted strings, indented strings delimited by
+// two single quotes, and the code inside their ${...} antiquotations.
+func RemoveNixComments(code string) string {
+	shebang, code := splitShebang(code)
+	runes := []rune(code)
+	result, _ := stripNixCode(runes, 0, []rune(shebang), false)
+	return string(result)
+}
+
+// stripNixCode copies code from runes[i:] to result with comments removed. When
+// inAntiquotation is set it stops at the '}' that closes the enclosing ${...} and
+// returns its index, so that strings nested inside antiquotations are parsed as code.
+func stripNixCode(runes []rune, i int, result []rune, inAntiquotation bool) ([]rune, int) {
+	braceDepth := 0
+
+	for i < len(runes) {
+		ch := runes[i]
+
+		switch {
+		case ch == '}' && inAntiquotation && braceDepth == 0:
+			return result, i
+		case ch == '{':
+			braceDepth++
+		case ch == '}':
+			braceDepth--
+		case ch == '"':
+			result, i = copyNixString(runes, i, result)
+			continue
+		case ch == '\'' && i+1 < len(runes) && runes[i+1] == '\'' && (i == 0 || !isNixIdentChar(runes[i-1])):
+			// Identifiers can end in primes, as in x'', so only a free '' opens a string
+			result, i = copyNixIndentedString(runes, i, result)
+			continue
+		case ch == '#':
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+			result = trimTrailingBlanks(result)
+			continue
+		case ch == '/' && i+1 < len(runes) && runes[i+1] == '*':
+			i += 2
+			for i < len(runes) && (runes[i] != '*' || i+1 >= len(runes) || runes[i+1] != '/') {
+				i++
+			}
+			i = min(i+2, len(runes))
+			if i >= len(runes) || runes[i] == '\n' {
+				result = trimTrailingBlanks(result)
+			}
+			continue
+		}
+
+		result = append(result, ch)
+		i++
+	}
+
+	return result, i
+}
+
+// isNixIdentChar reports whether r can appear in a Nix identifier after its first
+// character.
+func isNixIdentChar(r rune) bool {
+	return isAlphanumeric(r) || r == '_' || r == '-' || r == '\''
+}
+
+// copyNixString copies the "..." string starting at runes[i] to result and returns the
+// index just past it. Strings can span lines; \ escapes the next character and $${
+// is a literal "${".
+func copyNixString(runes []rune, i int, result []rune) ([]rune, int) {
+	result = append(result, '"')
+	i++
+
+	for i < len(runes) {
+		ch := runes[i]
+
+		switch {
+		case ch == '\\' && i+1 < len(runes):
+			result = append(result, ch, runes[i+1])
+			i += 2
+		case ch == '$' && i+1 < len(runes) && runes[i+1] == '$':
+			result = append(result, ch, '$')
+			i += 2
+		case ch == '$' && i+1 < len(runes) && runes[i+1] == '{':
+			result, i = copyNixAntiquotation(runes, i, result)
+		case ch == '"':
+			return append(result, ch), i + 1
+		default:
+			result = append(result, ch)
+			i++
+		}
+	}
+
+	return result, i
+}
+
+// copyNixIndentedString copies the indented string starting at runes[i] to result and
+// returns the index just past it. Inside, two single quotes followed by a third quote, a
+// '$', or a backslash are escapes rather than the end of the string.
+func copyNixIndentedString(runes []rune, i int, result []rune) ([]rune, int) {
+	result = append(result, '\'', '\'')
+	i += 2
+
+	for i < len(runes) {
+		ch := runes[i]
+
+		switch {
+		case ch == '\'' && i+1 < len(runes) && runes[i+1] == '\'':
+			if i+2 < len(runes) && (runes[i+2] == '\'' || runes[i+2] == '$') {
+				result = append(result, runes[i:i+3]...)
+				i += 3
+				continue
+			}
+			if i+3 < len(runes) && runes[i+2] == '\\' {
+				result = append(result, runes[i:i+4]...)
+				i += 4
+				continue
+			}
+			return append(result, '\'', '\''), i + 2
+		case ch == '$' && i+1 < len(runes) && runes[i+1] == '$':
+			result = append(result, ch, '$')
+			i += 2
+		case ch == '$' && i+1 < len(runes) && runes[i+1] == '{':
+			result, i = copyNixAntiquotation(runes, i, result)
+		default:
+			result = append(result, ch)
+			i++
+		}
+	}
+
+	return result, i
+}
+
+// copyNixAntiquotation copies the ${...} antiquotation starting at runes[i] to result,
+// removing comments from the code inside, and returns the index just past its '}'.
+func copyNixAntiquotation(runes []rune, i int, result []rune) ([]rune, int) {
+	result = append(result, '$', '{')
+	result, i = stripNixCode(runes, i+2, result, true)
+	if i < len(runes) {
+		result = append(result, runes[i])
+		i++
+	}
+	return result, i
+}
@@ -0,0 +1,81 @@
+package stripper
+
+import (
+	"testing"
+)
+
+func TestRemoveNixComments(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name: "line and block comments",
+			input: `# A package
+{ pkgs ? import <nixpkgs> {} }: # arguments
+/* build
+   inputs */
+pkgs.mkShell { buildInputs = [ pkgs.go /* toolchain */ ]; }`,
+			expected: `
+{ pkgs ? import <nixpkgs> {} }:
+
+pkgs.mkShell { buildInputs = [ pkgs.go  ]; }`,
+		},
+		{
+			name:     "shebang",
+			input:    "#!/usr/bin/env nix-shell\n#! nix-shell -i bash\n{ }: # args\n",
+			expected: "#!/usr/bin/env nix-shell\n\n{ }:\n",
+		},
+		{
+			name:     "hash in double-quoted strings",
+			input:    `url = "https://example.com/#readme"; # homepage`,
+			expected: `url = "https://example.com/#readme";`,
+		},
+		{
+			name: "hash in indented strings",
+			input: `script = ''
+  # this is shell, not Nix
+  echo "/* kept */" ''${HOME} '''#quoted'''
+''; # build script`,
+			expected: `script = ''
+  # this is shell, not Nix
+  echo "/* kept */" ''${HOME} '''#quoted'''
+'';`,
+		},
+		{
+			name:     "antiquotation with nested strings",
+			input:    `name = "${pname}-${builtins.replaceStrings ["#"] [""] /* strip */ version}"; # name`,
+			expected: `name = "${pname}-${builtins.replaceStrings ["#"] [""]  version}";`,
+		},
+		{
+			name: "antiquotation inside indented string",
+			input: `text = ''
+  ${lib.concatMapStrings (x: "# ${x}\n") items} # kept
+''; # removed`,
+			expected: `text = ''
+  ${lib.concatMapStrings (x: "# ${x}\n") items} # kept
+'';`,
+		},
+		{
+			name:     "escaped quotes and dollars",
+			input:    `a = "\"# not a comment\" $${literal}"; b = ''#${x}'''${y}''$#''; # comment`,
+			expected: `a = "\"# not a comment\" $${literal}"; b = ''#${x}'''${y}''$#'';`,
+		},
+		{
+			name:     "primed identifiers are not indented strings",
+			input:    `let x'' = 1; in x'' # prime`,
+			expected: `let x'' = 1; in x''`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := RemoveNixComments(tt.input)
+
+			if result != tt.expected {
+				t.Errorf("RemoveNixComments() failed\nInput:\n%s\n\nExpected:\n%s\n\nGot:\n%s", tt.input, tt.expected, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package stripper
+
+import (
+	"strings"
+)
+
+// RemoveOCamlComments removes (* *) comments, which nest and are OCaml's only kind, from
+// OCaml code while preserving string literals, {|...|} and {id|...|id} quoted strings,
+// and character literals such as '"'.
+func RemoveOCamlComments(code string) string {
+	runes := []rune(code)
+	result := make([]rune, 0, len(runes))
+	i := 0
+
+	for i < len(runes) {
+		ch := runes[i]
+
+		switch {
+		case ch == '"':
+			end := ocamlStringEnd(runes, i)
+			result = append(result, runes[i:end]...)
+			i = end
+			continue
+		case ch == '{':
+			if end := ocamlQuotedStringEnd(runes, i); end != -1 {
+				result = append(result, runes[i:end]...)
+				i = end
+				continue
+			}
+		case ch == '\'':
+			if end := ocamlCharLiteralEnd(runes, i); end != -1 {
+				result = append(result, runes[i:end]...)
+				i = end
+				continue
+			}
+		case ch == '(' && i+1 < len(runes) && runes[i+1] == '*':
+			i = ocamlCommentEnd(runes, i)
+			if i >= len(runes) || runes[i] == '\n' {
+				result = trimTrailingBlanks(result)
+			}
+			continue
+		}
+
+		result = append(result, ch)
+		i++
+	}
+
+	return string(result)
+}
+
+// ocamlCommentEnd returns the index just past the comment starting at runes[i], counting
+// nested comments like Rust's block comments. OCaml also lexes string and character
+// literals inside comments, so a "*)" in a commented-out string doesn't end the comment.
+// Unterminated comments run to the end of the input.
+func ocamlCommentEnd(runes []rune, i int) int {
+	depth := 0
+	for i < len(runes) {
+		ch := runes[i]
+
+		switch {
+		case ch == '(' && i+1 < len(runes) && runes[i+1] == '*':
+			depth++
+			i += 2
+		case ch == '*' && i+1 < len(runes) && runes[i+1] == ')':
+			depth--
+			i += 2
+			if depth == 0 {
+				return i
+			}
+		case ch == '"':
+			i = ocamlStringEnd(runes, i)
+		case ch == '{' && ocamlQuotedStringEnd(runes, i) != -1:
+			i = ocamlQuotedStringEnd(runes, i)
+		case ch == '\'' && ocamlCharLiteralEnd(runes, i) != -1:
+			i = ocamlCharLiteralEnd(runes, i)
+		default:
+			i++
+		}
+	}
+	return i
+}
+
+// ocamlStringEnd returns the index just past the string literal starting at runes[i].
+// Strings can span lines; unterminated strings run to the end of the input.
+func ocamlStringEnd(runes []rune, i int) int {
+	for i++; i < len(runes); i++ {
+		switch runes[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1
+		}
+	}
+	return len(runes)
+}
+
+// ocamlQuotedStringEnd returns the index just past the quoted string starting at
+// runes[i], such as {|raw "text"|} or {sql|...|sql}, or -1 if the brace doesn't open a
+// terminated quoted string. Quoted strings have no escapes and end only at the closing
+// delimiter with the same identifier.
+func ocamlQuotedStringEnd(runes []rune, i int) int {
+	j := i + 1
+	for j < len(runes) && (runes[j] >= 'a' && runes[j] <= 'z' || runes[j] == '_') {
+		j++
+	}
+	if j >= len(runes) || runes[j] != '|' {
+		return -1
+	}
+
+	closing := "|" + string(runes[i+1:j]) + "}"
+	rest := string(runes[j+1:])
+	idx := strings.Index(rest, closing)
+	if idx == -1 {
+		return -1
+	}
+	return j + 1 + len([]rune(rest[:idx])) + len(closing)
+}
+
+// ocamlCharLiteralEnd returns the index just past the character literal starting at
+// runes[i], or -1 if the quote doesn't open one, as in type variables ('a) and names
+// with primes (x').
+func ocamlCharLiteralEnd(runes []rune, i int) int {
+	if i > 0 && (isAlphanumeric(runes[i-1]) || runes[i-1] == '_' || runes[i-1] == '\'') {
+		return -1
+	}
+
+	if i+2 < len(runes) && runes[i+1] != '\\' && runes[i+1] != '\n' && runes[i+2] == '\'' {
+		return i + 3
+	}
+
+	// Escapes are at most a few characters long ('\n', '\'', '\065', '\xFF', '\o101')
+	if i+1 < len(runes) && runes[i+1] == '\\' {
+		for j := i + 3; j < len(runes) && j <= i+6; j++ {
+			if runes[j] == '\'' {
+				return j + 1
+			}
+		}
+	}
+	return -1
+}
@@ -0,0 +1,77 @@
+package stripper
+
+import (
+	"testing"
+)
+
+func TestRemoveOCamlComments(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name: "comments",
+			input: `(* Module header *)
+let x = 5 (* trailing *)
+let y = (* inline *) 10`,
+			expected: `
+let x = 5
+let y =  10`,
+		},
+		{
+			name: "nested comments",
+			input: `(* outer (* inner *) still comment *)
+let f x = x (* a (* b *) c *) + 1`,
+			expected: `
+let f x = x  + 1`,
+		},
+		{
+			name:     "comment markers in strings",
+			input:    `let s = "(* not a comment *)" ^ "a \" (* b" (* comment *)`,
+			expected: `let s = "(* not a comment *)" ^ "a \" (* b"`,
+		},
+		{
+			// OCaml lexes strings inside comments, so their *) doesn't close the comment
+			name:     "string inside a comment",
+			input:    `let a = 1 (* see "*)" here *) + 2`,
+			expected: `let a = 1  + 2`,
+		},
+		{
+			name: "quoted strings",
+			input: `let q = {|(* raw "text" *)|} (* comment *)
+let sql = {sql|SELECT "(*" |} FROM t|sql} (* query *)`,
+			expected: `let q = {|(* raw "text" *)|}
+let sql = {sql|SELECT "(*" |} FROM t|sql}`,
+		},
+		{
+			name:     "character literals and type variables",
+			input:    `let q : 'a list = ['"'; '\''; '\n'] and x' = '(' (* comment *)`,
+			expected: `let q : 'a list = ['"'; '\''; '\n'] and x' = '('`,
+		},
+		{
+			name:     "record braces are not quoted strings",
+			input:    `let r = { name = "a"; age = 1 } (* record *)`,
+			expected: `let r = { name = "a"; age = 1 }`,
+		},
+		{
+			name: "multi-line comment",
+			input: `(**
+   Documentation for f.
+   @param x the input *)
+let f x = x`,
+			expected: `
+let f x = x`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := RemoveOCamlComments(tt.input)
+
+			if result != tt.expected {
+				t.Errorf("RemoveOCamlComments() failed\nInput:\n%s\n\nExpected:\n%s\n\nGot:\n%s", tt.input, tt.expected, result)
+			}
+		})
+	}
+}
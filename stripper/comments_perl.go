@@ -0,0 +1,287 @@
+package stripper
+
+import (
+	"strings"
+	"unicode"
+)
+
+// perlQuoteOperators lists the quote-like operators and how many delimited parts each
+// takes; s///, tr///, and y/// have a pattern and a replacement.
+var perlQuoteOperators = map[string]int{
+	"q": 1, "qq": 1, "qw": 1, "qr": 1, "m": 1,
+	"s": 2, "tr": 2, "y": 2,
+}
+
+// perlBracketClosers maps bracketing delimiters to their closing character; Perl counts
+// nested pairs of these, while every other delimiter simply closes with itself.
+var perlBracketClosers = map[rune]rune{'(': ')', '[': ']', '{': '}', '<': '>'}
+
+// RemovePerlComments removes # comments and POD documentation blocks from Perl code
+// while preserving strings, quote-like operators (q(), qq{}, qw//, s###), heredocs,
+// array last-index expressions ($#array, $#{ref}), the shebang line, and anything after
+// __END__/__DATA__.
+func RemovePerlComments(code string) string {
+	shebang, code := splitShebang(code)
+	runes := []rune(code)
+	result := []rune(shebang)
+	var pendingHeredocs []shellHeredoc
+	i := 0
+
+	for i < len(runes) {
+		ch := runes[i]
+		atLineStart := i == 0 || runes[i-1] == '\n'
+
+		if atLineStart {
+			line := perlLineAt(runes, i)
+
+			// Everything after __END__ or __DATA__ is data read by the program, not code
+			if trimmed := strings.TrimSpace(line); trimmed == "__END__" || trimmed == "__DATA__" {
+				result = append(result, runes[i:]...)
+				break
+			}
+
+			// POD runs from any =directive at column zero through the next =cut line
+			if ch == '=' && i+1 < len(runes) && unicode.IsLetter(runes[i+1]) {
+				for i < len(runes) {
+					line := perlLineAt(runes, i)
+					i += len([]rune(line))
+					if i < len(runes) {
+						result = append(result, '\n')
+						i++
+					}
+					if strings.HasPrefix(line, "=cut") {
+						break
+					}
+				}
+				continue
+			}
+		}
+
+		if ch == '\n' {
+			result = append(result, ch)
+			i++
+
+			// Heredoc bodies are data, so they're copied verbatim through their terminator
+			for _, heredoc := range pendingHeredocs {
+				for i < len(runes) {
+					line := perlLineAt(runes, i)
+					result = append(result, []rune(line)...)
+					i += len([]rune(line))
+					if i < len(runes) {
+						result = append(result, '\n')
+						i++
+					}
+
+					if heredoc.stripTabs {
+						line = strings.TrimLeft(line, " \t")
+					}
+					if line == heredoc.delimiter {
+						break
+					}
+				}
+			}
+			pendingHeredocs = nil
+			continue
+		}
+
+		if ch == '"' || ch == '\'' || ch == '`' {
+			result, i = copyPerlDelimited(runes, i+1, append(result, ch), ch)
+			continue
+		}
+
+		// $#array and $#{ref} are last-index expressions, and ${#...} is not a comment either
+		if ch == '$' && i+1 < len(runes) && (runes[i+1] == '#' || (runes[i+1] == '{' && i+2 < len(runes) && runes[i+2] == '#')) {
+			end := i + 2
+			if runes[i+1] == '{' {
+				end = i + 3
+			}
+			result = append(result, runes[i:end]...)
+			i = end
+			continue
+		}
+
+		if ch == '<' && i+2 < len(runes) && runes[i+1] == '<' {
+			if heredoc, end, ok := parsePerlHeredoc(runes, i+2); ok {
+				result = append(result, runes[i:end]...)
+				i = end
+				pendingHeredocs = append(pendingHeredocs, heredoc)
+				continue
+			}
+		}
+
+		if isPerlWordStart(runes, i) {
+			end := i
+			for end < len(runes) && (isAlphanumeric(runes[end]) || runes[end] == '_') {
+				end++
+			}
+			word := string(runes[i:end])
+
+			if parts, ok := perlQuoteOperators[word]; ok {
+				if next, j, ok := copyPerlQuoteOperator(runes, end, append(result, runes[i:end]...), parts); ok {
+					result, i = next, j
+					continue
+				}
+			}
+
+			result = append(result, runes[i:end]...)
+			i = end
+			continue
+		}
+
+		if ch == '#' {
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+			result = trimTrailingBlanks(result)
+			continue
+		}
+
+		result = append(result, ch)
+		i++
+	}
+
+	return string(result)
+}
+
+// copyPerlQuoteOperator copies the delimited parts of a quote-like operator whose name
+// ends just before runes[i], followed by any modifier letters. It reports false when the
+// word is not actually used as an operator (e.g. the hash key in "s => 1").
+func copyPerlQuoteOperator(runes []rune, i int, result []rune, parts int) ([]rune, int, bool) {
+	j := i
+	for j < len(runes) && (runes[j] == ' ' || runes[j] == '\t') {
+		j++
+	}
+	if j >= len(runes) {
+		return nil, 0, false
+	}
+
+	open := runes[j]
+	// A # after whitespace starts a comment, and these characters mean the word is a
+	// plain identifier, hash key, or function call rather than an operator
+	if (open == '#' && j > i) || unicode.IsSpace(open) || isAlphanumeric(open) || open == '_' || strings.ContainsRune("=,;)", open) {
+		return nil, 0, false
+	}
+
+	result = append(result, runes[i:j+1]...)
+	result, j = copyPerlDelimited(runes, j+1, result, open)
+
+	if parts == 2 {
+		if _, bracketed := perlBracketClosers[open]; bracketed {
+			// s{...}{...} may put whitespace between the two bracketed parts
+			for j < len(runes) && unicode.IsSpace(runes[j]) {
+				result = append(result, runes[j])
+				j++
+			}
+			if j < len(runes) {
+				open = runes[j]
+				result = append(result, open)
+				result, j = copyPerlDelimited(runes, j+1, result, open)
+			}
+		} else {
+			// s/a/b/ shares the middle delimiter between both parts
+			result, j = copyPerlDelimited(runes, j, result, open)
+		}
+	}
+
+	for j < len(runes) && unicode.IsLetter(runes[j]) {
+		result = append(result, runes[j])
+		j++
+	}
+
+	return result, j, true
+}
+
+// copyPerlDelimited copies quoted content up to and including the delimiter that closes
+// open, honoring backslash escapes and nesting for bracketing delimiters.
+func copyPerlDelimited(runes []rune, i int, result []rune, open rune) ([]rune, int) {
+	closer, bracketed := perlBracketClosers[open]
+	if !bracketed {
+		closer = open
+	}
+	depth := 0
+
+	for i < len(runes) {
+		ch := runes[i]
+		result = append(result, ch)
+		i++
+
+		switch {
+		case ch == '\\' && i < len(runes):
+			result = append(result, runes[i])
+			i++
+		case bracketed && ch == open:
+			depth++
+		case ch == closer && depth > 0:
+			depth--
+		case ch == closer:
+			return result, i
+		}
+	}
+
+	return result, i
+}
+
+// parsePerlHeredoc reads a heredoc terminator starting just after "<<". Unlike shell,
+// a bare terminator must follow << immediately, which keeps "1 << 2" a shift.
+func parsePerlHeredoc(runes []rune, start int) (shellHeredoc, int, bool) {
+	var heredoc shellHeredoc
+	i := start
+
+	// <<~ allows the body and terminator to be indented
+	if i < len(runes) && runes[i] == '~' {
+		heredoc.stripTabs = true
+		i++
+	}
+	if i >= len(runes) {
+		return heredoc, start, false
+	}
+
+	if quote := runes[i]; quote == '"' || quote == '\'' {
+		end := i + 1
+		for end < len(runes) && runes[end] != quote && runes[end] != '\n' {
+			end++
+		}
+		if end >= len(runes) || runes[end] != quote {
+			return heredoc, start, false
+		}
+		heredoc.delimiter = string(runes[i+1 : end])
+		return heredoc, end + 1, true
+	}
+
+	end := i
+	for end < len(runes) && (isAlphanumeric(runes[end]) || runes[end] == '_') {
+		end++
+	}
+	if end == i || unicode.IsDigit(runes[i]) {
+		return heredoc, start, false
+	}
+
+	heredoc.delimiter = string(runes[i:end])
+	return heredoc, end, true
+}
+
+// isPerlWordStart reports whether a bareword starts at runes[i]. Words preceded by a
+// sigil or an arrow are variables and method names, never quote-like operators.
+func isPerlWordStart(runes []rune, i int) bool {
+	if !(unicode.IsLetter(runes[i]) || runes[i] == '_') {
+		return false
+	}
+	if i == 0 {
+		return true
+	}
+
+	prev := runes[i-1]
+	if isAlphanumeric(prev) || prev == '_' || strings.ContainsRune("$@%&*:", prev) {
+		return false
+	}
+	return !(prev == '>' && i >= 2 && runes[i-2] == '-')
+}
+
+// perlLineAt returns the text of the line starting at runes[i], without its newline.
+func perlLineAt(runes []rune, i int) string {
+	end := i
+	for end < len(runes) && runes[end] != '\n' {
+		end++
+	}
+	return string(runes[i:end])
+}
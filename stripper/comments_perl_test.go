@@ -0,0 +1,117 @@
+package stripper
+
+import (
+	"testing"
+)
+
+func TestRemovePerlComments(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name: "line comments",
+			input: `# header
+my $x = 1; # trailing`,
+			expected: `
+my $x = 1;`,
+		},
+		{
+			name: "shebang preserved",
+			input: `#!/usr/bin/perl -w
+# setup
+use strict;`,
+			expected: `#!/usr/bin/perl -w
+
+use strict;`,
+		},
+		{
+			name: "pod block",
+			input: `my $a = 1;
+=pod
+
+Documentation with # hashes.
+
+=cut
+my $b = 2;`,
+			expected: `my $a = 1;
+
+
+
+
+
+my $b = 2;`,
+		},
+		{
+			name: "pod head directive",
+			input: `=head1 NAME
+
+Foo - bar
+=cut
+sub foo { 1 }`,
+			expected: `
+
+
+
+sub foo { 1 }`,
+		},
+		{
+			// $#array is the last index of @array, not the start of a comment
+			name:     "array last index",
+			input:    `for my $i (0 .. $#array) { print $#{$ref} } # loop`,
+			expected: `for my $i (0 .. $#array) { print $#{$ref} }`,
+		},
+		{
+			name:     "strings with hash",
+			input:    `print "a # b", 'c # d'; # comment`,
+			expected: `print "a # b", 'c # d';`,
+		},
+		{
+			name:     "quote-like operators",
+			input:    `my @w = qw(a #b c); my $s = q{x # {y}}; my $t = qq#not#; # comment`,
+			expected: `my @w = qw(a #b c); my $s = q{x # {y}}; my $t = qq#not#;`,
+		},
+		{
+			name:     "substitution with hash delimiter",
+			input:    `$path =~ s#/usr#/opt#g; # relocate`,
+			expected: `$path =~ s#/usr#/opt#g;`,
+		},
+		{
+			// A word followed by => is a hash key, not a quote-like operator
+			name:     "fat comma keys",
+			input:    `my %h = (s => 1, y => 2); # comment`,
+			expected: `my %h = (s => 1, y => 2);`,
+		},
+		{
+			name: "heredoc",
+			input: `print <<"EOT"; # comment
+# not a comment
+EOT
+print 1 << 2; # shift`,
+			expected: `print <<"EOT";
+# not a comment
+EOT
+print 1 << 2;`,
+		},
+		{
+			name: "data section preserved",
+			input: `print <DATA>; # read
+__DATA__
+# data line`,
+			expected: `print <DATA>;
+__DATA__
+# data line`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := RemovePerlComments(tt.input)
+
+			if result != tt.expected {
+				t.Errorf("RemovePerlComments() failed\nInput:\n%s\n\nExpected:\n%s\n\nGot:\n%s", tt.input, tt.expected, result)
+			}
+		})
+	}
+}
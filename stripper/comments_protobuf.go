@@ -0,0 +1,9 @@
+package stripper
+
+// RemoveProtoComments removes // and /* */ comments from Protocol Buffers definitions
+// using the JavaScript state machine. Protobuf strings are single- or double-quoted like
+// JavaScript's, and outside comments a '/' or a backtick only appears inside strings, so
+// regex and template literal handling never applies.
+func RemoveProtoComments(content string) string {
+	return RemoveJSComments(content)
+}
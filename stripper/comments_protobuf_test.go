@@ -0,0 +1,82 @@
+package stripper
+
+import (
+	"testing"
+)
+
+func TestRemoveProtoComments(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name: "line and block comments",
+			input: `// Package comment
+syntax = "proto3";
+
+/* The user record */
+message User {
+  string name = 1; // display name
+  int64 id = 2 /* unique */;
+}`,
+			expected: `
+syntax = "proto3";
+
+
+message User {
+  string name = 1;
+  int64 id = 2 ;
+}`,
+		},
+		{
+			name: "comment markers in string default values",
+			input: `syntax = "proto2";
+
+message Link {
+  optional string url = 1 [default = "https://example.com"]; // homepage
+  optional string glob = 2 [default = '/* not a comment */'];
+  optional string escaped = 3 [default = "say \"// hi\""]; /* trailing */
+}`,
+			expected: `syntax = "proto2";
+
+message Link {
+  optional string url = 1 [default = "https://example.com"];
+  optional string glob = 2 [default = '/* not a comment */'];
+  optional string escaped = 3 [default = "say \"// hi\""];
+}`,
+		},
+		{
+			name: "option values",
+			input: `option go_package = "example.com/api/v1;apiv1"; // generated code
+option (custom.path) = "/v1/{name=users/*}"; // route`,
+			expected: `option go_package = "example.com/api/v1;apiv1";
+option (custom.path) = "/v1/{name=users/*}";`,
+		},
+		{
+			name: "multi-line block comment keeps the line structure",
+			input: `enum Status {
+  /*
+   * Unknown status.
+   */
+  STATUS_UNSPECIFIED = 0;
+}`,
+			expected: `enum Status {
+
+
+
+  STATUS_UNSPECIFIED = 0;
+}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := RemoveProtoComments(tt.input)
+
+			if result != tt.expected {
+				t.Errorf("RemoveProtoComments() failed\nInput:\n%s\n\nExpected:\n%s\n\nGot:\n%s", tt.input, tt.expected, result)
+			}
+		})
+	}
+}
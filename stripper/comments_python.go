@@ -0,0 +1,208 @@
+package stripper
+
+import (
+	"io"
+	"iter"
+	"regexp"
+	"strings"
+)
+
+// pythonDirectives are the default -keep-directives prefixes for Python: type comments,
+// linter and type checker suppressions, coverage pragmas, and formatter switches.
+var pythonDirectives = []string{
+	"type:", "noqa", "NOQA", "pragma:", "pylint:", "mypy:", "pyright:", "fmt:", "isort:", "ruff:",
+}
+
+// pythonCodingCookie matches a PEP 263 source encoding declaration such as
+// # -*- coding: utf-8 -*-, which Python only honors on the first two lines.
+var pythonCodingCookie = regexp.MustCompile(`^[ \t\f]*#.*?coding[:=][ \t]*[-\w.]+`)
+
+// RemovePythonComments removes # comments from Python source, preserving strings,
+// the shebang, and the coding declaration.
+func RemovePythonComments(content string) string {
+	return removePythonCommentsKeeping(content, nil)
+}
+
+// removePythonCommentsKeeping removes comments like RemovePythonComments, except those for
+// which keep returns true. keep receives the comment from its # to the end of the line.
+// A nil keep removes every comment. The shebang and a coding declaration are always kept,
+// since changing either changes how the file is run or decoded.
+func removePythonCommentsKeeping(content string, keep func(comment string) bool) string {
+	var result strings.Builder
+	removePythonCommentLines(&result, splitLines(content), keep)
+	return result.String()
+}
+
+// removePythonCommentLines writes lines to result with comments removed like
+// removePythonCommentsKeeping.
+func removePythonCommentLines(result io.StringWriter, lines iter.Seq2[string, bool], keep func(comment string) bool) {
+	// The strings open at this point, innermost last. Triple-quoted strings can span
+	// lines, and the replacement fields of f-strings are code that can hold further
+	// strings, including nested f-strings that reuse the same quotes (Python 3.12+).
+	var open []pythonString
+
+	i := -1
+	for line, newline := range lines {
+		i++
+		// The shebang is kept as is, like the coding declaration that may follow it
+		if i == 0 && strings.HasPrefix(line, "#!") {
+			result.WriteString(line)
+			if newline {
+				result.WriteString("\n")
+			}
+			continue
+		}
+
+		if len(open) == 0 && i < 2 && pythonCodingCookie.MatchString(line) {
+			result.WriteString(line)
+			if newline {
+				result.WriteString("\n")
+			}
+			continue
+		}
+
+		var cleaned strings.Builder
+		escaped := false
+		j := 0
+		runes := []rune(line)
+
+		for j < len(runes) {
+			ch := runes[j]
+
+			// The text of the innermost string, unless one of its replacement fields is open
+			if n := len(open); n > 0 && !open[n-1].inField {
+				str := &open[n-1]
+				switch {
+				case escaped:
+					escaped = false
+				case ch == '\\':
+					// A backslash keeps the next quote from closing the string even with an
+					// r or rb prefix: Python's tokenizer treats r"\"" as a complete string
+					// holding \" and r"\" as unterminated, so prefixes do not change where
+					// a string ends
+					escaped = true
+				case str.closesAt(runes, j):
+					width := str.width()
+					cleaned.WriteString(string(runes[j : j+width]))
+					j += width
+					open = open[:n-1]
+					continue
+				case str.format && ch == '{' && j+1 < len(runes) && runes[j+1] == '{':
+					// {{ is an escaped brace rather than a replacement field
+					cleaned.WriteString("{{")
+					j += 2
+					continue
+				case str.format && ch == '{':
+					str.inField = true
+				}
+				cleaned.WriteRune(ch)
+				j++
+				continue
+			}
+
+			// A quote in code opens a string; ''' and """ open triple-quoted strings,
+			// which can span multiple lines
+			if ch == '"' || ch == '\'' {
+				str := pythonString{
+					quote:  ch,
+					triple: j+2 < len(runes) && runes[j+1] == ch && runes[j+2] == ch,
+					format: isFormatStringPrefix(runes[:j]),
+				}
+				open = append(open, str)
+				cleaned.WriteString(string(runes[j : j+str.width()]))
+				j += str.width()
+				continue
+			}
+
+			// Inside a replacement field only the brace closing it matters; a # there is
+			// kept, since it can't start a comment that cuts the enclosing string short
+			if n := len(open); n > 0 {
+				field := &open[n-1]
+				if ch == '{' {
+					field.braces++
+				} else if ch == '}' && field.braces > 0 {
+					field.braces--
+				} else if ch == '}' {
+					field.inField = false
+				}
+				cleaned.WriteRune(ch)
+				j++
+				continue
+			}
+
+			// '#' outside of strings marks the start of a comment - discard rest of line
+			// unless it is kept
+			if ch == '#' {
+				if comment := string(runes[j:]); keep != nil && keep(comment) {
+					cleaned.WriteString(comment)
+				}
+				break
+			}
+
+			cleaned.WriteRune(ch)
+			j++
+		}
+
+		// Only triple-quoted strings continue on the next line; an unterminated
+		// single-quoted string ends with its line
+		for len(open) > 0 && !open[len(open)-1].triple {
+			open = open[:len(open)-1]
+		}
+
+		if len(open) > 0 {
+			// The line ends inside a multiline string, so its tail is string content and
+			// is kept as is
+			result.WriteString(cleaned.String())
+		} else {
+			// Remove trailing whitespace to avoid leaving empty spaces where comments were
+			trimmed := strings.TrimRight(cleaned.String(), " \t")
+			result.WriteString(trimmed)
+		}
+
+		if newline {
+			result.WriteString("\n")
+		}
+	}
+}
+
+// pythonString is a string literal open at some point in the source: its quote, whether
+// it is triple-quoted, and whether it is an f-string. For f-strings it also records
+// whether a replacement field is open and how many braces its expression has opened.
+type pythonString struct {
+	quote   rune
+	triple  bool
+	format  bool
+	inField bool
+	braces  int
+}
+
+// width returns the length of the string's opening and closing delimiters.
+func (s pythonString) width() int {
+	if s.triple {
+		return 3
+	}
+	return 1
+}
+
+// closesAt reports whether the string's closing delimiter starts at runes[j].
+func (s pythonString) closesAt(runes []rune, j int) bool {
+	if runes[j] != s.quote {
+		return false
+	}
+	return !s.triple || j+2 < len(runes) && runes[j+1] == s.quote && runes[j+2] == s.quote
+}
+
+// isFormatStringPrefix reports whether a string literal whose quote follows before has
+// an f prefix, or the t prefix of Python 3.14 template strings, so that its replacement
+// fields hold code.
+func isFormatStringPrefix(before []rune) bool {
+	k := len(before)
+	for k > 0 && len(before)-k < 2 && strings.ContainsRune("rRbBuUfFtT", before[k-1]) {
+		k--
+	}
+	// The letters must be the whole prefix, not the end of a name such as elif
+	if k > 0 && (isAlphanumeric(before[k-1]) || before[k-1] == '_') {
+		return false
+	}
+	return strings.ContainsAny(string(before[k:]), "fFtT")
+}
@@ -0,0 +1,295 @@
+package stripper
+
+import (
+	"testing"
+)
+
+func TestRemovePythonComments(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name: "single line comment",
+			input: `x = 5  # this is a comment
+y = 10`,
+			expected: `x = 5
+y = 10`,
+		},
+		{
+			// Empty line preserved at start because Python comment removal must maintain line structure
+			// for any leading comments to avoid shifting code positioning
+			name: "comment at start of line",
+			input: `# header comment
+x = 5
+# another comment
+y = 10`,
+			expected: `
+x = 5
+
+y = 10`,
+		},
+		{
+			name: "shebang preserved",
+			input: `#!/usr/bin/env python3
+# module comment
+import sys  # for argv`,
+			expected: `#!/usr/bin/env python3
+
+import sys`,
+		},
+		{
+			name: "string with hash",
+			input: `s = "# not a comment"
+s2 = '# also not'`,
+			expected: `s = "# not a comment"
+s2 = '# also not'`,
+		},
+		{
+			// Python's triple-quoted strings can span multiple lines and contain ANY characters
+			// including # symbols, which must not be treated as comments
+			name: "multiline string triple quotes",
+			input: `s = """This is a
+multiline string
+# not a comment"""
+x = 5`,
+			expected: `s = """This is a
+multiline string
+# not a comment"""
+x = 5`,
+		},
+		{
+			// Python allows both """ and ''' for multiline strings - both must be handled identically
+			name: "multiline string single quotes",
+			input: `s = '''This is a
+multiline string
+# not a comment'''
+x = 5`,
+			expected: `s = '''This is a
+multiline string
+# not a comment'''
+x = 5`,
+		},
+		{
+			// Docstrings are semantically documentation but syntactically strings,
+			// so they should be preserved even though they describe the code
+			name: "docstring",
+			input: `def foo():
+    """
+    This is a docstring
+    # not a comment
+    """
+    x = 5`,
+			expected: `def foo():
+    """
+    This is a docstring
+    # not a comment
+    """
+    x = 5`,
+		},
+		{
+			// Triple quotes can also be used inline (single line), and comments after them
+			// on the same line should still be removed
+			name:     "inline multiline string",
+			input:    `x = """single line""" # comment`,
+			expected: `x = """single line"""`,
+		},
+		{
+			// Escaped quotes inside strings don't terminate the string, so # after them
+			// is still inside the string and not a comment
+			name: "escaped quotes in string",
+			input: `s = "He said \"hello\" # comment"
+# another comment`,
+			expected: `s = "He said \"hello\" # comment"
+`,
+		},
+		{
+			name: "mixed strings and comments",
+			input: `# header
+x = "string"  # comment
+y = 'another'
+# footer`,
+			expected: `
+x = "string"
+y = 'another'
+`,
+		},
+		{
+			// Empty strings are still strings and must be properly tracked
+			// to avoid treating subsequent # as inside a string
+			name: "empty string",
+			input: `s = ""  # comment
+s2 = ''`,
+			expected: `s = ""
+s2 = ''`,
+		},
+		{
+			name: "comment at end of file",
+			input: `x = 5
+# final comment`,
+			expected: `x = 5
+`,
+		},
+		{
+			// When all content is comments, result should be empty lines matching the structure,
+			// testing the edge case of no actual code
+			name: "only comments",
+			input: `# comment 1
+# comment 2
+# comment 3`,
+			expected: `
+
+`,
+		},
+		{
+			// Backslashes in Python strings are escape sequences, but \\ is a literal backslash,
+			// not an escape for the next character - important for Windows paths
+			name:     "backslash in string",
+			input:    `s = "path\\to\\file"  # comment`,
+			expected: `s = "path\\to\\file"`,
+		},
+		{
+			// f-strings (formatted string literals) are still strings despite the f prefix,
+			// and # inside them is not a comment
+			name: "hash in f-string",
+			input: `s = f"value: {x}"  # comment
+s2 = f"# not a comment"`,
+			expected: `s = f"value: {x}"
+s2 = f"# not a comment"`,
+		},
+		{
+			// A line ending in exactly an opening """ must start the multiline string
+			name: "triple quote at end of line",
+			input: `x = """
+# inside string
+"""  # comment
+y = 1`,
+			expected: `x = """
+# inside string
+"""
+y = 1`,
+		},
+		{
+			// Raw strings keep the backslash, but it still stops the quote from closing them
+			name:     "raw string with escaped quote",
+			input:    `p = r"\"# not a comment" + R'\\' # comment`,
+			expected: `p = r"\"# not a comment" + R'\\'`,
+		},
+		{
+			name:     "raw bytes string with hash",
+			input:    `data = rb"\x00 # keep" + Br'#' # comment`,
+			expected: `data = rb"\x00 # keep" + Br'#'`,
+		},
+		{
+			// Since Python 3.12 a replacement field can reuse the enclosing quotes
+			name:     "replacement field containing quotes",
+			input:    `s = f"{d["#key"]} # kept {", ".join(xs)}" # comment`,
+			expected: `s = f"{d["#key"]} # kept {", ".join(xs)}"`,
+		},
+		{
+			name:     "nested f-strings",
+			input:    `s = f"{f"#{f'{x}#'}"} # kept" + f"{'#'}" # comment`,
+			expected: `s = f"{f"#{f'{x}#'}"} # kept" + f"{'#'}"`,
+		},
+		{
+			name:     "hash in replacement field",
+			input:    `s = f"{x # not a comment}" # comment`,
+			expected: `s = f"{x # not a comment}"`,
+		},
+		{
+			// {{ is a literal brace, and the format spec can hold nested fields and #
+			name:     "escaped braces and format specs",
+			input:    `s = f"{{x}} {n:#x} {v:>{width}.{p}f} {{" # comment`,
+			expected: `s = f"{{x}} {n:#x} {v:>{width}.{p}f} {{"`,
+		},
+		{
+			name:     "dict literal in replacement field",
+			input:    `s = rf"{ {'a': '"'}['a'] }" # comment`,
+			expected: `s = rf"{ {'a': '"'}['a'] }"`,
+		},
+		{
+			// Only an f prefix makes braces fields; elif is a keyword, not a prefix
+			name:     "braces in plain strings",
+			input:    `if a: x = "{" # one` + "\n" + `elif"}" in b: pass # two`,
+			expected: `if a: x = "{"` + "\n" + `elif"}" in b: pass`,
+		},
+		{
+			name: "multiline f-string with nested string",
+			input: `msg = f"""
+{", ".join(f"'{n}'" for n in names)} # kept
+"""  # comment
+y = 1`,
+			expected: `msg = f"""
+{", ".join(f"'{n}'" for n in names)} # kept
+"""
+y = 1`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := RemovePythonComments(tt.input)
+
+			if result != tt.expected {
+				t.Errorf("RemovePythonComments() failed\nInput:\n%s\n\nExpected:\n%s\n\nGot:\n%s", tt.input, tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestRemovePythonCommentsKeepingDirectives(t *testing.T) {
+	keep := directiveKeeper(pythonDirectives, "#")
+
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "type ignore kept",
+			input:    "import yaml  # type: ignore\nx = 1  # one\n",
+			expected: "import yaml  # type: ignore\nx = 1\n",
+		},
+		{
+			name:     "type comment kept",
+			input:    "def f(a, b):\n    # type: (int, str) -> bool\n    # check a\n    return bool(a)\n",
+			expected: "def f(a, b):\n    # type: (int, str) -> bool\n\n    return bool(a)\n",
+		},
+		{
+			name:     "linter and coverage pragmas kept",
+			input:    "from x import *  # noqa: F403\nif DEBUG:  # pragma: no cover\n    pass  # pylint: disable=unnecessary-pass\n",
+			expected: "from x import *  # noqa: F403\nif DEBUG:  # pragma: no cover\n    pass  # pylint: disable=unnecessary-pass\n",
+		},
+		{
+			name:     "coding cookie on first line kept",
+			input:    "# -*- coding: utf-8 -*-\n# module notes\nx = 1\n",
+			expected: "# -*- coding: utf-8 -*-\n\nx = 1\n",
+		},
+		{
+			name:     "coding cookie after shebang kept",
+			input:    "#!/usr/bin/env python\n# vim: set fileencoding=latin-1 :\nx = 1\n",
+			expected: "#!/usr/bin/env python\n# vim: set fileencoding=latin-1 :\nx = 1\n",
+		},
+		{
+			name:     "coding comment after line two removed",
+			input:    "x = 1\ny = 2\n# coding: utf-8\n",
+			expected: "x = 1\ny = 2\n\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := removePythonCommentsKeeping(tt.input, keep)
+
+			if result != tt.expected {
+				t.Errorf("removePythonCommentsKeeping() failed\nInput:\n%s\n\nExpected:\n%s\n\nGot:\n%s", tt.input, tt.expected, result)
+			}
+		})
+	}
+
+	// The coding cookie survives even when no directives are kept
+	if result := RemovePythonComments("# -*- coding: utf-8 -*-\nx = 1  # type: int\n"); result != "# -*- coding: utf-8 -*-\nx = 1\n" {
+		t.Errorf("RemovePythonComments() = %q, want coding cookie kept and type comment removed", result)
+	}
+}
@@ -0,0 +1,278 @@
+package stripper
+
+import (
+	"io"
+	"iter"
+	"strings"
+)
+
+// RemoveRustComments removes line, block, and doc comments from Rust source, preserving
+// string, raw string, and character literals.
+func RemoveRustComments(content string) string {
+	return removeRustCommentsKeeping(content, nil)
+}
+
+// removeRustCommentsKeeping removes comments like RemoveRustComments, except the //
+// comments for which keep returns true. keep receives the comment from its // opener to
+// the end of the line. Block comments are never kept. A nil keep removes every comment.
+func removeRustCommentsKeeping(content string, keep func(comment string) bool) string {
+	var result strings.Builder
+	removeRustCommentLines(&result, splitLines(content), keep)
+	return result.String()
+}
+
+// removeRustCommentLines writes lines to result with comments removed like
+// removeRustCommentsKeeping.
+func removeRustCommentLines(result io.StringWriter, lines iter.Seq2[string, bool], keep func(comment string) bool) {
+	// Rust allows nested block comments (/* /* nested */ */), so we must track depth
+	inBlockComment := false
+	blockCommentDepth := 0
+	// Strings and raw strings can span lines; rawDelimiter is the closing delimiter of
+	// the raw string being copied, such as "# for r#"..."#
+	inString := false
+	inRawString := false
+	rawDelimiter := ""
+
+	for line, newline := range lines {
+		// If we're inside a block comment from a previous line, continue processing it
+		if inBlockComment {
+			for idx := 0; idx < len(line); {
+				if idx+1 < len(line) && line[idx] == '/' && line[idx+1] == '*' {
+					blockCommentDepth++
+					idx += 2
+					continue
+				}
+
+				if idx+1 < len(line) && line[idx] == '*' && line[idx+1] == '/' {
+					blockCommentDepth--
+
+					// Only exit block comment when all nested levels are closed
+					if blockCommentDepth == 0 {
+						inBlockComment = false
+						// Resume processing the rest of this line after the closing */
+						line = line[idx+2:]
+						break
+					}
+					idx += 2
+					continue
+				}
+				idx++
+			}
+
+			// Entire line was inside block comment - preserve the newline structure
+			if inBlockComment {
+				result.WriteString("\n")
+				continue
+			}
+		}
+
+		var cleaned strings.Builder
+		inChar := false
+		escaped := false
+		j := 0
+
+		// Use runes instead of bytes to correctly handle multi-byte UTF-8 characters
+		runes := []rune(line)
+
+		// A raw string from a previous line runs up to its delimiter, ignoring everything
+		// else, escapes included
+		if inRawString {
+			var closed bool
+			j, closed = rustRawStringEnd(runes, 0, rawDelimiter)
+			cleaned.WriteString(string(runes[:j]))
+			inRawString = !closed
+		}
+
+		for j < len(runes) {
+			ch := runes[j]
+
+			if escaped {
+				cleaned.WriteRune(ch)
+				escaped = false
+				j++
+				continue
+			}
+
+			if ch == '\\' && (inString || inChar) {
+				cleaned.WriteRune(ch)
+				escaped = true
+				j++
+				continue
+			}
+
+			// Rust raw strings: r"text", r#"text"#, r##"text"##, etc., and the raw byte strings
+			// br"..." and br#"..."#. The number of # must match on both ends, making comment
+			// markers inside safe
+			if !inString && !inChar && ch == 'r' && j+1 < len(runes) && isRustLiteralPrefix(runes, j) {
+				hashCount := 0
+				k := j + 1
+
+				// Count the hash symbols to determine the delimiter
+				for k < len(runes) && runes[k] == '#' {
+					hashCount++
+					k++
+				}
+
+				if k < len(runes) && runes[k] == '"' {
+					cleaned.WriteString(string(runes[j : k+1]))
+					j = k + 1
+
+					// Look for closing delimiter with matching hash count
+					rawDelimiter = `"` + strings.Repeat("#", hashCount)
+					end, closed := rustRawStringEnd(runes, j, rawDelimiter)
+					cleaned.WriteString(string(runes[j:end]))
+					if !closed {
+						// Raw string extends beyond this line - continue on the next line
+						inRawString = true
+						break
+					}
+					j = end
+					continue
+				}
+			}
+
+			// Lifetimes ('a, 'static) share the opening quote with char literals but are
+			// never closed, so treating them as chars would swallow the rest of the line
+			if ch == '\'' && !inString && !inRawString && !inChar && isRustLifetime(runes, j) {
+				cleaned.WriteRune(ch)
+				j++
+				continue
+			}
+
+			if ch == '\'' && !inString && !inRawString {
+				if !inChar {
+					inChar = true
+				} else {
+					inChar = false
+				}
+				cleaned.WriteRune(ch)
+				j++
+				continue
+			}
+
+			// Byte strings (b"...") and byte chars (b'x') need no special case: the b is copied
+			// as code and the quote then opens an ordinary literal
+			if ch == '"' && !inRawString && !inChar {
+				if !inString {
+					inString = true
+				} else {
+					inString = false
+				}
+				cleaned.WriteRune(ch)
+				j++
+				continue
+			}
+
+			// Preserve all content inside strings and char literals
+			if inString || inRawString || inChar {
+				cleaned.WriteRune(ch)
+				j++
+				continue
+			}
+
+			// Handle block comments with nesting support
+			if j+1 < len(runes) && runes[j] == '/' && runes[j+1] == '*' {
+				inBlockComment = true
+				blockCommentDepth = 1
+				k := j + 2
+
+				// Try to find the closing */ on this same line, tracking nesting depth
+				for k < len(runes) {
+					if k+1 < len(runes) && runes[k] == '/' && runes[k+1] == '*' {
+						blockCommentDepth++
+						k += 2
+						continue
+					}
+
+					if k+1 < len(runes) && runes[k] == '*' && runes[k+1] == '/' {
+						blockCommentDepth--
+
+						if blockCommentDepth == 0 {
+							inBlockComment = false
+							// Continue processing code after the comment on this line
+							j = k + 2
+							break
+						}
+						k += 2
+						continue
+					}
+					k++
+				}
+
+				// Block comment continues to next line - stop processing this line
+				if inBlockComment {
+					break
+				}
+				continue
+			}
+
+			// Line comments extend to end of line - nothing more to process
+			if j+1 < len(runes) && runes[j] == '/' && runes[j+1] == '/' {
+				if comment := string(runes[j:]); keep != nil && keep(comment) {
+					cleaned.WriteString(comment)
+				}
+				break
+			}
+
+			cleaned.WriteRune(ch)
+			j++
+		}
+
+		if inString || inRawString {
+			// The line ends inside a string, so trailing whitespace is string content
+			result.WriteString(cleaned.String())
+		} else {
+			// Remove trailing whitespace but preserve the line structure
+			trimmed := strings.TrimRight(cleaned.String(), " \t")
+			result.WriteString(trimmed)
+		}
+
+		if newline {
+			result.WriteString("\n")
+		}
+	}
+}
+
+// rustRawStringEnd returns the index just past the delimiter that closes a raw string
+// whose content starts at runes[j], and true, or the end of the line and false if the
+// raw string continues on the next line.
+func rustRawStringEnd(runes []rune, j int, delimiter string) (int, bool) {
+	for k := j; k < len(runes); k++ {
+		if runes[k] == '"' && k+len(delimiter) <= len(runes) && string(runes[k:k+len(delimiter)]) == delimiter {
+			return k + len(delimiter), true
+		}
+	}
+	return len(runes), false
+}
+
+// isRustLiteralPrefix reports whether the r at runes[i] is a raw string prefix rather than
+// the end of an identifier such as "for" or "bar". A preceding b is allowed when it starts
+// a raw byte string (br"...").
+func isRustLiteralPrefix(runes []rune, i int) bool {
+	if i > 0 && runes[i-1] == 'b' {
+		i--
+	}
+	return i == 0 || !(isAlphanumeric(runes[i-1]) || runes[i-1] == '_')
+}
+
+// isRustLifetime reports whether the ' at runes[i] starts a lifetime rather than a char
+// literal. A char literal closes after one character ('a') or an escape ('\n'), while a
+// lifetime is an identifier with no closing quote ('a, 'static).
+func isRustLifetime(runes []rune, i int) bool {
+	if i+1 >= len(runes) || runes[i+1] == '\\' {
+		return false
+	}
+	if i+2 < len(runes) && runes[i+2] == '\'' {
+		return false
+	}
+	return isAlphanumeric(runes[i+1]) || runes[i+1] == '_'
+}
+
+// isRustDocComment reports whether comment is an outer (///) or inner (//!) doc comment.
+// Runs of four or more slashes are ordinary comments.
+func isRustDocComment(comment string) bool {
+	if strings.HasPrefix(comment, "///") {
+		return !strings.HasPrefix(comment, "////")
+	}
+	return strings.HasPrefix(comment, "//!")
+}
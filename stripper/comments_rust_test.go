@@ -1,4 +1,4 @@
-package main
+package stripper
 
 import (
 	"testing"
@@ -29,6 +29,8 @@ func TestRemoveRustComments(t *testing.T) {
     let x = 5;
 }`,
 			expected: `fn main() {
+
+
     let x = 5;
 }`,
 		},
@@ -41,6 +43,7 @@ func TestRemoveRustComments(t *testing.T) {
     let x = 5;
 }`,
 			expected: `fn main() {
+
     let x = 5;
 }`,
 		},
@@ -82,7 +85,8 @@ let c3 = '\'';`,
 			name: "escaped quotes in string",
 			input: `let s = "He said \"hello\" // comment";
 // another comment`,
-			expected: `let s = "He said \"hello\" // comment";`,
+			expected: `let s = "He said \"hello\" // comment";
+`,
 		},
 		{
 			name: "mixed comments and code",
@@ -95,6 +99,8 @@ fn main() {
 }`,
 			expected: `
 fn main() {
+
+
     let x = 5;
      let y = 10;
 }`,
@@ -107,13 +113,15 @@ fn main() {
 fn foo() {}
 //! Module doc comment`,
 			expected: `
-fn foo() {}`,
+fn foo() {}
+`,
 		},
 		{
 			name: "comment at end of file",
 			input: `fn main() {}
 // final comment`,
-			expected: `fn main() {}`,
+			expected: `fn main() {}
+`,
 		},
 		{
 			// Tests deeply nested block comments - Rust's nesting depth is unlimited
@@ -131,6 +139,13 @@ let x = 5;`,
 			input:    `let s = r##"String with "quotes" and #hash"##; // comment`,
 			expected: `let s = r##"String with "quotes" and #hash"##;`,
 		},
+		{
+			// Trailing spaces inside strings that span lines are content, and the string
+			// state carries over so the comment after it is still found
+			name:     "multiline strings",
+			input:    "let s = r#\"a  \n// not a comment  \n\"#; // comment\nlet t = \"x  \n/* y */  \"; // comment\n",
+			expected: "let s = r#\"a  \n// not a comment  \n\"#;\nlet t = \"x  \n/* y */  \";\n",
+		},
 		{
 			// Backslash in char literals requires special handling - '\' is not a single char
 			// but '\\' is (escaped backslash), testing escape sequence handling
@@ -140,14 +155,96 @@ let c2 = '\n';`,
 			expected: `let c = '\\';
 let c2 = '\n';`,
 		},
+		{
+			name:     "byte string",
+			input:    `let s = b"// not a comment"; // comment`,
+			expected: `let s = b"// not a comment";`,
+		},
+		{
+			name:     "raw byte string",
+			input:    `let r = br#"/* no */ "quoted""#; /* comment */`,
+			expected: `let r = br#"/* no */ "quoted""#;`,
+		},
+		{
+			name:     "byte char with quote",
+			input:    `let q = b'"'; let h = b'\''; // comment`,
+			expected: `let q = b'"'; let h = b'\'';`,
+		},
+		{
+			// An identifier ending in r is not a raw string prefix
+			name:     "identifier ending in r",
+			input:    `let n = bar("x"); // comment`,
+			expected: `let n = bar("x");`,
+		},
+		{
+			// Lifetimes start with ' but never close, so they must not open a char literal
+			name:     "lifetime annotation",
+			input:    `let x: &'a str = y; // comment`,
+			expected: `let x: &'a str = y;`,
+		},
+		{
+			name: "static lifetime and generics",
+			input: `fn foo<'a>(s: &'a str) -> &'static str { // comment
+    let c = 'x'; /* block */ s
+}`,
+			expected: `fn foo<'a>(s: &'a str) -> &'static str {
+    let c = 'x';  s
+}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := RemoveRustComments(tt.input)
+
+			if result != tt.expected {
+				t.Errorf("RemoveRustComments() failed\nInput:\n%s\n\nExpected:\n%s\n\nGot:\n%s", tt.input, tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestRemoveRustCommentsKeepingDocs(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name: "outer and inner doc comments kept",
+			input: `//! Parsing utilities.
+
+/// Parses a number.
+// TODO: handle hex
+fn parse(s: &str) -> u32 {
+    s.parse().unwrap() // panics on bad input
+}`,
+			expected: `//! Parsing utilities.
+
+/// Parses a number.
+
+fn parse(s: &str) -> u32 {
+    s.parse().unwrap()
+}`,
+		},
+		{
+			name:     "four slashes are an ordinary comment",
+			input:    "//// divider\nlet x = 1; /// trailing doc",
+			expected: "\nlet x = 1; /// trailing doc",
+		},
+		{
+			name:     "block comments removed",
+			input:    "/* note */ let y = 2;",
+			expected: " let y = 2;",
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := removeRustComments(tt.input)
+			result := removeRustCommentsKeeping(tt.input, isRustDocComment)
 
 			if result != tt.expected {
-				t.Errorf("removeRustComments() failed\nInput:\n%s\n\nExpected:\n%s\n\nGot:\n%s", tt.input, tt.expected, result)
+				t.Errorf("removeRustCommentsKeeping() failed\nInput:\n%s\n\nExpected:\n%s\n\nGot:\n%s", tt.input, tt.expected, result)
 			}
 		})
 	}
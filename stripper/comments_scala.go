@@ -0,0 +1,140 @@
+package stripper
+
+import (
+	"strings"
+)
+
+// RemoveScalaComments removes // line comments and nested /* */ block comments from
+// Scala code while preserving strings, triple-quoted strings, and interpolated strings
+// such as s"..." and raw"..." including the code inside their ${...} splices.
+func RemoveScalaComments(code string) string {
+	runes := []rune(code)
+	result, _ := stripScalaCode(runes, 0, make([]rune, 0, len(runes)), false)
+	return string(result)
+}
+
+// stripScalaCode copies code from runes[i:] to result with comments removed. When
+// inSplice is set it stops at the '}' that closes the enclosing ${...} splice and
+// returns its index.
+func stripScalaCode(runes []rune, i int, result []rune, inSplice bool) ([]rune, int) {
+	braceDepth := 0
+
+	for i < len(runes) {
+		ch := runes[i]
+
+		switch {
+		case ch == '}' && inSplice && braceDepth == 0:
+			return result, i
+		case ch == '{':
+			braceDepth++
+		case ch == '}':
+			braceDepth--
+		case ch == '"':
+			// An identifier directly before the quote makes it an interpolated string
+			prefixStart := i
+			for prefixStart > 0 && (isAlphanumeric(runes[prefixStart-1]) || runes[prefixStart-1] == '_') {
+				prefixStart--
+			}
+			interpolator := string(runes[prefixStart:i])
+			triple := i+2 < len(runes) && runes[i+1] == '"' && runes[i+2] == '"'
+			result, i = copyScalaString(runes, i, result, triple, interpolator)
+			continue
+		case ch == '\'':
+			// 'a' and '\n' are char literals, but 'sym is a (Scala 2) symbol literal
+			end := -1
+			if i+2 < len(runes) && runes[i+1] == '\\' {
+				end = strings.IndexRune(string(runes[i+2:]), '\'')
+				if end != -1 {
+					end = i + 2 + len([]rune(string(runes[i+2:])[:end])) + 1
+				}
+			} else if i+2 < len(runes) && runes[i+2] == '\'' {
+				end = i + 3
+			}
+			if end != -1 {
+				result = append(result, runes[i:end]...)
+				i = end
+				continue
+			}
+		case ch == '/' && i+1 < len(runes) && runes[i+1] == '*':
+			i = skipNestedBlockComment(runes, i, "/*", "*/")
+			if i >= len(runes) || runes[i] == '\n' {
+				result = trimTrailingBlanks(result)
+			}
+			continue
+		case ch == '/' && i+1 < len(runes) && runes[i+1] == '/':
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+			result = trimTrailingBlanks(result)
+			continue
+		}
+
+		result = append(result, ch)
+		i++
+	}
+
+	return result, i
+}
+
+// copyScalaString copies the string literal starting at runes[i] to result and returns
+// the index just past it. Only interpolated strings have ${...} splices and $$ escapes,
+// and backslash escapes apply to neither triple-quoted nor raw"..." strings.
+func copyScalaString(runes []rune, i int, result []rune, triple bool, interpolator string) ([]rune, int) {
+	delimiter := `"`
+	if triple {
+		delimiter = `"""`
+	}
+	interpolated := interpolator != ""
+	escapes := !triple && interpolator != "raw"
+
+	result = append(result, []rune(delimiter)...)
+	i += len(delimiter)
+
+	for i < len(runes) {
+		ch := runes[i]
+
+		if escapes && ch == '\\' && i+1 < len(runes) {
+			result = append(result, ch, runes[i+1])
+			i += 2
+			continue
+		}
+
+		if interpolated && ch == '$' && i+1 < len(runes) {
+			switch runes[i+1] {
+			case '$', '"':
+				result = append(result, ch, runes[i+1])
+				i += 2
+				continue
+			case '{':
+				result = append(result, '$', '{')
+				result, i = stripScalaCode(runes, i+2, result, true)
+				if i < len(runes) {
+					result = append(result, runes[i])
+					i++
+				}
+				continue
+			}
+		}
+
+		if strings.HasPrefix(string(runes[i:min(i+len(delimiter), len(runes))]), delimiter) {
+			// Extra quotes before the closing """ belong to the content
+			for triple && i+3 < len(runes) && runes[i+3] == '"' {
+				result = append(result, '"')
+				i++
+			}
+			result = append(result, []rune(delimiter)...)
+			return result, i + len(delimiter)
+		}
+
+		// Single-quoted strings cannot span lines; stop so a malformed string doesn't
+		// swallow the rest of the file
+		if !triple && ch == '\n' {
+			return result, i
+		}
+
+		result = append(result, ch)
+		i++
+	}
+
+	return result, i
+}
@@ -0,0 +1,70 @@
+package stripper
+
+import (
+	"testing"
+)
+
+func TestRemoveScalaComments(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name: "line comments",
+			input: `// header
+val x = 1 // trailing`,
+			expected: `
+val x = 1`,
+		},
+		{
+			name: "nested block comments",
+			input: `/* outer /* inner */ still outer */
+def f = 1 /* a /* b */ c */ + 2`,
+			expected: `
+def f = 1  + 2`,
+		},
+		{
+			name: "triple-quoted string containing block comment marker",
+			input: `val sql = """
+  SELECT * /* hint */ FROM t
+  -- // not a comment
+""" /* removed */`,
+			expected: `val sql = """
+  SELECT * /* hint */ FROM t
+  -- // not a comment
+"""`,
+		},
+		{
+			name:     "interpolated string with splice",
+			input:    `val s = s"user: ${m("//k")} // text" // comment`,
+			expected: `val s = s"user: ${m("//k")} // text"`,
+		},
+		{
+			// raw"..." does not process backslash escapes, so the backslash cannot escape the quote
+			name:     "raw interpolator",
+			input:    `val r = raw"C:\path\" // comment`,
+			expected: `val r = raw"C:\path\"`,
+		},
+		{
+			name:     "dollar escapes in interpolated string",
+			input:    `val p = s"$$price /* not */ $$" // comment`,
+			expected: `val p = s"$$price /* not */ $$"`,
+		},
+		{
+			name:     "char and symbol literals",
+			input:    `val c = '/'; val s = 'sym; val q = '"' // comment`,
+			expected: `val c = '/'; val s = 'sym; val q = '"'`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := RemoveScalaComments(tt.input)
+
+			if result != tt.expected {
+				t.Errorf("RemoveScalaComments() failed\nInput:\n%s\n\nExpected:\n%s\n\nGot:\n%s", tt.input, tt.expected, result)
+			}
+		})
+	}
+}
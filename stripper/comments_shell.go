@@ -0,0 +1,220 @@
+package stripper
+
+import (
+	"strings"
+)
+
+// shellHeredoc describes a pending heredoc whose body starts on the line after its opener.
+type shellHeredoc struct {
+	delimiter string
+	// stripTabs is set for <<- heredocs, where leading tabs are ignored on the closing line
+	stripTabs bool
+}
+
+// RemoveShellComments removes # comments from sh/bash/zsh scripts while preserving the
+// shebang line, quoted strings, parameter expansions such as ${#arr[@]} and ${var#prefix},
+// and heredoc bodies.
+func RemoveShellComments(content string) string {
+	shebang, content := splitShebang(content)
+	runes := []rune(content)
+	result := []rune(shebang)
+	i := 0
+
+	// Heredoc openers are seen before their bodies, so collect them until the end of the line
+	var pendingHeredocs []shellHeredoc
+	// Depth of ${...} parameter expansions, inside which # is an operator rather than a comment
+	braceDepth := 0
+
+	for i < len(runes) {
+		ch := runes[i]
+
+		if ch == '\n' {
+			result = append(result, ch)
+			i++
+
+			// Heredoc bodies are copied line by line until each closing delimiter is found
+			for _, heredoc := range pendingHeredocs {
+				for i < len(runes) {
+					lineEnd := i
+					for lineEnd < len(runes) && runes[lineEnd] != '\n' {
+						lineEnd++
+					}
+
+					line := string(runes[i:lineEnd])
+					result = append(result, runes[i:lineEnd]...)
+					i = lineEnd
+					if i < len(runes) {
+						result = append(result, '\n')
+						i++
+					}
+
+					if heredoc.stripTabs {
+						line = strings.TrimLeft(line, "\t")
+					}
+					if line == heredoc.delimiter {
+						break
+					}
+				}
+			}
+			pendingHeredocs = nil
+			continue
+		}
+
+		// A backslash outside quotes makes the next character literal, including \#
+		if ch == '\\' {
+			result = append(result, ch)
+			i++
+			if i < len(runes) {
+				result = append(result, runes[i])
+				i++
+			}
+			continue
+		}
+
+		// Single-quoted strings have no escapes at all, except in ANSI-C $'...' quoting
+		if ch == '\'' {
+			ansiC := i > 0 && runes[i-1] == '$'
+			result = append(result, ch)
+			i++
+			for i < len(runes) {
+				result = append(result, runes[i])
+				if ansiC && runes[i] == '\\' && i+1 < len(runes) {
+					i++
+					result = append(result, runes[i])
+					i++
+					continue
+				}
+				if runes[i] == '\'' {
+					i++
+					break
+				}
+				i++
+			}
+			continue
+		}
+
+		if ch == '"' {
+			result = append(result, ch)
+			i++
+			for i < len(runes) {
+				result = append(result, runes[i])
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+					result = append(result, runes[i])
+					i++
+					continue
+				}
+				if runes[i] == '"' {
+					i++
+					break
+				}
+				i++
+			}
+			continue
+		}
+
+		if ch == '$' && i+1 < len(runes) && runes[i+1] == '{' {
+			braceDepth++
+			result = append(result, ch, runes[i+1])
+			i += 2
+			continue
+		}
+
+		if ch == '}' && braceDepth > 0 {
+			braceDepth--
+			result = append(result, ch)
+			i++
+			continue
+		}
+
+		// Here-strings (<<<) have no body, so only << and <<- introduce heredocs
+		if ch == '<' && i+2 < len(runes) && runes[i+1] == '<' && runes[i+2] == '<' {
+			result = append(result, runes[i:i+3]...)
+			i += 3
+			continue
+		}
+
+		if ch == '<' && i+1 < len(runes) && runes[i+1] == '<' {
+			if heredoc, end, ok := parseShellHeredoc(runes, i+2); ok {
+				result = append(result, runes[i:end]...)
+				i = end
+				pendingHeredocs = append(pendingHeredocs, heredoc)
+				continue
+			}
+		}
+
+		// # only starts a comment at the beginning of a word, so foo#bar and $# are left alone
+		if ch == '#' && braceDepth == 0 && (i == 0 || isShellWordBoundary(runes[i-1])) {
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+
+			result = trimTrailingBlanks(result)
+			continue
+		}
+
+		result = append(result, ch)
+		i++
+	}
+
+	return string(result)
+}
+
+// parseShellHeredoc reads a heredoc delimiter starting just after "<<". It returns the
+// heredoc, the index just past the delimiter word, and whether a delimiter was found.
+func parseShellHeredoc(runes []rune, start int) (shellHeredoc, int, bool) {
+	var heredoc shellHeredoc
+	i := start
+
+	if i < len(runes) && runes[i] == '-' {
+		heredoc.stripTabs = true
+		i++
+	}
+
+	for i < len(runes) && (runes[i] == ' ' || runes[i] == '\t') {
+		i++
+	}
+
+	// Quoting any part of the delimiter disables expansion in the body, but the
+	// delimiter itself is the word with the quotes removed
+	var delimiter strings.Builder
+	for i < len(runes) {
+		ch := runes[i]
+		if ch == '\'' || ch == '"' {
+			i++
+			for i < len(runes) && runes[i] != ch && runes[i] != '\n' {
+				delimiter.WriteRune(runes[i])
+				i++
+			}
+			if i < len(runes) && runes[i] == ch {
+				i++
+			}
+			continue
+		}
+		if ch == '\\' && i+1 < len(runes) {
+			delimiter.WriteRune(runes[i+1])
+			i += 2
+			continue
+		}
+		if isShellWordBoundary(ch) || ch == '\n' || ch == '<' || ch == '>' {
+			break
+		}
+		delimiter.WriteRune(ch)
+		i++
+	}
+
+	if delimiter.Len() == 0 {
+		return heredoc, start, false
+	}
+
+	heredoc.delimiter = delimiter.String()
+	return heredoc, i, true
+}
+
+func isShellWordBoundary(r rune) bool {
+	switch r {
+	case ' ', '\t', '\n', ';', '&', '|', '(', ')':
+		return true
+	}
+	return false
+}
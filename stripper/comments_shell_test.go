@@ -0,0 +1,101 @@
+package stripper
+
+import (
+	"testing"
+)
+
+func TestRemoveShellComments(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name: "shebang preserved",
+			input: `#!/usr/bin/env bash
+# comment
+echo hi # trailing`,
+			expected: `#!/usr/bin/env bash
+
+echo hi`,
+		},
+		{
+			name: "hash inside quotes",
+			input: `echo "# not a comment" '# nor this' # but this is
+echo $'it\'s # kept' # gone`,
+			expected: `echo "# not a comment" '# nor this'
+echo $'it\'s # kept'`,
+		},
+		{
+			// # is only a comment at the start of a word
+			name: "hash inside a word",
+			input: `echo foo#bar
+echo $# args
+echo \# escaped`,
+			expected: `echo foo#bar
+echo $# args
+echo \# escaped`,
+		},
+		{
+			name: "parameter expansion",
+			input: `echo ${#arr[@]} # length
+echo ${var#prefix} ${var##*/} # strip`,
+			expected: `echo ${#arr[@]}
+echo ${var#prefix} ${var##*/}`,
+		},
+		{
+			name: "heredoc body preserved",
+			input: `cat <<EOF # comment after opener
+# not a comment
+  value # still text
+EOF
+echo done # comment`,
+			expected: `cat <<EOF
+# not a comment
+  value # still text
+EOF
+echo done`,
+		},
+		{
+			name: "quoted heredoc delimiter",
+			input: `cat <<'END'
+# literal $HOME
+END
+# removed`,
+			expected: `cat <<'END'
+# literal $HOME
+END
+`,
+		},
+		{
+			// <<- allows the closing delimiter to be indented with tabs
+			name:     "indented heredoc",
+			input:    "if true; then\n\tcat <<-EOF\n\t# kept\n\tEOF\nfi # end",
+			expected: "if true; then\n\tcat <<-EOF\n\t# kept\n\tEOF\nfi",
+		},
+		{
+			name: "here-string is not a heredoc",
+			input: `grep x <<< "$input" # comment
+echo next # comment`,
+			expected: `grep x <<< "$input"
+echo next`,
+		},
+		{
+			name: "comment after semicolon",
+			input: `echo a;# comment
+echo b`,
+			expected: `echo a;
+echo b`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := RemoveShellComments(tt.input)
+
+			if result != tt.expected {
+				t.Errorf("RemoveShellComments() failed\nInput:\n%s\n\nExpected:\n%s\n\nGot:\n%s", tt.input, tt.expected, result)
+			}
+		})
+	}
+}
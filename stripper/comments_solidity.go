@@ -0,0 +1,25 @@
+package stripper
+
+import (
+	"strings"
+)
+
+// RemoveSolidityComments removes // and /* */ comments from Solidity code using the
+// JavaScript state machine, since the lexical rules for strings and comments match.
+// When keepNatSpec is set, NatSpec documentation (/// and /** */) is preserved because
+// tooling such as solc --userdoc and Etherscan extracts it from the source.
+func RemoveSolidityComments(content string, keepNatSpec bool) string {
+	if !keepNatSpec {
+		return RemoveJSComments(content)
+	}
+	return removeJSCommentsKeeping(content, isNatSpecComment, false)
+}
+
+// isNatSpecComment reports whether comment is a NatSpec comment. Runs of four or more
+// slashes and the empty /**/ comment are ordinary comments in the Solidity grammar.
+func isNatSpecComment(comment string) bool {
+	if strings.HasPrefix(comment, "///") {
+		return !strings.HasPrefix(comment, "////")
+	}
+	return strings.HasPrefix(comment, "/**") && !strings.HasPrefix(comment, "/**/")
+}
@@ -0,0 +1,97 @@
+package stripper
+
+import (
+	"testing"
+)
+
+func TestRemoveSolidityComments(t *testing.T) {
+	input := `// SPDX-License-Identifier: MIT
+/// @title A token
+/// @notice Tracks balances
+contract Token {
+    /**
+     * @dev Moves tokens.
+     * @param to recipient
+     */
+    function transfer(address to) public { // implementation note
+        /* internal */ emit Sent("//not a comment");
+    }
+    //// divider
+    /**/ uint x;
+}`
+
+	tests := []struct {
+		name        string
+		keepNatSpec bool
+		expected    string
+	}{
+		{
+			name:        "all comments removed",
+			keepNatSpec: false,
+			expected: `
+
+
+contract Token {
+
+
+
+
+    function transfer(address to) public {
+         emit Sent("//not a comment");
+    }
+
+     uint x;
+}`,
+		},
+		{
+			name:        "natspec preserved",
+			keepNatSpec: true,
+			expected: `
+/// @title A token
+/// @notice Tracks balances
+contract Token {
+    /**
+     * @dev Moves tokens.
+     * @param to recipient
+     */
+    function transfer(address to) public {
+         emit Sent("//not a comment");
+    }
+
+     uint x;
+}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := RemoveSolidityComments(input, tt.keepNatSpec)
+
+			if result != tt.expected {
+				t.Errorf("RemoveSolidityComments() failed\nInput:\n%s\n\nExpected:\n%s\n\nGot:\n%s", input, tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestIsNatSpecComment(t *testing.T) {
+	tests := []struct {
+		comment  string
+		expected bool
+	}{
+		{"/// @notice hi", true},
+		{"/** @dev hi */", true},
+		{"// plain", false},
+		{"/* plain */", false},
+		{"//// divider", false},
+		{"/**/", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.comment, func(t *testing.T) {
+			if got := isNatSpecComment(tt.comment); got != tt.expected {
+				t.Errorf("isNatSpecComment(%q) = %v, want %v", tt.comment, got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package stripper
+
+import (
+	"strings"
+)
+
+// RemoveSQLComments removes -- line comments and /* */ block comments from SQL while
+// preserving single-quoted literals, double-quoted identifiers, and PostgreSQL
+// dollar-quoted strings ($$...$$ or $tag$...$tag$).
+func RemoveSQLComments(code string) string {
+	runes := []rune(code)
+	result := make([]rune, 0, len(runes))
+	i := 0
+
+	for i < len(runes) {
+		ch := runes[i]
+
+		// Both quote styles escape their delimiter by doubling it ('it''s', "a""b"),
+		// so a doubled quote simply continues the literal
+		if ch == '\'' || ch == '"' {
+			result = append(result, ch)
+			i++
+			for i < len(runes) {
+				result = append(result, runes[i])
+				if runes[i] == ch {
+					if i+1 < len(runes) && runes[i+1] == ch {
+						result = append(result, runes[i+1])
+						i += 2
+						continue
+					}
+					i++
+					break
+				}
+				i++
+			}
+			continue
+		}
+
+		// Dollar-quoted bodies are typically whole function definitions, so everything up
+		// to the matching closing tag is copied untouched
+		if ch == '$' {
+			if tag, ok := readDollarQuoteTag(runes, i); ok {
+				closeIdx := strings.Index(string(runes[i+len(tag):]), tag)
+				end := len(runes)
+				if closeIdx != -1 {
+					end = i + len(tag) + len([]rune(string(runes[i+len(tag):])[:closeIdx])) + len(tag)
+				}
+				result = append(result, runes[i:end]...)
+				i = end
+				continue
+			}
+		}
+
+		if ch == '-' && i+1 < len(runes) && runes[i+1] == '-' {
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+			result = trimTrailingBlanks(result)
+			continue
+		}
+
+		if ch == '/' && i+1 < len(runes) && runes[i+1] == '*' {
+			i += 2
+			for i < len(runes) {
+				if i+1 < len(runes) && runes[i] == '*' && runes[i+1] == '/' {
+					i += 2
+					break
+				}
+				i++
+			}
+			continue
+		}
+
+		result = append(result, ch)
+		i++
+	}
+
+	return string(result)
+}
+
+// readDollarQuoteTag returns the dollar-quote tag (including both $ signs) starting at
+// runes[start]. Positional parameters like $1 are rejected because tags cannot start
+// with a digit, and a $ inside an identifier (foo$bar) never opens a dollar quote.
+func readDollarQuoteTag(runes []rune, start int) (string, bool) {
+	if start > 0 && (isAlphanumeric(runes[start-1]) || runes[start-1] == '_') {
+		return "", false
+	}
+
+	i := start + 1
+	for i < len(runes) && (isAlphanumeric(runes[i]) || runes[i] == '_') {
+		if i == start+1 && runes[i] >= '0' && runes[i] <= '9' {
+			return "", false
+		}
+		i++
+	}
+
+	if i >= len(runes) || runes[i] != '$' {
+		return "", false
+	}
+
+	return string(runes[start : i+1]), true
+}
+
+// trimTrailingBlanks drops spaces and tabs left behind on the current line after a
+// comment is removed.
+func trimTrailingBlanks(result []rune) []rune {
+	for len(result) > 0 && (result[len(result)-1] == ' ' || result[len(result)-1] == '\t') {
+		result = result[:len(result)-1]
+	}
+	return result
+}
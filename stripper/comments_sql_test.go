@@ -0,0 +1,82 @@
+package stripper
+
+import (
+	"testing"
+)
+
+func TestRemoveSQLComments(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name: "line comment",
+			input: `SELECT id -- primary key
+FROM users;`,
+			expected: `SELECT id
+FROM users;`,
+		},
+		{
+			name: "block comment",
+			input: `SELECT /* all columns */ *
+/* multi
+   line */
+FROM users;`,
+			expected: `SELECT  *
+
+FROM users;`,
+		},
+		{
+			// '' escapes a quote inside a literal, so the literal does not end early
+			name:     "doubled single quote escape",
+			input:    `SELECT 'it''s -- not a comment' AS s; -- comment`,
+			expected: `SELECT 'it''s -- not a comment' AS s;`,
+		},
+		{
+			name:     "double-quoted identifier",
+			input:    `SELECT "weird--name", "a""/*b*/" FROM t; -- comment`,
+			expected: `SELECT "weird--name", "a""/*b*/" FROM t;`,
+		},
+		{
+			name: "dollar-quoted function body",
+			input: `CREATE FUNCTION f() RETURNS int AS $$
+  -- kept inside body
+  SELECT 1; /* also kept */
+$$ LANGUAGE sql; -- removed`,
+			expected: `CREATE FUNCTION f() RETURNS int AS $$
+  -- kept inside body
+  SELECT 1; /* also kept */
+$$ LANGUAGE sql;`,
+		},
+		{
+			name: "tagged dollar quote",
+			input: `DO $body$
+BEGIN
+  RAISE NOTICE '$$ -- inner';
+END
+$body$; -- removed`,
+			expected: `DO $body$
+BEGIN
+  RAISE NOTICE '$$ -- inner';
+END
+$body$;`,
+		},
+		{
+			// Positional parameters look like the start of a dollar quote but are not
+			name:     "positional parameters",
+			input:    `SELECT * FROM t WHERE a = $1 AND b = $2; -- params`,
+			expected: `SELECT * FROM t WHERE a = $1 AND b = $2;`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := RemoveSQLComments(tt.input)
+
+			if result != tt.expected {
+				t.Errorf("RemoveSQLComments() failed\nInput:\n%s\n\nExpected:\n%s\n\nGot:\n%s", tt.input, tt.expected, result)
+			}
+		})
+	}
+}
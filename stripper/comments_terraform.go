@@ -1,13 +1,13 @@
-package main
+package stripper
 
 import (
 	"strings"
 )
 
-// removeTerraformComments removes line comments (#, //) and block comments (/* */)
+// RemoveTerraformComments removes line comments (#, //) and block comments (/* */)
 // from Terraform code while preserving strings and avoiding comment-like content
 // within string literals.
-func removeTerraformComments(code string) string {
+func RemoveTerraformComments(code string) string {
 	var result strings.Builder
 	runes := []rune(code)
 	i := 0
@@ -15,28 +15,12 @@ func removeTerraformComments(code string) string {
 	for i < len(runes) {
 		// Check for double-quoted string
 		if runes[i] == '"' {
-			result.WriteRune(runes[i])
-			i++
-			for i < len(runes) {
-				result.WriteRune(runes[i])
-				if runes[i] == '\\' && i+1 < len(runes) {
-					// Skip escaped character
-					i++
-					result.WriteRune(runes[i])
-					i++
-					continue
-				}
-				if runes[i] == '"' {
-					i++
-					break
-				}
-				i++
-			}
+			i = copyTerraformString(runes, i, &result)
 			continue
 		}
 
 		// Check for heredoc (<<EOF or <<-EOF)
-		if i+2 < len(runes) && runes[i] == '<' && runes[i+1] == '<' {
+		if i+1 < len(runes) && runes[i] == '<' && runes[i+1] == '<' {
 			// Handle heredoc
 			heredocStart := i
 			i += 2
@@ -59,6 +43,19 @@ func removeTerraformComments(code string) string {
 					result.WriteRune(runes[j])
 				}
 
+				// The body starts on the next line; anything after the delimiter on the
+				// opener line is ordinary code and may itself hold a comment
+				lineEnd := i
+				for lineEnd < len(runes) && runes[lineEnd] != '\n' {
+					lineEnd++
+				}
+				result.WriteString(RemoveTerraformComments(string(runes[i:lineEnd])))
+				i = lineEnd
+				if i < len(runes) {
+					result.WriteRune(runes[i])
+					i++
+				}
+
 				// Copy everything until we find the delimiter on its own line
 				delimiterStr := delimiter.String()
 				for i < len(runes) {
@@ -89,6 +86,9 @@ func removeTerraformComments(code string) string {
 				}
 				continue
 			}
+
+			// Not a heredoc (e.g. a bare << at the end of input), so treat << as code
+			i = heredocStart
 		}
 
 		// Check for # line comment
@@ -131,6 +131,71 @@ func removeTerraformComments(code string) string {
 	return result.String()
 }
 
+// copyTerraformString copies the double-quoted string starting at runes[i] to result and
+// returns the index just past its closing quote. Template sequences (${...} and %{...})
+// are copied as a unit so that quotes nested inside them do not end the string, while
+// the escapes $${ and %%{ produce a literal ${ or %{ and open nothing.
+func copyTerraformString(runes []rune, i int, result *strings.Builder) int {
+	result.WriteRune(runes[i])
+	i++
+
+	for i < len(runes) {
+		ch := runes[i]
+
+		switch {
+		case ch == '\\' && i+1 < len(runes):
+			// Skip escaped character
+			result.WriteRune(ch)
+			result.WriteRune(runes[i+1])
+			i += 2
+		case (ch == '$' || ch == '%') && i+2 < len(runes) && runes[i+1] == ch && runes[i+2] == '{':
+			result.WriteString(string(runes[i : i+3]))
+			i += 3
+		case (ch == '$' || ch == '%') && i+1 < len(runes) && runes[i+1] == '{':
+			i = copyTerraformTemplate(runes, i, result)
+		case ch == '"':
+			result.WriteRune(ch)
+			return i + 1
+		default:
+			result.WriteRune(ch)
+			i++
+		}
+	}
+
+	return i
+}
+
+// copyTerraformTemplate copies the ${...} or %{...} sequence starting at runes[i] to
+// result, including any nested strings, and returns the index just past its closing brace.
+func copyTerraformTemplate(runes []rune, i int, result *strings.Builder) int {
+	result.WriteString(string(runes[i : i+2]))
+	i += 2
+	depth := 1
+
+	for i < len(runes) {
+		ch := runes[i]
+
+		switch ch {
+		case '"':
+			i = copyTerraformString(runes, i, result)
+			continue
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				result.WriteRune(ch)
+				return i + 1
+			}
+		}
+
+		result.WriteRune(ch)
+		i++
+	}
+
+	return i
+}
+
 func isAlphanumeric(r rune) bool {
 	return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9')
 }
@@ -1,4 +1,4 @@
-package main
+package stripper
 
 import (
 	"strings"
@@ -135,6 +135,52 @@ metadata = <<-METADATA
   # Also preserved
   METADATA`,
 		},
+		{
+			name: "heredoc at end of file",
+			input: `x = <<A
+# kept
+A`,
+			expected: `x = <<A
+# kept
+A`,
+		},
+		{
+			name:     "heredoc with one trailing char",
+			input:    "x = <<E\n# kept\nE\n",
+			expected: "x = <<E\n# kept\nE\n",
+		},
+		{
+			name:     "bare heredoc marker at end of file",
+			input:    `x = <<`,
+			expected: `x = <<`,
+		},
+		{
+			name: "comment after heredoc opener",
+			input: `x = <<EOF // not really
+# kept
+EOF`,
+			expected: "x = <<EOF \n# kept\nEOF",
+		},
+		{
+			name:     "hash after interpolation",
+			input:    `x = "${var.y} # not comment" # comment`,
+			expected: `x = "${var.y} # not comment" `,
+		},
+		{
+			name:     "quotes nested in interpolation",
+			input:    `x = "${lookup(var.m, "k # x", "// y")} tail" // comment`,
+			expected: `x = "${lookup(var.m, "k # x", "// y")} tail" `,
+		},
+		{
+			name:     "escaped interpolation",
+			input:    `x = "a$${b}c" # comment`,
+			expected: `x = "a$${b}c" `,
+		},
+		{
+			name:     "template directive",
+			input:    `x = "%{ if var.on == "y" }on # yes%{ endif }" # comment`,
+			expected: `x = "%{ if var.on == "y" }on # yes%{ endif }" `,
+		},
 		{
 			name: "nested strings",
 			input: `value = "outer \"inner # not comment\" end"`,
@@ -144,9 +190,9 @@ metadata = <<-METADATA
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := removeTerraformComments(tt.input)
+			result := RemoveTerraformComments(tt.input)
 			if result != tt.expected {
-				t.Errorf("removeTerraformComments() failed\n"+
+				t.Errorf("RemoveTerraformComments() failed\n"+
 					"Input:\n%s\n"+
 					"Expected:\n%s\n"+
 					"Got:\n%s",
@@ -179,7 +225,7 @@ output "instance_id" {
   value = aws_instance.web.id
 }`
 
-	result := removeTerraformComments(input)
+	result := RemoveTerraformComments(input)
 
 	// Check that key elements are still present
 	requiredElements := []string{
@@ -194,7 +240,7 @@ output "instance_id" {
 
 	for _, element := range requiredElements {
 		if !strings.Contains(result, element) {
-			t.Errorf("removeTerraformComments() removed required element: %s", element)
+			t.Errorf("RemoveTerraformComments() removed required element: %s", element)
 		}
 	}
 }
@@ -0,0 +1,241 @@
+package stripper
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSplitShebang(t *testing.T) {
+	tests := []struct {
+		name            string
+		input           string
+		expectedShebang string
+		expectedRest    string
+	}{
+		{
+			name:            "shebang with body",
+			input:           "#!/bin/sh\necho hi",
+			expectedShebang: "#!/bin/sh\n",
+			expectedRest:    "echo hi",
+		},
+		{
+			name:            "shebang only",
+			input:           "#!/bin/sh",
+			expectedShebang: "#!/bin/sh",
+			expectedRest:    "",
+		},
+		{
+			name:            "plain comment",
+			input:           "# comment\nx = 1",
+			expectedShebang: "",
+			expectedRest:    "# comment\nx = 1",
+		},
+		{
+			name:            "shebang not on first line",
+			input:           "\n#!/bin/sh",
+			expectedShebang: "",
+			expectedRest:    "\n#!/bin/sh",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shebang, rest := splitShebang(tt.input)
+
+			if shebang != tt.expectedShebang || rest != tt.expectedRest {
+				t.Errorf("splitShebang(%q) = (%q, %q), want (%q, %q)", tt.input, shebang, rest, tt.expectedShebang, tt.expectedRest)
+			}
+		})
+	}
+}
+
+func TestCollapseExcessiveNewlines(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "leading blank lines removed",
+			input:    "\n\n\npackage main\n",
+			expected: "package main\n",
+		},
+		{
+			name:     "runs of blank lines collapsed",
+			input:    "a\n\n\n\nb\n\n\nc",
+			expected: "a\n\nb\n\nc",
+		},
+		{
+			name:     "single blank line kept",
+			input:    "a\n\nb\n",
+			expected: "a\n\nb\n",
+		},
+		{
+			name:     "empty input",
+			input:    "",
+			expected: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := CollapseExcessiveNewlines(tt.input)
+
+			if result != tt.expected {
+				t.Errorf("CollapseExcessiveNewlines(%q) = %q, want %q", tt.input, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestDirectiveKeeper(t *testing.T) {
+	keep := directiveKeeper([]string{"go:", "eslint-"}, "//", "/*")
+
+	tests := []struct {
+		comment  string
+		expected bool
+	}{
+		{"//go:generate stringer -type=Kind", true},
+		{"// go:generate with a space", true},
+		{"/* eslint-disable */", true},
+		{"// eslint-disable-next-line no-console", true},
+		{"// ordinary comment", false},
+		{"/* go */", false},
+		{"# go:generate with another marker", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.comment, func(t *testing.T) {
+			if got := keep(tt.comment); got != tt.expected {
+				t.Errorf("keep(%q) = %v, want %v", tt.comment, got, tt.expected)
+			}
+		})
+	}
+
+	if directiveKeeper(nil, "//")("//go:build linux") {
+		t.Errorf("keep() with no directives = true, want false")
+	}
+}
+
+func TestSplitHeader(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		syntax commentSyntax
+		header string
+	}{
+		{
+			name:   "line comment header",
+			input:  "// SPDX-License-Identifier: MIT\n// Copyright 2024 Acme\n\npackage main\n",
+			syntax: cStyleComments,
+			header: "// SPDX-License-Identifier: MIT\n// Copyright 2024 Acme\n",
+		},
+		{
+			name:   "block comment header",
+			input:  "/*\n * Licensed under the Apache License 2.0\n */\npackage main\n",
+			syntax: cStyleComments,
+			header: "/*\n * Licensed under the Apache License 2.0\n */\n",
+		},
+		{
+			name:   "block followed by line comments",
+			input:  "/* Copyright */\n// SPDX-License-Identifier: MIT\nx := 1\n",
+			syntax: cStyleComments,
+			header: "/* Copyright */\n// SPDX-License-Identifier: MIT\n",
+		},
+		{
+			name:   "block comment followed by code is not a header",
+			input:  "/* inline */ x := 1\n",
+			syntax: cStyleComments,
+			header: "",
+		},
+		{
+			name:   "unterminated block is not a header",
+			input:  "/* oops\nx := 1\n",
+			syntax: cStyleComments,
+			header: "",
+		},
+		{
+			name:   "shebang then hash header",
+			input:  "#!/usr/bin/env python3\n# SPDX-License-Identifier: MIT\nimport os  # os\n",
+			syntax: hashComments,
+			header: "#!/usr/bin/env python3\n# SPDX-License-Identifier: MIT\n",
+		},
+		{
+			name:   "code first",
+			input:  "package main\n// comment\n",
+			syntax: cStyleComments,
+			header: "",
+		},
+		{
+			name:   "whole file is a comment",
+			input:  "# only a comment",
+			syntax: hashComments,
+			header: "# only a comment",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header, rest := splitHeader(tt.input, tt.syntax)
+
+			if header != tt.header || header+rest != tt.input {
+				t.Errorf("splitHeader(%q) = %q, %q, want header %q", tt.input, header, rest, tt.header)
+			}
+		})
+	}
+}
+
+func TestFindRegionMarkers(t *testing.T) {
+	tests := []struct {
+		name  string
+		lang  string
+		input string
+		lines []int
+	}{
+		{
+			name:  "no markers",
+			lang:  Go,
+			input: "x := 1 // one\n",
+		},
+		{
+			name:  "line and block comment markers",
+			lang:  Go,
+			input: "a\n// nocomms:off\nb // kept\n  /*nocomms:on*/\nc\n",
+			lines: []int{1, 3},
+		},
+		{
+			name:  "markers after code or in strings don't count",
+			lang:  Python,
+			input: "x = 1  # nocomms:off\ns = \"\"\"\n# nocomms:off\n\"\"\"\n",
+		},
+		{
+			name:  "markers in block comments don't count",
+			lang:  Go,
+			input: "/*\n// nocomms:off\n*/\nvar b = 2 // drop\n",
+		},
+		{
+			name:  "markers in raw strings don't count",
+			lang:  Go,
+			input: "var s = `\n// nocomms:off\n`\n",
+		},
+		{
+			name:  "markers in yaml block scalars don't count",
+			lang:  YAML,
+			input: "text: |\n  # nocomms:off\nkey: 1\n# nocomms:on\n",
+			lines: []int{3},
+		},
+	}
+
+	opts := Options{KeepRegionStart: "nocomms:off", KeepRegionEnd: "nocomms:on"}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var lines []int
+			for _, marker := range findRegionMarkers(languages[tt.lang], tt.input, opts) {
+				lines = append(lines, marker.line)
+			}
+			if !slices.Equal(lines, tt.lines) {
+				t.Errorf("findRegionMarkers() lines = %v, want %v", lines, tt.lines)
+			}
+		})
+	}
+}
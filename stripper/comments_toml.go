@@ -0,0 +1,73 @@
+package stripper
+
+import (
+	"strings"
+)
+
+// RemoveTOMLComments removes # comments from TOML while preserving basic strings,
+// literal strings, and their triple-quoted multi-line variants.
+func RemoveTOMLComments(code string) string {
+	runes := []rune(code)
+	result := make([]rune, 0, len(runes))
+	i := 0
+
+	for i < len(runes) {
+		ch := runes[i]
+
+		if ch == '"' || ch == '\'' {
+			// Only basic strings support backslash escapes; literal strings are taken as-is
+			escapes := ch == '"'
+			delimiter := string(ch)
+			if i+2 < len(runes) && runes[i+1] == ch && runes[i+2] == ch {
+				delimiter = strings.Repeat(string(ch), 3)
+			}
+
+			result = append(result, []rune(delimiter)...)
+			i += len(delimiter)
+
+			for i < len(runes) {
+				if escapes && runes[i] == '\\' && i+1 < len(runes) {
+					result = append(result, runes[i], runes[i+1])
+					i += 2
+					continue
+				}
+
+				if strings.HasPrefix(string(runes[i:min(i+len(delimiter), len(runes))]), delimiter) {
+					result = append(result, []rune(delimiter)...)
+					i += len(delimiter)
+
+					// Multi-line strings may end with up to two extra quotes that belong to
+					// the content (e.g. """value""""), so absorb them as well
+					for extra := 0; len(delimiter) == 3 && extra < 2 && i < len(runes) && runes[i] == ch; extra++ {
+						result = append(result, ch)
+						i++
+					}
+					break
+				}
+
+				// Single-line strings cannot contain newlines; stop so a malformed string
+				// doesn't swallow the rest of the file
+				if len(delimiter) == 1 && runes[i] == '\n' {
+					break
+				}
+
+				result = append(result, runes[i])
+				i++
+			}
+			continue
+		}
+
+		if ch == '#' {
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+			result = trimTrailingBlanks(result)
+			continue
+		}
+
+		result = append(result, ch)
+		i++
+	}
+
+	return string(result)
+}
@@ -0,0 +1,82 @@
+package stripper
+
+import (
+	"testing"
+)
+
+func TestRemoveTOMLComments(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name: "full line and inline comments",
+			input: `# top comment
+[package]
+name = "nocomms" # inline
+version = "0.1.0"`,
+			expected: `
+[package]
+name = "nocomms"
+version = "0.1.0"`,
+		},
+		{
+			name:     "hash in basic string",
+			input:    `color = "#ff0000" # red`,
+			expected: `color = "#ff0000"`,
+		},
+		{
+			name:     "escaped quote in basic string",
+			input:    `s = "say \"#hi\"" # comment`,
+			expected: `s = "say \"#hi\""`,
+		},
+		{
+			// Literal strings have no escapes, so the backslash does not protect the quote
+			name:     "literal string with backslash",
+			input:    `path = 'C:\temp\' # comment`,
+			expected: `path = 'C:\temp\'`,
+		},
+		{
+			name: "multi-line literal string",
+			input: `regex = '''
+# not a comment
+[a-z]+ # still not
+''' # comment
+next = 1`,
+			expected: `regex = '''
+# not a comment
+[a-z]+ # still not
+'''
+next = 1`,
+		},
+		{
+			name: "multi-line basic string",
+			input: `text = """
+Line # one
+Quote: \"""
+""" # comment`,
+			expected: `text = """
+Line # one
+Quote: \"""
+"""`,
+		},
+		{
+			name: "inline table and array",
+			input: `point = { x = "#1", y = '#2' } # table
+list = ["a#b", 'c#d'] # array`,
+			expected: `point = { x = "#1", y = '#2' }
+list = ["a#b", 'c#d']`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := RemoveTOMLComments(tt.input)
+
+			if result != tt.expected {
+				t.Errorf("RemoveTOMLComments() failed\nInput:\n%s\n\nExpected:\n%s\n\nGot:\n%s", tt.input, tt.expected, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package stripper
+
+import (
+	"io"
+	"iter"
+	"regexp"
+	"strings"
+)
+
+// yamlBlockScalarHeader matches a line ending in a literal (|) or folded (>) block scalar
+// indicator, with optional chomping (+/-) and indentation indicators and any tags or
+// anchors, e.g. "key: |", "- >-", or "key: !!str |2+".
+var yamlBlockScalarHeader = regexp.MustCompile(`(?:^\s*|[:-]\s+)(?:[!&]\S*\s+)*[|>](?:[1-9][+-]?|[+-][1-9]?)?$`)
+
+// RemoveYAMLComments removes # comments from YAML, preserving quoted scalars and block
+// scalar bodies.
+func RemoveYAMLComments(content string) string {
+	var result strings.Builder
+	removeYAMLCommentLines(&result, splitLines(content))
+	return result.String()
+}
+
+// removeYAMLCommentLines writes lines to result with comments removed like
+// RemoveYAMLComments.
+func removeYAMLCommentLines(result io.StringWriter, lines iter.Seq2[string, bool]) {
+	// Block scalar bodies are verbatim text, so # inside them is content, not a comment.
+	// The body is every line indented deeper than the line holding the | or > indicator.
+	inBlockScalar := false
+	blockScalarParentIndent := 0
+
+	// YAML comments work like Python - # outside strings marks comment to end of line
+	// YAML supports single and double quotes with different escaping rules
+	for line, newline := range lines {
+		if inBlockScalar {
+			content := strings.TrimLeft(line, " \t")
+			if content == "" || len(line)-len(content) > blockScalarParentIndent {
+				result.WriteString(line)
+				if newline {
+					result.WriteString("\n")
+				}
+				continue
+			}
+			inBlockScalar = false
+		}
+
+		var cleaned strings.Builder
+		inString := false
+		stringDelim := rune(0)
+		escaped := false
+		runes := []rune(line)
+
+		for j := 0; j < len(runes); j++ {
+			ch := runes[j]
+
+			if escaped {
+				cleaned.WriteRune(ch)
+				escaped = false
+				continue
+			}
+
+			// In YAML double-quoted strings, backslash escapes the next character
+			// Single-quoted strings use '' to escape a single quote, not backslash
+			if ch == '\\' && inString && stringDelim == '"' {
+				cleaned.WriteRune(ch)
+				escaped = true
+				continue
+			}
+
+			// Handle quote escaping in single-quoted strings ('' represents a single ')
+			if inString && stringDelim == '\'' && ch == '\'' && j+1 < len(runes) && runes[j+1] == '\'' {
+				cleaned.WriteRune(ch)
+				cleaned.WriteRune(runes[j+1])
+				j++
+				continue
+			}
+
+			if (ch == '"' || ch == '\'') && !inString {
+				inString = true
+				stringDelim = ch
+				cleaned.WriteRune(ch)
+				continue
+			}
+
+			if inString && ch == stringDelim {
+				inString = false
+				stringDelim = 0
+				cleaned.WriteRune(ch)
+				continue
+			}
+
+			if inString {
+				cleaned.WriteRune(ch)
+				continue
+			}
+
+			// '#' outside of strings starts a comment only at line start or after whitespace;
+			// otherwise it is part of a plain scalar like value#notacomment
+			if ch == '#' && (j == 0 || runes[j-1] == ' ' || runes[j-1] == '\t') {
+				break
+			}
+
+			cleaned.WriteRune(ch)
+		}
+
+		// Remove trailing whitespace to avoid leaving empty spaces where comments were
+		trimmed := strings.TrimRight(cleaned.String(), " \t")
+		result.WriteString(trimmed)
+
+		if yamlBlockScalarHeader.MatchString(trimmed) {
+			inBlockScalar = true
+			blockScalarParentIndent = len(trimmed) - len(strings.TrimLeft(trimmed, " \t"))
+		}
+
+		if newline {
+			result.WriteString("\n")
+		}
+	}
+}
@@ -1,4 +1,4 @@
-package main
+package stripper
 
 import (
 	"testing"
@@ -152,8 +152,8 @@ tags: ["dev", "staging"]  # environment tags`,
 tags: ["dev", "staging"]`,
 		},
 		{
-			// Multi-line strings in YAML (using | or >) don't need quotes
-			// but are separate YAML constructs, not handled by quote escaping
+			// Block scalar bodies (using | or >) are verbatim text, so lines that look
+			// like comments are part of the string
 			name: "literal block scalar",
 			input: `description: |
   This is a multi-line
@@ -162,18 +162,82 @@ tags: ["dev", "staging"]`,
 key: value  # actual comment`,
 			expected: `description: |
   This is a multi-line
-
+  # this looks like a comment but is part of the string
   description
 key: value`,
 		},
 		{
-			// Edge case: hash immediately after colon
+			name: "folded block scalar with indicators",
+			input: `script: >-  # folded
+  echo start
+
+  # real text
+next: 1 # strip`,
+			expected: `script: >-
+  echo start
+
+  # real text
+next: 1`,
+		},
+		{
+			name: "block scalar in sequence",
+			input: `steps:
+  - run: |
+      # real text
+      make test
+  # removed
+  - name: done`,
+			expected: `steps:
+  - run: |
+      # real text
+      make test
+
+  - name: done`,
+		},
+		{
+			name: "block scalar with tag and indentation indicator",
+			input: `key: !!str |2
+   # real text
+other: value # strip`,
+			expected: `key: !!str |2
+   # real text
+other: value`,
+		},
+		{
+			name:     "greater-than in plain scalar",
+			input:    `cmp: a > b # strip
+next: 1`,
+			expected: `cmp: a > b
+next: 1`,
+		},
+		{
+			// Edge case: a hash without preceding whitespace does not start a comment
 			name: "hash after colon",
 			input: `key:# comment
 value: test`,
-			expected: `key:
+			expected: `key:# comment
 value: test`,
 		},
+		{
+			name:     "hash in url",
+			input:    `url: http://x#y`,
+			expected: `url: http://x#y`,
+		},
+		{
+			name:     "hash inside plain scalar",
+			input:    `key: value#keep`,
+			expected: `key: value#keep`,
+		},
+		{
+			name:     "hash after space",
+			input:    `key: value # strip`,
+			expected: `key: value`,
+		},
+		{
+			name:     "hash after tab",
+			input:    "key: value\t# strip",
+			expected: `key: value`,
+		},
 		{
 			// Edge case: multiple hashes in line
 			name: "multiple hashes",
@@ -202,10 +266,10 @@ implicit:`,
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := removeYAMLComments(tt.input)
+			result := RemoveYAMLComments(tt.input)
 
 			if result != tt.expected {
-				t.Errorf("removeYAMLComments() failed\nInput:\n%s\n\nExpected:\n%s\n\nGot:\n%s", tt.input, tt.expected, result)
+				t.Errorf("RemoveYAMLComments() failed\nInput:\n%s\n\nExpected:\n%s\n\nGot:\n%s", tt.input, tt.expected, result)
 			}
 		})
 	}
@@ -0,0 +1,134 @@
+package stripper
+
+import (
+	"path"
+	"slices"
+	"strings"
+)
+
+// shebangInterpreters maps the interpreters named in #! lines, with any version suffix
+// removed, to the language of their scripts.
+var shebangInterpreters = map[string]string{
+	"sh": Shell, "bash": Shell, "dash": Shell, "ksh": Shell, "zsh": Shell,
+	"python": Python, "pypy": Python,
+	"node": JavaScript, "nodejs": JavaScript, "deno": JavaScript, "bun": JavaScript,
+	"ts-node": TypeScript, "tsx": TypeScript,
+	"perl": Perl, "elixir": Elixir, "julia": Julia, "groovy": Groovy, "scala": Scala,
+	"ocaml": OCaml, "runghc": Haskell, "runhaskell": Haskell,
+}
+
+// contentSniffLines is how many lines at the start of content detectFromSignatures looks
+// at, since the signatures it looks for appear near the top of a file.
+const contentSniffLines = 50
+
+// DetectLanguageFromContent returns the language of content, named filename, for input
+// such as stdin where the extension may be missing. The extension and name are tried
+// first, as in DetectLanguage, then the interpreter of a #! line, and then a few
+// signatures of the content itself, such as a Go package clause followed by a func or a
+// Python def. These heuristics can be wrong, so an explicit language should win over them.
+func DetectLanguageFromContent(filename, content string) (string, bool) {
+	if filename != "" {
+		if lang, ok := DetectLanguage(filename); ok {
+			return lang, true
+		}
+	}
+	if lang, ok := detectFromShebang(content); ok {
+		return lang, true
+	}
+	return detectFromSignatures(content)
+}
+
+// detectFromShebang returns the language of the interpreter named by the #! line that
+// starts content, looking past env and its options for the interpreter env runs.
+func detectFromShebang(content string) (string, bool) {
+	line, _, _ := strings.Cut(content, "\n")
+	if !strings.HasPrefix(line, "#!") {
+		return "", false
+	}
+
+	fields := strings.Fields(line[2:])
+	if len(fields) > 0 && path.Base(fields[0]) == "env" {
+		fields = fields[1:]
+		for len(fields) > 0 && (strings.HasPrefix(fields[0], "-") || strings.Contains(fields[0], "=")) {
+			fields = fields[1:]
+		}
+	}
+	if len(fields) == 0 {
+		return "", false
+	}
+
+	// Versioned interpreters such as python3.12 run the same language
+	interpreter := strings.TrimRight(path.Base(fields[0]), "0123456789.")
+	lang, ok := shebangInterpreters[interpreter]
+	return lang, ok
+}
+
+// detectFromSignatures recognizes Go, Python, and Dockerfile content by statements that
+// start their lines: a package clause without the semicolon Java would end it with,
+// followed by a func, for Go; def or class statements, from imports, or other block
+// statements ending in a colon for Python; and a FROM instruction before any other for
+// Dockerfiles. A bare import statement is not enough for Python, since Kotlin, Scala, and
+// Haskell import modules the same way.
+func detectFromSignatures(content string) (string, bool) {
+	var goPackage, goFunc, python bool
+	firstInstruction := ""
+	for i, line := range strings.SplitN(content, "\n", contentSniffLines+1) {
+		if i == contentSniffLines {
+			break
+		}
+		line = strings.TrimRight(line, " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "//") {
+			continue
+		}
+		if firstInstruction == "" {
+			firstInstruction, _, _ = strings.Cut(line, " ")
+		}
+
+		switch {
+		case strings.HasPrefix(line, "package ") && !strings.HasSuffix(line, ";"):
+			goPackage = true
+		case strings.HasPrefix(line, "func "):
+			goFunc = true
+		case strings.HasPrefix(line, "def ") && strings.HasSuffix(line, ":"),
+			strings.HasPrefix(line, "class ") && strings.HasSuffix(line, ":"),
+			strings.HasPrefix(line, "from ") && strings.Contains(line, " import "),
+			isPythonBlock(strings.TrimLeft(line, " \t")):
+			python = true
+		}
+	}
+
+	switch {
+	case goPackage && goFunc:
+		return Go, true
+	case firstInstruction == "FROM":
+		return Dockerfile, true
+	case python:
+		return Python, true
+	}
+	return "", false
+}
+
+// pythonBlockKeywords are the keywords that start Python compound statements other than
+// def and class, and pythonBareBlocks the clauses that take no expression.
+var (
+	pythonBlockKeywords = []string{"if ", "elif ", "for ", "while ", "with ", "except ", "async "}
+	pythonBareBlocks    = []string{"else:", "try:", "except:", "finally:"}
+)
+
+// isPythonBlock reports whether line, with its indentation removed, opens a Python block
+// such as "if __name__ == '__main__':". Keywords must be followed by an expression, so
+// YAML keys such as "with:" don't count.
+func isPythonBlock(line string) bool {
+	if slices.Contains(pythonBareBlocks, line) {
+		return true
+	}
+	if !strings.HasSuffix(line, ":") {
+		return false
+	}
+	for _, keyword := range pythonBlockKeywords {
+		if strings.HasPrefix(line, keyword) {
+			return true
+		}
+	}
+	return false
+}
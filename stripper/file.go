@@ -0,0 +1,113 @@
+package stripper
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrUnsupportedFileType is returned when a file type is not supported
+type ErrUnsupportedFileType struct {
+	Extension string
+}
+
+func (e *ErrUnsupportedFileType) Error() string {
+	return fmt.Sprintf("unsupported file type: %s", e.Extension)
+}
+
+// ErrBinaryFile is returned when a file of a supported type doesn't hold text, such as
+// an image with a source file extension; running a remover over it would corrupt it
+type ErrBinaryFile struct {
+	Reason string
+}
+
+func (e *ErrBinaryFile) Error() string {
+	return fmt.Sprintf("binary file: %s", e.Reason)
+}
+
+// StripFile returns the content of the file at path with comments removed, in the
+// language its name implies, without modifying the file. The result ends with a newline
+// exactly when the file does. Files of unsupported types return ErrUnsupportedFileType,
+// and files that aren't text return ErrBinaryFile.
+func StripFile(path string, opts Options) (string, error) {
+	lang, ok := FileLanguage(path, opts)
+	if !ok {
+		return "", &ErrUnsupportedFileType{Extension: filepath.Ext(path)}
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read file: %w", err)
+	}
+	if err := CheckText(content, false); err != nil {
+		return "", err
+	}
+
+	// lang comes from FileLanguage, so it is supported
+	cleaned, _ := StripWithOptions(lang, string(content), FileOptions(path, lang, opts))
+	return MatchFinalNewline(string(content), cleaned), nil
+}
+
+// FileLanguage returns the language of the file at path like DetectLanguage, except that
+// plain .json files are JSONC when opts.JSONC is set.
+func FileLanguage(path string, opts Options) (string, bool) {
+	if opts.JSONC && filepath.Ext(path) == ".json" {
+		return JSONC, true
+	}
+	return DetectLanguage(path)
+}
+
+// FileOptions returns opts for the file at path, in lang, with what its extension
+// implies added.
+func FileOptions(path, lang string, opts Options) Options {
+	switch ext := filepath.Ext(path); {
+	// Without an AsmDialect, .asm files are taken to be NASM and .s and .S files GAS
+	case lang == Assembly && opts.AsmDialect == "" && ext == ".asm":
+		opts.AsmDialect = AsmNASM
+	// Only .tsx files hold JSX; in .ts files a '<' can start a type assertion instead
+	case lang == TypeScript && ext == ".tsx":
+		opts.JSX = true
+	}
+	return opts
+}
+
+// CheckText returns ErrBinaryFile if content holds a NUL byte or invalid UTF-8. An
+// incomplete character at the end is allowed when partial is set, for content cut
+// short from a larger file.
+func CheckText(content []byte, partial bool) error {
+	if bytes.IndexByte(content, 0) != -1 {
+		return &ErrBinaryFile{Reason: "contains NUL bytes"}
+	}
+	if partial {
+		start := len(content) - 1
+		for start > 0 && len(content)-start < utf8.UTFMax && !utf8.RuneStart(content[start]) {
+			start--
+		}
+		if start >= 0 && !utf8.FullRune(content[start:]) {
+			content = content[:start]
+		}
+	}
+	if !utf8.Valid(content) {
+		return &ErrBinaryFile{Reason: "not valid UTF-8"}
+	}
+	return nil
+}
+
+// MatchFinalNewline makes cleaned end with a newline exactly when original does, so that
+// removing a comment on the last line neither adds nor drops the file's final newline.
+// Blank lines left at the end of a file without a final newline are dropped with it.
+func MatchFinalNewline(original, cleaned string) string {
+	if !strings.HasSuffix(original, "\n") {
+		return strings.TrimRight(cleaned, "\r\n")
+	}
+	if cleaned != "" && !strings.HasSuffix(cleaned, "\n") {
+		if strings.HasSuffix(original, "\r\n") {
+			return cleaned + "\r\n"
+		}
+		return cleaned + "\n"
+	}
+	return cleaned
+}
@@ -0,0 +1,189 @@
+package stripper_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"nocomms/stripper"
+)
+
+func TestStripFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "main.go")
+	input := "package main // comment\n"
+	if err := os.WriteFile(path, []byte(input), 0o644); err != nil {
+		t.Fatalf("os.WriteFile() error = %v", err)
+	}
+
+	cleaned, err := stripper.StripFile(path, stripper.Options{})
+	if err != nil {
+		t.Fatalf("StripFile() error = %v", err)
+	}
+	if want := "package main\n"; cleaned != want {
+		t.Errorf("StripFile() = %q, want %q", cleaned, want)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("os.ReadFile() error = %v", err)
+	}
+	if string(data) != input {
+		t.Errorf("StripFile() modified the file to %q", string(data))
+	}
+}
+
+func TestStripFileAsmDialect(t *testing.T) {
+	dir := t.TempDir()
+	const input = "\tmov eax, 1 ; set # 1\n"
+	tests := []struct {
+		name    string
+		dialect string
+		want    string
+	}{
+		// ';' separates statements in GAS, so only the NASM default for .asm removes it
+		{"boot.asm", "", "\tmov eax, 1\n"},
+		{"boot.s", "", "\tmov eax, 1 ; set\n"},
+		{"boot.S", "", "\tmov eax, 1 ; set\n"},
+		{"boot.asm", stripper.AsmGAS, "\tmov eax, 1 ; set\n"},
+		{"boot.s", stripper.AsmNASM, "\tmov eax, 1\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name+"/"+tt.dialect, func(t *testing.T) {
+			path := filepath.Join(dir, tt.name)
+			if err := os.WriteFile(path, []byte(input), 0o644); err != nil {
+				t.Fatalf("os.WriteFile() error = %v", err)
+			}
+
+			cleaned, err := stripper.StripFile(path, stripper.Options{AsmDialect: tt.dialect})
+			if err != nil {
+				t.Fatalf("StripFile() error = %v", err)
+			}
+			if cleaned != tt.want {
+				t.Errorf("StripFile() = %q, want %q", cleaned, tt.want)
+			}
+		})
+	}
+}
+
+func TestStripFileJSX(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"app.tsx", "const a = <a>http://x</a>; // link\n", "const a = <a>http://x</a>;\n"},
+		{"app.jsx", "const a = <a>http://x</a>; // link\n", "const a = <a>http://x</a>;\n"},
+		// In .ts files <string> is a type assertion, not an element
+		{"cast.ts", "let s = <string>v; // cast\nlet t = 1; // one\n", "let s = <string>v;\nlet t = 1;\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.name)
+			if err := os.WriteFile(path, []byte(tt.input), 0o644); err != nil {
+				t.Fatalf("os.WriteFile() error = %v", err)
+			}
+
+			cleaned, err := stripper.StripFile(path, stripper.Options{})
+			if err != nil {
+				t.Fatalf("StripFile() error = %v", err)
+			}
+			if cleaned != tt.want {
+				t.Errorf("StripFile() = %q, want %q", cleaned, tt.want)
+			}
+		})
+	}
+}
+
+func TestStripFileKeepRegions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tune.py")
+	input := "x = 1  # one\n# nocomms:off\ny = 2  # hand-tuned\n# nocomms:on\n"
+	if err := os.WriteFile(path, []byte(input), 0o644); err != nil {
+		t.Fatalf("os.WriteFile() error = %v", err)
+	}
+
+	cleaned, err := stripper.StripFile(path, stripper.Options{KeepRegionStart: "nocomms:off", KeepRegionEnd: "nocomms:on"})
+	if err != nil {
+		t.Fatalf("StripFile() error = %v", err)
+	}
+	if want := "x = 1\n# nocomms:off\ny = 2  # hand-tuned\n# nocomms:on\n"; cleaned != want {
+		t.Errorf("StripFile() = %q, want %q", cleaned, want)
+	}
+}
+
+func TestStripFileUnsupported(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"README.txt", "package.json", "Makefile"} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(dir, name)
+			if err := os.WriteFile(path, []byte("# heading\n"), 0o644); err != nil {
+				t.Fatalf("os.WriteFile() error = %v", err)
+			}
+
+			cleaned, err := stripper.StripFile(path, stripper.Options{})
+			var unsupportedErr *stripper.ErrUnsupportedFileType
+			if !errors.As(err, &unsupportedErr) {
+				t.Fatalf("StripFile() error = %v, want ErrUnsupportedFileType", err)
+			}
+			if unsupportedErr.Extension != filepath.Ext(name) {
+				t.Errorf("Extension = %q, want %q", unsupportedErr.Extension, filepath.Ext(name))
+			}
+			if cleaned != "" {
+				t.Errorf("StripFile() = %q, want empty", cleaned)
+			}
+		})
+	}
+
+	// The file type is checked before the file is read
+	_, err := stripper.StripFile(filepath.Join(dir, "missing.txt"), stripper.Options{})
+	var unsupportedErr *stripper.ErrUnsupportedFileType
+	if !errors.As(err, &unsupportedErr) {
+		t.Errorf("StripFile() of missing file error = %v, want ErrUnsupportedFileType", err)
+	}
+}
+
+func TestCheckText(t *testing.T) {
+	euro := []byte("price: €")
+	tests := []struct {
+		name    string
+		content []byte
+		partial bool
+		binary  bool
+	}{
+		{"text", []byte("héllo // wörld\n"), false, false},
+		{"empty", nil, false, false},
+		{"nul byte", []byte("a\x00b"), false, true},
+		{"invalid utf-8", []byte("caf\xe9"), false, true},
+		{"cut character", euro[:len(euro)-1], false, true},
+		{"cut character in partial content", euro[:len(euro)-1], true, false},
+		{"invalid utf-8 in partial content", []byte("caf\xe9 ok"), true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := stripper.CheckText(tt.content, tt.partial)
+			var binaryErr *stripper.ErrBinaryFile
+			if binary := errors.As(err, &binaryErr); binary != tt.binary {
+				t.Errorf("CheckText(%q, %v) = %v, want binary %v", tt.content, tt.partial, err, tt.binary)
+			}
+		})
+	}
+}
+
+func TestStripFileJSONC(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tsconfig.json")
+	if err := os.WriteFile(path, []byte("{\"strict\": true} // on\n"), 0o644); err != nil {
+		t.Fatalf("os.WriteFile() error = %v", err)
+	}
+
+	cleaned, err := stripper.StripFile(path, stripper.Options{JSONC: true})
+	if err != nil {
+		t.Fatalf("StripFile() error = %v", err)
+	}
+	if want := "{\"strict\": true}\n"; cleaned != want {
+		t.Errorf("StripFile() = %q, want %q", cleaned, want)
+	}
+}
@@ -0,0 +1,229 @@
+package stripper
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"strings"
+)
+
+// ErrNotStreamable is returned by StripStream for languages and options that need the
+// whole file at once.
+var ErrNotStreamable = errors.New("comment removal cannot be streamed")
+
+// lineStrippers are the removers that work line by line, carrying their state from one
+// line to the next, so they can run on lines read one at a time.
+var lineStrippers = map[string]func(result io.StringWriter, lines iter.Seq2[string, bool], opts Options){
+	Go: func(result io.StringWriter, lines iter.Seq2[string, bool], opts Options) {
+		removeGoCommentLines(result, lines, directiveKeeper(opts.KeepDirectives, "//"), nil)
+	},
+	JavaScript: func(result io.StringWriter, lines iter.Seq2[string, bool], opts Options) {
+		removeJSCommentLines(result, lines, jsKeeper(opts), true)
+	},
+	TypeScript: func(result io.StringWriter, lines iter.Seq2[string, bool], opts Options) {
+		removeJSCommentLines(result, lines, jsKeeper(opts), opts.JSX)
+	},
+	Python: func(result io.StringWriter, lines iter.Seq2[string, bool], opts Options) {
+		removePythonCommentLines(result, lines, directiveKeeper(opts.KeepDirectives, "#"))
+	},
+	Rust: func(result io.StringWriter, lines iter.Seq2[string, bool], opts Options) {
+		var keep func(string) bool
+		if opts.KeepDocs {
+			keep = isRustDocComment
+		}
+		removeRustCommentLines(result, lines, keep)
+	},
+	YAML: func(result io.StringWriter, lines iter.Seq2[string, bool], _ Options) {
+		removeYAMLCommentLines(result, lines)
+	},
+}
+
+// CanStream reports whether StripStream supports lang with opts: Go, JavaScript,
+// TypeScript, Python, Rust, and YAML can be streamed, except that KeepHeader and, for
+// Go, KeepDocs look ahead in the file, KeepTrailingWhitespace compares the result with
+// the whole source, and kept regions split the source before it is stripped.
+func CanStream(lang string, opts Options) bool {
+	if _, ok := lineStrippers[lang]; !ok {
+		return false
+	}
+	keepsRegions := opts.KeepRegionStart != "" && opts.KeepRegionEnd != ""
+	return !opts.KeepHeader && !opts.KeepTrailingWhitespace && !keepsRegions && !(lang == Go && opts.KeepDocs)
+}
+
+// StripStream removes comments like StripWithOptions, but reads src from r one line at a
+// time and writes the result to w, so memory use stays flat for large files. It returns
+// ErrNotStreamable when CanStream is false.
+func StripStream(lang string, r io.Reader, w io.Writer, opts Options) error {
+	if !CanStream(lang, opts) {
+		return fmt.Errorf("%w: %s", ErrNotStreamable, lang)
+	}
+
+	// The whole file isn't at hand, so its line ending is decided from how it starts
+	in := bufio.NewReaderSize(r, crlfSampleSize)
+	sample, _ := in.Peek(crlfSampleSize)
+	if usesCRLF(string(sample)) {
+		w = &crlfWriter{w: w}
+	}
+
+	var collapser *collapsingWriter
+	if opts.CollapseNewlines {
+		collapser = &collapsingWriter{w: w}
+		w = collapser
+	}
+	out := bufio.NewWriter(w)
+
+	var readErr error
+	lines := readLines(in, &readErr)
+	if collapser != nil {
+		lines = collapser.track(lines)
+	}
+	lineStrippers[lang](out, lines, opts)
+	if readErr != nil {
+		return readErr
+	}
+	if err := out.Flush(); err != nil {
+		return err
+	}
+	if collapser != nil {
+		return collapser.Close()
+	}
+	return nil
+}
+
+// splitLines yields the lines of content as strings.Split(content, "\n") would split
+// them, each with whether a newline follows it.
+func splitLines(content string) iter.Seq2[string, bool] {
+	return func(yield func(string, bool) bool) {
+		for {
+			idx := strings.IndexByte(content, '\n')
+			if idx == -1 {
+				yield(content, false)
+				return
+			}
+			if !yield(content[:idx], true) {
+				return
+			}
+			content = content[idx+1:]
+		}
+	}
+}
+
+// crlfSampleSize is how much of a streamed file is looked at to choose its line ending.
+const crlfSampleSize = 64 << 10
+
+// readLines yields the lines of r like splitLines, reading one line at a time, with the
+// "\r" of "\r\n" line endings dropped. A read error ends the lines early and is stored in
+// *err.
+func readLines(r *bufio.Reader, err *error) iter.Seq2[string, bool] {
+	return func(yield func(string, bool) bool) {
+		for {
+			line, readErr := r.ReadString('\n')
+			if readErr == io.EOF {
+				yield(line, false)
+				return
+			}
+			if readErr != nil {
+				*err = readErr
+				return
+			}
+			line = strings.TrimSuffix(line[:len(line)-1], "\r")
+			if !yield(line, true) {
+				return
+			}
+		}
+	}
+}
+
+// crlfWriter turns every "\n" written through it into "\r\n".
+type crlfWriter struct {
+	w   io.Writer
+	buf []byte
+}
+
+func (c *crlfWriter) Write(p []byte) (int, error) {
+	c.buf = c.buf[:0]
+	for _, b := range p {
+		if b == '\n' {
+			c.buf = append(c.buf, '\r')
+		}
+		c.buf = append(c.buf, b)
+	}
+	if _, err := c.w.Write(c.buf); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// collapsingWriter collapses blank lines like collapseRemovedLines as text is written
+// through it, told by track which lines of the source had text. Newlines are held back
+// until the next other byte shows whether they end the text; Close writes those that do.
+type collapsingWriter struct {
+	w        io.Writer
+	started  bool
+	newlines int
+	removed  bool
+	// hadText holds whether each source line not yet written had text
+	hadText []bool
+	buf     []byte
+}
+
+// track returns lines, noting for Write whether each line has text, so that the blank
+// lines left by removed comments can be told from those the source already had.
+func (c *collapsingWriter) track(lines iter.Seq2[string, bool]) iter.Seq2[string, bool] {
+	return func(yield func(string, bool) bool) {
+		for line, more := range lines {
+			c.hadText = append(c.hadText, line != "")
+			if !yield(line, more) {
+				return
+			}
+		}
+	}
+}
+
+func (c *collapsingWriter) Write(p []byte) (int, error) {
+	c.buf = c.buf[:0]
+	for _, b := range p {
+		if b == '\n' {
+			hadText := len(c.hadText) > 0 && c.hadText[0]
+			if len(c.hadText) > 0 {
+				c.hadText = c.hadText[1:]
+			}
+			// The newline ends a blank line unless it ends the text written before it
+			if c.newlines > 0 || !c.started {
+				c.removed = c.removed || hadText
+			}
+			c.newlines++
+			continue
+		}
+		c.buf = c.appendNewlines(c.buf)
+		c.newlines = 0
+		c.removed = false
+		c.started = true
+		c.buf = append(c.buf, b)
+	}
+	if _, err := c.w.Write(c.buf); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// appendNewlines appends the newlines held back to buf: all of them, unless a removed
+// comment left one of the blank lines they make, in which case leading newlines are
+// dropped and longer runs shortened to one blank line.
+func (c *collapsingWriter) appendNewlines(buf []byte) []byte {
+	switch {
+	case !c.removed:
+		return append(buf, strings.Repeat("\n", c.newlines)...)
+	case c.started:
+		return append(buf, "\n\n"[:min(c.newlines, 2)]...)
+	}
+	return buf
+}
+
+// Close writes the trailing newlines held back by Write.
+func (c *collapsingWriter) Close() error {
+	_, err := c.w.Write(c.appendNewlines(c.buf[:0]))
+	return err
+}
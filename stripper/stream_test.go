@@ -0,0 +1,152 @@
+package stripper
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestStripStreamMatchesStrip(t *testing.T) {
+	samples := map[string][]string{
+		Go: {
+			"package main // main\n\n\n\n/* block\ncomment */\nvar s = `raw // not a comment\n/* still raw */`\n//go:generate stringer\n",
+			"x := '/' // slash",
+			"package main // main\r\n\r\n// doc\r\nfunc f() {} /* inline */\r\n",
+			"// doc\n\n\nvar s = `a\n\n\n\nb` // s\n\n\n\n// end\n\n",
+		},
+		JavaScript: {
+			"#!/usr/bin/env node\n// comment\nconst re = /\\/\\//g; // regex\nconst t = `a\n// in template\n`;\n/** doc */\nf();\n",
+			"/* unterminated",
+		},
+		TypeScript: {
+			"// @ts-ignore\nlet x: number = 1; /* inline */ let y = 2;\n\n\n\n",
+		},
+		Python: {
+			"#!/usr/bin/env python\n# -*- coding: utf-8 -*-\n# comment\ns = \"\"\"\n# in docstring\n\"\"\"  # after\nx = 1  # type: int\n",
+			"#!/bin/python",
+		},
+		Rust: {
+			"/// doc\nfn main() { /* a /* nested */ b */ }\nlet s = r#\"// raw\"#; // trailing\n",
+		},
+		YAML: {
+			"# comment\nkey: value # trailing\ntext: |\n  # kept\n  body\nother: 'a # b'\n\n\n\n",
+			"",
+			"# comment\r\nkey: value # trailing\r\ntext: |\r\n  # kept\r\n",
+		},
+	}
+	optionSets := []Options{
+		{},
+		{KeepDirectives: DefaultDirectives, KeepDocs: true, CollapseNewlines: true},
+	}
+
+	for lang, inputs := range samples {
+		for _, input := range inputs {
+			for _, opts := range optionSets {
+				if !CanStream(lang, opts) {
+					continue
+				}
+
+				want, err := StripWithOptions(lang, input, opts)
+				if err != nil {
+					t.Fatalf("StripWithOptions(%s) error = %v", lang, err)
+				}
+				var got bytes.Buffer
+				if err := StripStream(lang, strings.NewReader(input), &got, opts); err != nil {
+					t.Fatalf("StripStream(%s) error = %v", lang, err)
+				}
+				if got.String() != want {
+					t.Errorf("StripStream(%s, %q, %+v) = %q, want %q", lang, input, opts, got.String(), want)
+				}
+			}
+		}
+	}
+}
+
+func TestCanStream(t *testing.T) {
+	tests := []struct {
+		lang     string
+		opts     Options
+		expected bool
+	}{
+		{Go, Options{}, true},
+		{Go, Options{KeepDocs: true}, false},
+		{Rust, Options{KeepDocs: true}, true},
+		{Python, Options{KeepHeader: true}, false},
+		{Go, Options{KeepRegionStart: "nocomms:off", KeepRegionEnd: "nocomms:on"}, false},
+		{Shell, Options{}, false},
+	}
+
+	for _, tt := range tests {
+		if got := CanStream(tt.lang, tt.opts); got != tt.expected {
+			t.Errorf("CanStream(%s, %+v) = %v, want %v", tt.lang, tt.opts, got, tt.expected)
+		}
+	}
+
+	err := StripStream(Shell, strings.NewReader("echo hi # comment\n"), &bytes.Buffer{}, Options{})
+	if err == nil || !strings.Contains(err.Error(), ErrNotStreamable.Error()) {
+		t.Errorf("StripStream(shell) error = %v, want ErrNotStreamable", err)
+	}
+}
+
+func TestCollapsingWriter(t *testing.T) {
+	// Each source is paired with the text written for it, with its comments removed
+	tests := []struct{ src, cleaned string }{
+		{"", ""},
+		{"\n\n\n", "\n\n\n"},
+		{"// a\n\n\n", "\n\n\n"},
+		{"// a\n// b\na\n// c\n\n\nb\n", "\n\na\n\n\n\nb\n"},
+		{"a\n\n\n\nb\n", "a\n\n\n\nb\n"},
+		{"a\n// b\n// c\n", "a\n\n\n"},
+		{"a\nb\n\nc", "a\nb\n\nc"},
+	}
+	for _, tt := range tests {
+		var got bytes.Buffer
+		w := &collapsingWriter{w: &got}
+		for range w.track(splitLines(tt.src)) {
+		}
+		// One byte per write exercises runs of newlines split across writes
+		for i := range len(tt.cleaned) {
+			if _, err := w.Write([]byte{tt.cleaned[i]}); err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("Close() error = %v", err)
+		}
+
+		if want := collapseRemovedLines(tt.src, tt.cleaned); got.String() != want {
+			t.Errorf("collapsingWriter(%q, %q) = %q, want %q", tt.src, tt.cleaned, got.String(), want)
+		}
+	}
+}
+
+// largeGoSource returns about 2 MB of Go source with a comment on every other line.
+func largeGoSource() string {
+	var b strings.Builder
+	b.WriteString("package generated\n\n")
+	for b.Len() < 2<<20 {
+		b.WriteString("// Value is a generated constant.\nconst Value = \"// not a comment\" /* trailing */\n")
+	}
+	return b.String()
+}
+
+func BenchmarkStrip(b *testing.B) {
+	src := largeGoSource()
+	b.SetBytes(int64(len(src)))
+	for b.Loop() {
+		if _, err := Strip(Go, src); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkStripStream(b *testing.B) {
+	src := largeGoSource()
+	b.SetBytes(int64(len(src)))
+	for b.Loop() {
+		var out bytes.Buffer
+		if err := StripStream(Go, strings.NewReader(src), &out, Options{}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
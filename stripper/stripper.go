@@ -0,0 +1,421 @@
+// Package stripper removes comments from source code while leaving strings, heredocs,
+// and other literals that merely look like comments intact.
+//
+// The Go, Java, JavaScript, TypeScript, Python, Rust, and YAML removers keep the line
+// structure of the source so that line numbers don't shift: every line that held only
+// comments becomes an empty line, and every newline is kept, including the final one
+// or its absence. A file of three comment lines without a final newline thus becomes
+// two newlines. Use Options.CollapseNewlines to squeeze the resulting blank lines.
+package stripper
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// The supported languages, as accepted by Strip.
+const (
+	Assembly   = "assembly"
+	Dockerfile = "dockerfile"
+	Elixir     = "elixir"
+	Go         = "go"
+	GraphQL    = "graphql"
+	Groovy     = "groovy"
+	Haskell    = "haskell"
+	Java       = "java"
+	JavaScript = "javascript"
+	JSONC      = "jsonc"
+	Julia      = "julia"
+	Kotlin     = "kotlin"
+	Markdown   = "markdown"
+	Nix        = "nix"
+	OCaml      = "ocaml"
+	Perl       = "perl"
+	Protobuf   = "protobuf"
+	Python     = "python"
+	Rust       = "rust"
+	Scala      = "scala"
+	Shell      = "shell"
+	Solidity   = "solidity"
+	SQL        = "sql"
+	Terraform  = "terraform"
+	TOML       = "toml"
+	TypeScript = "typescript"
+	YAML       = "yaml"
+)
+
+// Languages lists every supported language.
+var Languages = []string{
+	Assembly, Dockerfile, Elixir, Go, GraphQL, Groovy, Haskell, Java, JavaScript, JSONC, Julia,
+	Kotlin, Markdown, Nix, OCaml, Perl, Protobuf, Python, Rust, Scala, Shell, Solidity, SQL,
+	Terraform, TOML, TypeScript, YAML,
+}
+
+// ErrUnsupportedLanguage is returned for languages that have no comment remover.
+var ErrUnsupportedLanguage = errors.New("unsupported language")
+
+// DefaultDirectives are the directive comment prefixes preserved by default: Go compiler
+// and linter directives, JavaScript and TypeScript tooling comments, and Python type
+// comments and tool pragmas.
+var DefaultDirectives = slices.Concat(goDirectives, jsDirectives, pythonDirectives)
+
+// Options selects comments to preserve. The zero value removes every comment.
+type Options struct {
+	// KeepDirectives holds prefixes of tooling directive comments to preserve, such as
+	// "go:" for //go:generate; they are matched against the comment text after its
+	// marker and any spaces. Directives are recognized in Go, JavaScript, TypeScript,
+	// and Python.
+	KeepDirectives []string
+	// KeepDocs preserves documentation comments: Go doc comments, Rust /// and //!,
+	// JSDoc /** */, and Solidity NatSpec
+	KeepDocs bool
+	// KeepNatSpec preserves Solidity NatSpec documentation comments (/// and /** */)
+	KeepNatSpec bool
+	// KeepHeader preserves the comment block at the top of the file, such as a license
+	// header; see splitHeader
+	KeepHeader bool
+	// KeepRegionStart and KeepRegionEnd, when both are set, mark regions of code and
+	// comments that are left untouched, such as from a "// nocomms:off" line through a
+	// "// nocomms:on" line. The markers are matched like KeepDirectives, against lines that
+	// start with a comment; see restoreKeptRegions
+	KeepRegionStart string
+	KeepRegionEnd   string
+	// CollapseNewlines collapses runs of blank lines left behind by removed comments to a
+	// single blank line, and drops those at the top of the file, like
+	// CollapseExcessiveNewlines. Blank lines the source already had, such as in multi-line
+	// strings, are kept unless a removed comment's line joins them
+	CollapseNewlines bool
+	// AsmDialect selects the comment syntax of Assembly sources: AsmGAS, the default, or
+	// AsmNASM
+	AsmDialect string
+	// JSX parses JSX elements in TypeScript, as in .tsx files, so that comment-like text
+	// in them is kept. It is off by default since in .ts files a '<' can start a type
+	// assertion or type parameter list instead. JavaScript always parses JSX, since a '<'
+	// where an element can start has no other meaning there.
+	JSX bool
+	// KeepTrailingWhitespace keeps the trailing whitespace of lines that held no comment.
+	// The removers that keep the line structure otherwise trim every line; the others
+	// only trim the whitespace left before a removed comment.
+	KeepTrailingWhitespace bool
+	// JSONC takes plain .json files to be JSONC in StripFile and FileLanguage, for the
+	// many tools that accept comments in them
+	JSONC bool
+}
+
+// language describes a supported language: its display name, the file extensions that
+// identify it, its comment markers, and its remover.
+type language struct {
+	name       string
+	extensions []string
+	syntax     commentSyntax
+	strip      func(src string, opts Options) string
+}
+
+// cStyleComments and hashComments are the comment syntaxes shared by most languages.
+var (
+	cStyleComments = commentSyntax{line: []string{"//"}, blockOpen: "/*", blockClose: "*/"}
+	hashComments   = commentSyntax{line: []string{"#"}}
+)
+
+// ignoreOptions adapts a remover that has no comments worth preserving.
+func ignoreOptions(remove func(string) string) func(string, Options) string {
+	return func(src string, _ Options) string {
+		return remove(src)
+	}
+}
+
+// jsKeeper returns the function that selects the JavaScript and TypeScript comments opts
+// preserves.
+func jsKeeper(opts Options) func(comment string) bool {
+	keep := directiveKeeper(opts.KeepDirectives, "//", "/*")
+	if opts.KeepDocs {
+		keep = keepAny(keep, isJSDocComment)
+	}
+	return keep
+}
+
+func stripJS(src string, opts Options) string {
+	return removeJSCommentsKeeping(src, jsKeeper(opts), true)
+}
+
+func stripTS(src string, opts Options) string {
+	return removeJSCommentsKeeping(src, jsKeeper(opts), opts.JSX)
+}
+
+var languages = map[string]language{
+	Assembly: {"Assembly", []string{".s", ".S", ".asm"}, commentSyntax{line: []string{"#", "//", ";"}, blockOpen: "/*", blockClose: "*/"}, func(src string, opts Options) string {
+		return RemoveAsmComments(src, opts.AsmDialect)
+	}},
+	Dockerfile: {"Dockerfile", []string{".dockerfile"}, hashComments, ignoreOptions(RemoveDockerfileComments)},
+	Elixir:     {"Elixir", []string{".ex", ".exs"}, hashComments, ignoreOptions(RemoveElixirComments)},
+	Go: {"Go", []string{".go"}, cStyleComments, func(src string, opts Options) string {
+		return removeGoCommentsKeeping(src, directiveKeeper(opts.KeepDirectives, "//"), opts.KeepDocs)
+	}},
+	GraphQL:    {"GraphQL", []string{".graphql", ".gql"}, hashComments, ignoreOptions(RemoveGraphQLComments)},
+	Groovy:     {"Groovy", []string{".groovy", ".gradle"}, cStyleComments, ignoreOptions(RemoveGroovyComments)},
+	Haskell:    {"Haskell", []string{".hs"}, commentSyntax{line: []string{"--"}, blockOpen: "{-", blockClose: "-}"}, ignoreOptions(RemoveHaskellComments)},
+	Java:       {"Java", []string{".java"}, cStyleComments, ignoreOptions(RemoveJavaComments)},
+	JavaScript: {"JavaScript", []string{".js", ".jsx"}, cStyleComments, stripJS},
+	JSONC:      {"JSON", []string{".jsonc", ".json5"}, cStyleComments, ignoreOptions(RemoveJSONCComments)},
+	Julia:      {"Julia", []string{".jl"}, commentSyntax{line: []string{"#"}, blockOpen: "#=", blockClose: "=#"}, ignoreOptions(RemoveJuliaComments)},
+	Kotlin:     {"Kotlin", []string{".kt", ".kts"}, cStyleComments, ignoreOptions(RemoveKotlinComments)},
+	Markdown:   {"Markdown", []string{".md", ".markdown"}, commentSyntax{blockOpen: "<!--", blockClose: "-->"}, ignoreOptions(RemoveMarkdownComments)},
+	Nix:        {"Nix", []string{".nix"}, commentSyntax{line: []string{"#"}, blockOpen: "/*", blockClose: "*/"}, ignoreOptions(RemoveNixComments)},
+	OCaml:      {"OCaml", []string{".ml", ".mli"}, commentSyntax{blockOpen: "(*", blockClose: "*)"}, ignoreOptions(RemoveOCamlComments)},
+	Perl:       {"Perl", []string{".pl", ".pm"}, hashComments, ignoreOptions(RemovePerlComments)},
+	Protobuf:   {"Protobuf", []string{".proto"}, cStyleComments, ignoreOptions(RemoveProtoComments)},
+	Python: {"Python", []string{".py"}, hashComments, func(src string, opts Options) string {
+		return removePythonCommentsKeeping(src, directiveKeeper(opts.KeepDirectives, "#"))
+	}},
+	Rust: {"Rust", []string{".rs"}, cStyleComments, func(src string, opts Options) string {
+		if opts.KeepDocs {
+			return removeRustCommentsKeeping(src, isRustDocComment)
+		}
+		return RemoveRustComments(src)
+	}},
+	Scala: {"Scala", []string{".scala", ".sc"}, cStyleComments, ignoreOptions(RemoveScalaComments)},
+	Shell: {"Shell", []string{".sh", ".bash", ".zsh"}, hashComments, ignoreOptions(RemoveShellComments)},
+	Solidity: {"Solidity", []string{".sol"}, cStyleComments, func(src string, opts Options) string {
+		return RemoveSolidityComments(src, opts.KeepNatSpec || opts.KeepDocs)
+	}},
+	SQL:        {"SQL", []string{".sql"}, commentSyntax{line: []string{"--"}, blockOpen: "/*", blockClose: "*/"}, ignoreOptions(RemoveSQLComments)},
+	Terraform:  {"Terraform", []string{".tf", ".tfvars"}, commentSyntax{line: []string{"#", "//"}, blockOpen: "/*", blockClose: "*/"}, ignoreOptions(RemoveTerraformComments)},
+	TOML:       {"TOML", []string{".toml"}, hashComments, ignoreOptions(RemoveTOMLComments)},
+	TypeScript: {"TypeScript", []string{".ts", ".tsx"}, cStyleComments, stripTS},
+	YAML:       {"YAML", []string{".yaml", ".yml"}, hashComments, ignoreOptions(RemoveYAMLComments)},
+}
+
+// registered holds the removers added with RegisterRemover, keyed by extension.
+var (
+	registeredMu sync.RWMutex
+	registered   = map[string]func(string) string{}
+)
+
+// RegisterRemover makes fn the comment remover for files with extension ext, such as
+// ".lua", taking precedence over any built-in language for that extension. The
+// extension, dot included, is also the language name that DetectLanguage returns and
+// Strip accepts for it. Options do not apply to registered removers. RegisterRemover
+// panics if fn is nil.
+func RegisterRemover(ext string, fn func(string) string) {
+	if fn == nil {
+		panic("stripper: RegisterRemover remover is nil")
+	}
+
+	registeredMu.Lock()
+	defer registeredMu.Unlock()
+	registered[ext] = fn
+}
+
+// registeredRemover returns the remover registered for ext, if any.
+func registeredRemover(ext string) (func(string) string, bool) {
+	registeredMu.RLock()
+	defer registeredMu.RUnlock()
+	fn, ok := registered[ext]
+	return fn, ok
+}
+
+// Strip removes every comment from src, which is written in lang, one of Languages.
+func Strip(lang string, src string) (string, error) {
+	return StripWithOptions(lang, src, Options{})
+}
+
+// StripWithOptions removes comments from src, which is written in lang, except those
+// selected by opts. The result uses the line ending that most of src's lines use, "\r\n"
+// or "\n", on every line.
+func StripWithOptions(lang string, src string, opts Options) (string, error) {
+	crlf := usesCRLF(src)
+	src = strings.ReplaceAll(src, "\r\n", "\n")
+
+	var cleaned string
+	if remove, ok := registeredRemover(lang); ok {
+		cleaned = remove(src)
+	} else {
+		l, ok := languages[lang]
+		if !ok {
+			return "", fmt.Errorf("%w: %s", ErrUnsupportedLanguage, lang)
+		}
+
+		cleaned = stripLanguage(l, src, opts)
+		if opts.KeepTrailingWhitespace && trimsEveryLine[lang] {
+			cleaned = restoreTrailingWhitespace(src, cleaned)
+		}
+		if opts.KeepRegionStart != "" && opts.KeepRegionEnd != "" {
+			cleaned = restoreKeptRegions(l, src, cleaned, opts)
+		}
+	}
+
+	if opts.CollapseNewlines {
+		cleaned = collapseRemovedLines(src, cleaned)
+	}
+	if crlf {
+		cleaned = strings.ReplaceAll(cleaned, "\n", "\r\n")
+	}
+	return cleaned, nil
+}
+
+// stripLanguage removes comments from src with l, keeping the header if opts.KeepHeader
+// is set.
+func stripLanguage(l language, src string, opts Options) string {
+	if opts.KeepHeader {
+		header, rest := splitHeader(src, l.syntax)
+		return header + l.strip(rest, opts)
+	}
+	return l.strip(src, opts)
+}
+
+// regionMarker is a line of src holding a comment that starts or ends a kept region.
+type regionMarker struct {
+	start, end bool
+	// line is the index of the line in src and strippedLine in src with its comments
+	// removed, which differ when a remover joins the lines a block comment spans
+	line, strippedLine int
+}
+
+// restoreKeptRegions puts the regions of src delimited by opts.KeepRegionStart and
+// opts.KeepRegionEnd back into cleaned, which is src with its comments removed. A region
+// runs from a start marker line through the next end marker line, or to the end of src if
+// there is none.
+func restoreKeptRegions(l language, src, cleaned string, opts Options) string {
+	markers := findRegionMarkers(l, src, opts)
+	if len(markers) == 0 {
+		return cleaned
+	}
+
+	srcLines := strings.Split(src, "\n")
+	lines := strings.Split(cleaned, "\n")
+	result := make([]string, 0, len(lines))
+	next := 0
+	var open *regionMarker
+	for i, marker := range markers {
+		// A remover whose output can't be lined up with its probe's keeps no regions
+		if marker.strippedLine < next || marker.strippedLine >= len(lines) {
+			break
+		}
+		switch {
+		case open == nil && marker.start:
+			open = &markers[i]
+		case open != nil && marker.end:
+			result = append(result, lines[next:open.strippedLine]...)
+			result = append(result, srcLines[open.line:marker.line+1]...)
+			next = marker.strippedLine + 1
+			open = nil
+		}
+	}
+
+	if open != nil {
+		result = append(result, lines[next:open.strippedLine]...)
+		result = append(result, srcLines[open.line:]...)
+	} else {
+		result = append(result, lines[next:]...)
+	}
+	return strings.Join(result, "\n")
+}
+
+// findRegionMarkers returns the lines of src holding a comment that starts with
+// opts.KeepRegionStart or opts.KeepRegionEnd, in order. Lines that only look like one, in
+// a string or inside another comment, are told apart by what l's remover does with two
+// probes. In the first, each such line is replaced by a bare probe word, which the remover
+// keeps unless the line is inside a comment; where the word ends up also gives the line's
+// index in the stripped text. In the second, the marker is replaced by a probe word, which
+// the remover keeps only if the line is inside a string.
+func findRegionMarkers(l language, src string, opts Options) []regionMarker {
+	srcLines := strings.Split(src, "\n")
+	bare := slices.Clone(srcLines)
+	marked := slices.Clone(srcLines)
+	prefix := "nocommsprobe"
+	for strings.Contains(src, prefix) {
+		prefix += "x"
+	}
+
+	var markers []regionMarker
+	var probes []string
+	for i, line := range srcLines {
+		startIdx := markerIndex(line, l.syntax, opts.KeepRegionStart)
+		endIdx := markerIndex(line, l.syntax, opts.KeepRegionEnd)
+		if startIdx == -1 && endIdx == -1 {
+			continue
+		}
+		idx, marker := startIdx, opts.KeepRegionStart
+		if idx == -1 {
+			idx, marker = endIdx, opts.KeepRegionEnd
+		}
+
+		// The trailing letter keeps one probe from being the prefix of another
+		probe := prefix + strconv.Itoa(len(probes)) + "q"
+		indent := len(line) - len(strings.TrimLeft(line, " \t"))
+		bare[i] = line[:indent] + probe
+		marked[i] = line[:idx] + probe + line[idx+len(marker):]
+		markers = append(markers, regionMarker{start: startIdx != -1, end: endIdx != -1, line: i})
+		probes = append(probes, probe)
+	}
+	if len(markers) == 0 {
+		return nil
+	}
+
+	// Only the options that change how source is read apply to the probes; kept comments
+	// would hide which markers are comments
+	strippedBare := stripLanguage(l, strings.Join(bare, "\n"), opts)
+	strippedMarked := l.strip(strings.Join(marked, "\n"), Options{AsmDialect: opts.AsmDialect, JSX: opts.JSX})
+
+	comments := markers[:0]
+	for i, marker := range markers {
+		pos := strings.Index(strippedBare, probes[i])
+		if pos == -1 || strings.Contains(strippedMarked, probes[i]) {
+			continue
+		}
+		marker.strippedLine = strings.Count(strippedBare[:pos], "\n")
+		comments = append(comments, marker)
+	}
+	return comments
+}
+
+// DetectLanguage returns the language of the file at path from its extension, or from
+// its name for Dockerfiles. Plain .json files are not detected since JSON has no
+// comments; callers that know a .json file allows them can use JSONC.
+func DetectLanguage(path string) (string, bool) {
+	ext := filepath.Ext(path)
+	if _, ok := registeredRemover(ext); ok {
+		return ext, true
+	}
+
+	if isDockerfile(path) {
+		return Dockerfile, true
+	}
+
+	for lang, l := range languages {
+		if slices.Contains(l.extensions, ext) {
+			return lang, true
+		}
+	}
+	return "", false
+}
+
+// Extensions returns the file extensions that identify lang, such as ".ts" and ".tsx" for
+// TypeScript, or nil for unsupported languages. Dockerfiles are also detected by name.
+func Extensions(lang string) []string {
+	if _, ok := registeredRemover(lang); ok {
+		return []string{lang}
+	}
+	return slices.Clone(languages[lang].extensions)
+}
+
+// Name returns the display name of lang, such as "TypeScript", or an empty string for
+// unsupported languages. Registered languages are named after their extension.
+func Name(lang string) string {
+	if _, ok := registeredRemover(lang); ok {
+		return strings.TrimPrefix(lang, ".")
+	}
+	return languages[lang].name
+}
+
+// isDockerfile reports whether path names a Dockerfile, either by the conventional
+// Dockerfile or Dockerfile.<variant> names or by a .dockerfile extension.
+func isDockerfile(path string) bool {
+	base := filepath.Base(path)
+	return base == "Dockerfile" || strings.HasPrefix(base, "Dockerfile.") || strings.EqualFold(filepath.Ext(base), ".dockerfile")
+}
@@ -0,0 +1,492 @@
+package stripper_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"nocomms/stripper"
+)
+
+func TestStrip(t *testing.T) {
+	tests := []struct {
+		name     string
+		lang     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "go",
+			lang:     stripper.Go,
+			input:    "x := 1 // one\n",
+			expected: "x := 1\n",
+		},
+		{
+			name:     "python keeps strings",
+			lang:     stripper.Python,
+			input:    "s = \"# not a comment\"  # comment\n",
+			expected: "s = \"# not a comment\"\n",
+		},
+		{
+			name:     "sql",
+			lang:     stripper.SQL,
+			input:    "SELECT 1; -- one\n",
+			expected: "SELECT 1;\n",
+		},
+		{
+			// Trailing spaces are content in block scalars, heredocs, and multiline strings
+			name:     "yaml block scalar trailing spaces",
+			lang:     stripper.YAML,
+			input:    "text: |\n  line  \n  more \t\nkey: 1  # one\n",
+			expected: "text: |\n  line  \n  more \t\nkey: 1\n",
+		},
+		{
+			name:     "shell heredoc trailing spaces",
+			lang:     stripper.Shell,
+			input:    "cat <<EOF\nline  \nEOF\necho hi  # hi\n",
+			expected: "cat <<EOF\nline  \nEOF\necho hi\n",
+		},
+		{
+			name:     "python multiline string trailing spaces",
+			lang:     stripper.Python,
+			input:    "s = '''\nline  \n'''  # s\n",
+			expected: "s = '''\nline  \n'''\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := stripper.Strip(tt.lang, tt.input)
+			if err != nil {
+				t.Fatalf("Strip() error = %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Strip() = %q, want %q", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestStripLineStructure(t *testing.T) {
+	// Each input uses the language's line comment marker in place of #
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"only comments", "# a\n# b\n# c", "\n\n"},
+		{"only comments with final newline", "# a\n# b\n# c\n", "\n\n\n"},
+		{"comment at end of file", "x = 1\n# a", "x = 1\n"},
+		{"comment at end of file with final newline", "x = 1\n# a\n", "x = 1\n\n"},
+		{"comment between lines", "x = 1\n# a\ny = 2", "x = 1\n\ny = 2"},
+	}
+	markers := map[string]string{
+		stripper.Go: "//", stripper.Java: "//", stripper.JavaScript: "//", stripper.TypeScript: "//",
+		stripper.Python: "#", stripper.Rust: "//", stripper.YAML: "#",
+	}
+
+	for lang, marker := range markers {
+		for _, tt := range tests {
+			t.Run(lang+"/"+tt.name, func(t *testing.T) {
+				input := strings.ReplaceAll(tt.input, "#", marker)
+				result, err := stripper.Strip(lang, input)
+				if err != nil {
+					t.Fatalf("Strip() error = %v", err)
+				}
+				if result != tt.expected {
+					t.Errorf("Strip(%q) = %q, want %q", input, result, tt.expected)
+				}
+			})
+		}
+	}
+}
+
+func TestStripLineEndings(t *testing.T) {
+	tests := []struct {
+		name     string
+		lang     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "go crlf",
+			lang:     stripper.Go,
+			input:    "package main // main\r\n\r\n// f does things\r\nfunc f() {} /* inline */\r\n",
+			expected: "package main\r\n\r\n\r\nfunc f() {}\r\n",
+		},
+		{
+			name:     "go crlf block comment",
+			lang:     stripper.Go,
+			input:    "x := 1 /* a\r\nb */ + 2\r\ns := `raw // kept`",
+			expected: "x := 1\r\n + 2\r\ns := `raw // kept`",
+		},
+		{
+			name:     "yaml crlf",
+			lang:     stripper.YAML,
+			input:    "# comment\r\nkey: value # trailing\r\ntext: |\r\n  # kept\r\n  body\r\n",
+			expected: "\r\nkey: value\r\ntext: |\r\n  # kept\r\n  body\r\n",
+		},
+		{
+			name:     "mostly crlf",
+			lang:     stripper.YAML,
+			input:    "a: 1 # one\r\nb: 2\nc: 3\r\n",
+			expected: "a: 1\r\nb: 2\r\nc: 3\r\n",
+		},
+		{
+			name:     "mostly lf",
+			lang:     stripper.YAML,
+			input:    "a: 1 # one\r\nb: 2\nc: 3\n",
+			expected: "a: 1\nb: 2\nc: 3\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := stripper.Strip(tt.lang, tt.input)
+			if err != nil {
+				t.Fatalf("Strip() error = %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Strip(%q) = %q, want %q", tt.input, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestStripUnsupportedLanguage(t *testing.T) {
+	if _, err := stripper.Strip("cobol", "* comment"); !errors.Is(err, stripper.ErrUnsupportedLanguage) {
+		t.Errorf("Strip() error = %v, want ErrUnsupportedLanguage", err)
+	}
+}
+
+func TestStripWithOptions(t *testing.T) {
+	tests := []struct {
+		name     string
+		lang     string
+		input    string
+		opts     stripper.Options
+		expected string
+	}{
+		{
+			name:     "go directives",
+			lang:     stripper.Go,
+			input:    "//go:generate stringer -type=Kind\ntype Kind int // the kind\n",
+			opts:     stripper.Options{KeepDirectives: stripper.DefaultDirectives},
+			expected: "//go:generate stringer -type=Kind\ntype Kind int\n",
+		},
+		{
+			name:     "rust docs",
+			lang:     stripper.Rust,
+			input:    "/// Adds one.\nfn inc(x: i32) -> i32 { x + 1 } // trivial\n",
+			opts:     stripper.Options{KeepDocs: true},
+			expected: "/// Adds one.\nfn inc(x: i32) -> i32 { x + 1 }\n",
+		},
+		{
+			name:     "shell header",
+			lang:     stripper.Shell,
+			input:    "# Copyright 2024 Example\n\necho hi # say hi\n",
+			opts:     stripper.Options{KeepHeader: true},
+			expected: "# Copyright 2024 Example\n\necho hi\n",
+		},
+		{
+			name:     "go trailing whitespace",
+			lang:     stripper.Go,
+			input:    "x := 1  \ny := 2  // two\n\t\n",
+			opts:     stripper.Options{KeepTrailingWhitespace: true},
+			expected: "x := 1  \ny := 2\n\t\n",
+		},
+		{
+			name:     "yaml trailing whitespace",
+			lang:     stripper.YAML,
+			input:    "text: |\n  line  \nkey: value  \nother: 1  # one\n",
+			opts:     stripper.Options{KeepTrailingWhitespace: true},
+			expected: "text: |\n  line  \nkey: value  \nother: 1\n",
+		},
+		{
+			name:     "javascript jsx",
+			lang:     stripper.JavaScript,
+			input:    "const a = <a href=\"/\">http://x</a>; // link\n",
+			expected: "const a = <a href=\"/\">http://x</a>;\n",
+		},
+		{
+			name:     "typescript jsx",
+			lang:     stripper.TypeScript,
+			input:    "const a = <a href=\"/\">http://x</a>; // link\n",
+			opts:     stripper.Options{JSX: true},
+			expected: "const a = <a href=\"/\">http://x</a>;\n",
+		},
+		{
+			name:     "typescript type assertion",
+			lang:     stripper.TypeScript,
+			input:    "let s = <string>value; // cast\nlet t = 1; // one\n",
+			expected: "let s = <string>value;\nlet t = 1;\n",
+		},
+		{
+			name:     "collapse removed comment lines",
+			lang:     stripper.Go,
+			input:    "// Package p\n\npackage p\n\n// a\n// b\n\nvar x = 1\n// c\n// d\n",
+			opts:     stripper.Options{CollapseNewlines: true},
+			expected: "package p\n\nvar x = 1\n\n",
+		},
+		{
+			name:     "collapse keeps blank lines in go raw strings",
+			lang:     stripper.Go,
+			input:    "var s = `a\n\n\n\nb` // s\n",
+			opts:     stripper.Options{CollapseNewlines: true},
+			expected: "var s = `a\n\n\n\nb`\n",
+		},
+		{
+			name:     "collapse keeps blank lines in python docstrings",
+			lang:     stripper.Python,
+			input:    "# module\ndef f():\n    \"\"\"Doc.\n\n\n\n    More.\n    \"\"\"\n",
+			opts:     stripper.Options{CollapseNewlines: true},
+			expected: "def f():\n    \"\"\"Doc.\n\n\n\n    More.\n    \"\"\"\n",
+		},
+		{
+			name:     "collapse keeps blank lines in markdown fences",
+			lang:     stripper.Markdown,
+			input:    "Text\n<!-- note -->\n\n```\na\n\n\n\nb\n```\n",
+			opts:     stripper.Options{CollapseNewlines: true},
+			expected: "Text\n\n```\na\n\n\n\nb\n```\n",
+		},
+		{
+			name:     "collapse keeps blank lines in yaml block scalars",
+			lang:     stripper.YAML,
+			input:    "# config\ntext: |\n  a\n\n\n\n  b\nkey: 1 # one\n",
+			opts:     stripper.Options{CollapseNewlines: true},
+			expected: "text: |\n  a\n\n\n\n  b\nkey: 1\n",
+		},
+		{
+			name:     "collapse after a block comment joins lines",
+			lang:     stripper.Kotlin,
+			input:    "/**\n * Doc.\n */\n\n\nval s = \"\"\"a\n\n\n\nb\"\"\"\n// c\n\n\nval t = 1\n",
+			opts:     stripper.Options{CollapseNewlines: true},
+			expected: "val s = \"\"\"a\n\n\n\nb\"\"\"\n\nval t = 1\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := stripper.StripWithOptions(tt.lang, tt.input, tt.opts)
+			if err != nil {
+				t.Fatalf("StripWithOptions() error = %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("StripWithOptions() = %q, want %q", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestStripKeepRegions(t *testing.T) {
+	regions := stripper.Options{KeepRegionStart: "nocomms:off", KeepRegionEnd: "nocomms:on"}
+	tests := []struct {
+		name     string
+		lang     string
+		input    string
+		opts     stripper.Options
+		expected string
+	}{
+		{
+			name: "go region",
+			lang: stripper.Go,
+			input: `package main // main
+
+// nocomms:off
+// Tuned by hand:
+//   0x5f3759df is the magic constant
+const magic = 0x5f3759df // do not touch
+// nocomms:on
+
+func main() {} // entry
+`,
+			opts: regions,
+			expected: `package main
+
+// nocomms:off
+// Tuned by hand:
+//   0x5f3759df is the magic constant
+const magic = 0x5f3759df // do not touch
+// nocomms:on
+
+func main() {}
+`,
+		},
+		{
+			name: "python region to the end of the file",
+			lang: stripper.Python,
+			input: `import os  # os
+
+def f():
+    # nocomms:off
+    return os.sep  # the separator
+    # TODO: keep this note
+`,
+			opts: regions,
+			expected: `import os
+
+def f():
+    # nocomms:off
+    return os.sep  # the separator
+    # TODO: keep this note
+`,
+		},
+		{
+			name:     "blank lines in regions are not collapsed",
+			lang:     stripper.Python,
+			input:    "# gone\n\n\n\nx = 1\n# nocomms:off\n\n\n\ny = 2\n# nocomms:on\n# gone\n\n\nz = 3  # three\n",
+			opts:     stripper.Options{KeepRegionStart: "nocomms:off", KeepRegionEnd: "nocomms:on", CollapseNewlines: true},
+			expected: "x = 1\n# nocomms:off\n\n\n\ny = 2\n# nocomms:on\n\nz = 3\n",
+		},
+		{
+			name:     "regions with a kept header and CRLF",
+			lang:     stripper.Shell,
+			input:    "# Copyright\r\necho a # a\r\n# nocomms:off\r\necho b # b\r\n# nocomms:on\r\n",
+			opts:     stripper.Options{KeepRegionStart: "nocomms:off", KeepRegionEnd: "nocomms:on", KeepHeader: true},
+			expected: "# Copyright\r\necho a\r\n# nocomms:off\r\necho b # b\r\n# nocomms:on\r\n",
+		},
+		{
+			name:     "markdown region",
+			lang:     stripper.Markdown,
+			input:    "<!-- gone -->\nText\n<!-- nocomms:off -->\n<!-- kept -->\n<!-- nocomms:on -->\n",
+			opts:     regions,
+			expected: "\nText\n<!-- nocomms:off -->\n<!-- kept -->\n<!-- nocomms:on -->\n",
+		},
+		{
+			name:     "markers in block comments don't start regions",
+			lang:     stripper.Go,
+			input:    "/*\n// nocomms:off\n*/\nvar b = 2 // drop\n",
+			opts:     regions,
+			expected: "\n\n\nvar b = 2\n",
+		},
+		{
+			name:     "region after a block comment that joins lines",
+			lang:     stripper.Kotlin,
+			input:    "/* a\nb */ val a = 1\n// nocomms:off\nval b = 2 // kept\n// nocomms:on\nval c = 3 // c\n",
+			opts:     regions,
+			expected: " val a = 1\n// nocomms:off\nval b = 2 // kept\n// nocomms:on\nval c = 3\n",
+		},
+		{
+			name:     "markers are ordinary comments without options",
+			lang:     stripper.Go,
+			input:    "// nocomms:off\nx := 1 // one\n// nocomms:on\n",
+			expected: "\nx := 1\n\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := stripper.StripWithOptions(tt.lang, tt.input, tt.opts)
+			if err != nil {
+				t.Fatalf("StripWithOptions() error = %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("StripWithOptions() = %q, want %q", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestDetectLanguage(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected string
+		ok       bool
+	}{
+		{"main.go", stripper.Go, true},
+		{"src/app.tsx", stripper.TypeScript, true},
+		{"deploy/Dockerfile.dev", stripper.Dockerfile, true},
+		{"tsconfig.jsonc", stripper.JSONC, true},
+		{"package.json", "", false},
+		{"README.md", stripper.Markdown, true},
+		{"boot.asm", stripper.Assembly, true},
+		{"start.S", stripper.Assembly, true},
+		{"notes.txt", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			lang, ok := stripper.DetectLanguage(tt.path)
+			if lang != tt.expected || ok != tt.ok {
+				t.Errorf("DetectLanguage(%q) = %q, %v, want %q, %v", tt.path, lang, ok, tt.expected, tt.ok)
+			}
+		})
+	}
+}
+
+func TestDetectLanguageFromContent(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+		content  string
+		expected string
+		ok       bool
+	}{
+		{"extension", "main.go", "#!/usr/bin/env python3\n", stripper.Go, true},
+		{"extension without content", "app.tsx", "", stripper.TypeScript, true},
+		{"dockerfile name", "Dockerfile", "", stripper.Dockerfile, true},
+		{"unknown extension falls back to content", "script", "#!/bin/bash\necho hi\n", stripper.Shell, true},
+		{"shebang path", "", "#!/bin/sh\nset -e\n", stripper.Shell, true},
+		{"shebang env", "", "#!/usr/bin/env node\nconsole.log(1)\n", stripper.JavaScript, true},
+		{"shebang versioned", "", "#!/usr/bin/python3.12\nprint(1)\n", stripper.Python, true},
+		{"shebang env options", "", "#!/usr/bin/env -S PERL5LIB=lib perl -w\nprint 1;\n", stripper.Perl, true},
+		{"shebang unknown interpreter", "", "#!/usr/bin/env ruby\nputs 1\n", "", false},
+		{"go signature", "", "// Package main runs.\npackage main\n\nimport \"fmt\"\n\nfunc main() {}\n", stripper.Go, true},
+		{"java package is not go", "", "package com.example;\n\nclass Main {}\n", "", false},
+		{"python def", "", "def main():\n    pass\n", stripper.Python, true},
+		{"python import alone is unknown", "", "# tool\nimport os, sys\n", "", false},
+		{"python import with block", "", "import sys\n\nif __name__ == \"__main__\":\n    sys.exit(0)\n", stripper.Python, true},
+		{"python indented block", "", "for line in open(0):\n    try:\n        print(int(line))\n    except ValueError:\n        pass\n", stripper.Python, true},
+		{"kotlin import is not python", "", "import kotlin.math.max\n\nfun main() = println(max(1, 2))\n", "", false},
+		{"scala import is not python", "", "import scala.util.Try\n\nobject Main extends App\n", "", false},
+		{"haskell import is not python", "", "import Data.List\n\nmain = print (sort [2, 1])\n", "", false},
+		{"yaml with key is not python", "", "steps:\n  - uses: actions/checkout@v4\n    with:\n      fetch-depth: 0\n", "", false},
+		{"python from import", "", "from pathlib import Path\n", stripper.Python, true},
+		{"javascript import is not python", "", "import React from \"react\";\n", "", false},
+		{"dockerfile from", "", "# syntax=docker/dockerfile:1\nFROM golang:1.25\nRUN go build\n", stripper.Dockerfile, true},
+		{"plain text", "", "hello world\n", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lang, ok := stripper.DetectLanguageFromContent(tt.filename, tt.content)
+			if lang != tt.expected || ok != tt.ok {
+				t.Errorf("DetectLanguageFromContent(%q, %q) = %q, %v, want %q, %v", tt.filename, tt.content, lang, ok, tt.expected, tt.ok)
+			}
+		})
+	}
+}
+
+func TestLanguages(t *testing.T) {
+	for _, lang := range stripper.Languages {
+		if stripper.Name(lang) == "" {
+			t.Errorf("Name(%q) is empty", lang)
+		}
+		if _, err := stripper.Strip(lang, ""); err != nil {
+			t.Errorf("Strip(%q) error = %v", lang, err)
+		}
+	}
+}
+
+func TestRegisterRemover(t *testing.T) {
+	stripper.RegisterRemover(".fake", func(src string) string {
+		code, _, _ := strings.Cut(src, " %")
+		return code
+	})
+
+	lang, ok := stripper.DetectLanguage("notes.fake")
+	if !ok || lang != ".fake" {
+		t.Fatalf("DetectLanguage() = %q, %v, want %q, true", lang, ok, ".fake")
+	}
+	if name := stripper.Name(lang); name != "fake" {
+		t.Errorf("Name() = %q, want %q", name, "fake")
+	}
+
+	result, err := stripper.Strip(lang, "text % comment")
+	if err != nil {
+		t.Fatalf("Strip() error = %v", err)
+	}
+	if want := "text"; result != want {
+		t.Errorf("Strip() = %q, want %q", result, want)
+	}
+}